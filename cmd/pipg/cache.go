@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/cache"
+)
+
+// cacheOptions holds the settings for reusing local wheels during an install.
+type cacheOptions struct {
+	disabled  bool   // --no-cache
+	dir       string // --cache-dir; empty uses PIPG_CACHE_DIR or the platform default
+	namespace string // index host, see cacheNamespace
+	wheelDir  string // --wheel-dir, checked before the cache
+	maxSize   int64  // --cache-max-size in bytes; 0 is unbounded
+}
+
+// sizeUnits are the suffixes parseSize accepts, largest first, as binary
+// multiples to match formatSize.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// parseSize parses a size such as "500MB", "5G" or "1048576" into bytes.
+// The "B" and "iB" endings are optional. An empty string is zero.
+func parseSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	if text == "" {
+		return 0, nil
+	}
+
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "B"), "I")
+	multiplier := int64(1)
+
+	for _, u := range sizeUnits {
+		if rest, ok := strings.CutSuffix(text, u.suffix); ok {
+			text, multiplier = strings.TrimSpace(rest), u.bytes
+
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q; expected a number of bytes with an optional K, M, G or T suffix, e.g., 5GB", s)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// localWheel returns the path of filename in the wheel dir or the cache, or
+// "" when neither has it. The file is not verified.
+func (o cacheOptions) localWheel(filename string) string {
+	candidates := make([]string, 0, 2)
+
+	if o.wheelDir != "" {
+		candidates = append(candidates, filepath.Join(o.wheelDir, filename))
+	}
+
+	if !o.disabled {
+		candidates = append(candidates, cache.Path(o.dir, o.namespace, filename))
+	}
+
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and manage the wheel cache",
+	}
+
+	cmd.PersistentFlags().String("cache-dir", "", "Cache directory (default: $PIPG_CACHE_DIR or the platform cache dir)")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "dir",
+			Short: "Print the cache directory",
+			Args:  cobra.NoArgs,
+			RunE:  runCacheDir,
+		},
+		&cobra.Command{
+			Use:   "clear",
+			Short: "Remove all cached wheels",
+			Args:  cobra.NoArgs,
+			RunE:  runCacheClear,
+		},
+		&cobra.Command{
+			Use:   "info",
+			Short: "Show the number and total size of cached wheels",
+			Args:  cobra.NoArgs,
+			RunE:  runCacheInfo,
+		},
+	)
+
+	return cmd
+}
+
+func cacheDirFlag(cmd *cobra.Command) string {
+	dir, _ := cmd.Flags().GetString("cache-dir")
+
+	return cache.Dir(dir)
+}
+
+func runCacheDir(cmd *cobra.Command, _ []string) error {
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), cacheDirFlag(cmd))
+
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, _ []string) error {
+	removed, err := cache.Clear(cacheDirFlag(cmd))
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Removed %d cached wheels\n", removed)
+
+	return nil
+}
+
+func runCacheInfo(cmd *cobra.Command, _ []string) error {
+	dir := cacheDirFlag(cmd)
+
+	info, err := cache.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "Location: %s\n", dir)
+	_, _ = fmt.Fprintf(out, "Wheels:   %d\n", info.Files)
+	_, _ = fmt.Fprintf(out, "Size:     %s\n", formatSize(info.Size))
+
+	return nil
+}
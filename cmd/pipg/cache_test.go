@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCacheCmd(t *testing.T, args ...string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	cmd := newCacheCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("cache %v: %v", args, err)
+	}
+
+	return out.String()
+}
+
+func TestCacheCommand(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "pkg-1.0-py3-none-any.whl"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runCacheCmd(t, "dir", "--cache-dir", dir); strings.TrimSpace(got) != dir {
+		t.Errorf("cache dir = %q, want %q", got, dir)
+	}
+
+	if got := runCacheCmd(t, "info", "--cache-dir", dir); !strings.Contains(got, "Wheels:   1") || !strings.Contains(got, "Size:     2 KB") {
+		t.Errorf("cache info = %q", got)
+	}
+
+	if got := runCacheCmd(t, "clear", "--cache-dir", dir); !strings.Contains(got, "Removed 1 cached wheels") {
+		t.Errorf("cache clear = %q", got)
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("cache clear removed the directory: %v", err)
+	}
+}
+
+func TestCacheClearMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+
+	if got := runCacheCmd(t, "clear", "--cache-dir", dir); !strings.Contains(got, "Removed 0 cached wheels") {
+		t.Errorf("cache clear = %q", got)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"1048576", 1 << 20},
+		{"500MB", 500 << 20},
+		{"5g", 5 << 30},
+		{"1.5 GiB", 3 << 29},
+		{"64K", 64 << 10},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"lots", "-1G", "5X"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) expected an error", in)
+		}
+	}
+}
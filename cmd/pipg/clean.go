@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+func newCleanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove packages left inconsistent by an interrupted install",
+		Args:  cobra.NoArgs,
+		RunE:  runClean,
+	}
+
+	cmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	cmd.Flags().String("target", "", "Target directory (default: auto-detect site-packages)")
+	cmd.Flags().CountP("verbose", "v", "Verbose output")
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before removing")
+
+	return cmd
+}
+
+func runClean(cmd *cobra.Command, _ []string) error {
+	pythonBin, _ := cmd.Flags().GetString("python")
+	targetDir, _ := cmd.Flags().GetString("target")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	logger := newLogger(verbose)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env, err := detectEnv(ctx, pythonBin, targetDir, logger)
+	if err != nil {
+		return err
+	}
+
+	broken, err := installer.FindBroken(env.LibDirs())
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+
+	if len(broken) == 0 {
+		_, _ = fmt.Fprintln(out, "No broken installs found.")
+
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(out, "Broken installs:")
+
+	for _, b := range broken {
+		_, _ = fmt.Fprintf(out, "  %s: %s\n", b.Name(), b.Reason)
+	}
+
+	if !yes && !confirm(cmd.InOrStdin(), out, "Remove them?") {
+		_, _ = fmt.Fprintln(out, "Aborted.")
+
+		return nil
+	}
+
+	inst := installer.New(env, installer.WithLogger(logger))
+
+	for _, b := range broken {
+		if err := inst.RemoveBroken(b); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(out, "Removed %s\n", b.Name())
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/python"
+)
+
+func newDebugCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Show diagnostic information",
+	}
+
+	tags := &cobra.Command{
+		Use:   "tags",
+		Short: "Print the compatible wheel tags in selection priority order",
+		Args:  cobra.NoArgs,
+		RunE:  runDebugTags,
+	}
+
+	tags.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	tags.Flags().String("platform", "", "Platform to generate tags for instead of the interpreter's, e.g., linux-x86_64 or macosx-14.0-arm64")
+	tags.Flags().String("python-version", "", "Python version to generate tags for instead of the interpreter's, e.g., 3.12")
+	tags.Flags().String("format", formatPlain, "Output format: plain or json")
+	tags.Flags().CountP("verbose", "v", "Verbose output")
+
+	cmd.AddCommand(tags)
+
+	return cmd
+}
+
+func runDebugTags(cmd *cobra.Command, _ []string) error {
+	pythonBin, _ := cmd.Flags().GetString("python")
+	platform, _ := cmd.Flags().GetString("platform")
+	pyVersion, _ := cmd.Flags().GetString("python-version")
+	format, _ := cmd.Flags().GetString("format")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+
+	if format != formatPlain && format != formatJSON {
+		return fmt.Errorf("unknown --format %q; expected %s or %s", format, formatPlain, formatJSON)
+	}
+
+	env := &python.Environment{PlatformTag: platform}
+
+	if pyVersion != "" {
+		v, err := tagPythonVersion(pyVersion)
+		if err != nil {
+			return err
+		}
+
+		env.PythonVersion = v
+	}
+
+	// The interpreter is only needed for what the flags leave open.
+	if env.PlatformTag == "" || env.PythonVersion == "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		detected, err := detectEnv(ctx, pythonBin, "", newLogger(verbose))
+		if err != nil {
+			return err
+		}
+
+		if env.PlatformTag == "" {
+			env.PlatformTag = detected.PlatformTag
+		}
+
+		if env.PythonVersion == "" {
+			env.PythonVersion = detected.PythonVersion
+			env.FreeThreaded = detected.FreeThreaded
+		}
+	}
+
+	return writeTags(cmd.OutOrStdout(), format, buildCompatTags(env))
+}
+
+// tagPythonVersion converts a --python-version value such as "3.12" into the
+// form used in tags, "312".
+func tagPythonVersion(v string) (string, error) {
+	major, minor, ok := strings.Cut(v, ".")
+	if !ok {
+		major, minor = v[:1], v[1:]
+	}
+
+	if !isDigits(major) || !isDigits(minor) || minor == "" {
+		return "", fmt.Errorf("invalid --python-version %q; expected a version like 3.12", v)
+	}
+
+	return major + minor, nil
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// tagJSON is the JSON form of a compatibility tag.
+type tagJSON struct {
+	Tag      string `json:"tag"`
+	Python   string `json:"python"`
+	ABI      string `json:"abi"`
+	Platform string `json:"platform"`
+}
+
+// writeTags writes tags in priority order: one "python-abi-platform" line
+// each, or a JSON array.
+func writeTags(w io.Writer, format string, tags []downloader.WheelTag) error {
+	if format == formatJSON {
+		out := make([]tagJSON, len(tags))
+		for i, t := range tags {
+			out[i] = tagJSON{Tag: t.Python + "-" + t.ABI + "-" + t.Platform, Python: t.Python, ABI: t.ABI, Platform: t.Platform}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(out)
+	}
+
+	for _, t := range tags {
+		if _, err := fmt.Fprintf(w, "%s-%s-%s\n", t.Python, t.ABI, t.Platform); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
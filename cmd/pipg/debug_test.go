@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDebugTagsOrder(t *testing.T) {
+	var buf bytes.Buffer
+
+	cmd := newDebugCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"tags", "--platform", "macosx-11.0-arm64", "--python-version", "3.4"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("debug tags error: %v", err)
+	}
+
+	want := []string{
+		"cp34-cp34-macosx_11_0_arm64",
+		"cp34-cp34-macosx_11_0_universal2",
+		"cp34-abi3-macosx_11_0_arm64",
+		"cp34-abi3-macosx_11_0_universal2",
+		"cp34-none-macosx_11_0_arm64",
+		"cp34-none-macosx_11_0_universal2",
+		"cp33-abi3-macosx_11_0_arm64",
+		"cp33-abi3-macosx_11_0_universal2",
+		"cp32-abi3-macosx_11_0_arm64",
+		"cp32-abi3-macosx_11_0_universal2",
+		"py3-none-macosx_11_0_arm64",
+		"py3-none-macosx_11_0_universal2",
+		"cp34-none-any",
+		"py3-none-any",
+	}
+
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("tags =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDebugTagsJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	cmd := newDebugCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"tags", "--platform", "linux-x86_64", "--python-version", "312", "--format", "json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("debug tags error: %v", err)
+	}
+
+	var tags []tagJSON
+	if err := json.Unmarshal(buf.Bytes(), &tags); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	want := tagJSON{Tag: "cp312-cp312-linux_x86_64", Python: "cp312", ABI: "cp312", Platform: "linux_x86_64"}
+	if len(tags) == 0 || tags[0] != want {
+		t.Fatalf("first tag = %+v, want %+v", tags, want)
+	}
+
+	if last := tags[len(tags)-1]; last.Tag != "py3-none-any" {
+		t.Errorf("last tag = %q, want py3-none-any", last.Tag)
+	}
+}
+
+func TestTagPythonVersion(t *testing.T) {
+	for in, want := range map[string]string{"3.12": "312", "312": "312", "3.9": "39"} {
+		if got, err := tagPythonVersion(in); err != nil || got != want {
+			t.Errorf("tagPythonVersion(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"3", "three", "3.x"} {
+		if _, err := tagPythonVersion(in); err == nil {
+			t.Errorf("tagPythonVersion(%q) should fail", in)
+		}
+	}
+}
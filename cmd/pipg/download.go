@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+func newDownloadCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "download [packages...]",
+		Short: "Resolve requirements and download their wheels into a directory without installing",
+		Long: `Resolve requirements and download the selected wheel of every package into a
+directory, e.g., to carry them to a machine without network access. Wheels
+already in the directory are kept. Wheels are selected for the --python
+interpreter.
+
+Only wheels are downloaded; pipg cannot build sdists, so a package without a
+compatible wheel is an error (there is no --no-binary).
+
+To install offline, copy the directory across and run
+'pipg install --no-index --find-links <dir>' on the target machine.`,
+		RunE: runDownload,
+	}
+
+	cmd.Flags().StringP("requirements", "r", "", "Download the requirements in this file")
+	cmd.Flags().StringP("dest", "d", ".", "Directory to download wheels into")
+	cmd.Flags().StringP("constraint", "c", "", "Constrain versions using a constraints file without downloading its entries")
+	cmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	cmd.Flags().CountP("verbose", "v", "Verbose output")
+	cmd.Flags().Bool("pre", false, "Include pre-release and development versions")
+	cmd.Flags().Bool("no-deps", false, "Skip dependencies, download only specified packages")
+	cmd.Flags().IntP("jobs", "j", 0, "Max concurrent network requests across resolution and downloads; downloads from one host are further capped by --max-per-host (default: GOMAXPROCS)")
+	cmd.Flags().Int("max-per-host", 0, "Max concurrent downloads from any one host, to stay under its rate limits (default: 6)")
+	cmd.Flags().Bool("no-cache", false, "Don't read or write the wheel cache")
+	cmd.Flags().String("cache-dir", "", "Wheel cache directory (default: $PIPG_CACHE_DIR or the platform cache dir)")
+	cmd.Flags().String("cache-max-size", "", "Evict the least recently used wheels once the cache, across all indexes, exceeds this size, e.g., 5GB (default: unbounded)")
+	cmd.Flags().String("index-url", "", "Base URL of the package index (default: $PIP_INDEX_URL or https://pypi.org/pypi)")
+	cmd.Flags().StringArray("extra-index-url", nil, "Fallback package index queried when a package is not on --index-url (repeatable)")
+	cmd.Flags().String("index-auth", "", "Bearer token sent to the package index hosts (default: $PIPG_INDEX_TOKEN)")
+	cmd.Flags().String("index-type", indexTypeJSON, "API of the package indexes: json, or simple for PEP 503 HTML indexes without the JSON API")
+	cmd.Flags().Bool("trusted-index-only", false, "Refuse to download unless every wheel has an index-provided sha256")
+	cmd.Flags().Bool("require-hashes", false, "Refuse to download any package without a --hash pin in the requirements file; implied by any --hash")
+	addTransportFlags(cmd)
+
+	return cmd
+}
+
+func runDownload(cmd *cobra.Command, args []string) error {
+	reqFile, _ := cmd.Flags().GetString("requirements")
+	dest, _ := cmd.Flags().GetString("dest")
+	conFile, _ := cmd.Flags().GetString("constraint")
+	pythonBin, _ := cmd.Flags().GetString("python")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+	pre, _ := cmd.Flags().GetBool("pre")
+	noDeps, _ := cmd.Flags().GetBool("no-deps")
+	jobs, _ := cmd.Flags().GetInt("jobs")
+	perHost, _ := cmd.Flags().GetInt("max-per-host")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	cacheDir, _ := cmd.Flags().GetString("cache-dir")
+	rawCacheMaxSize, _ := cmd.Flags().GetString("cache-max-size")
+	rawIndexURL, _ := cmd.Flags().GetString("index-url")
+	rawExtraURLs, _ := cmd.Flags().GetStringArray("extra-index-url")
+	indexAuth, _ := cmd.Flags().GetString("index-auth")
+	indexType, _ := cmd.Flags().GetString("index-type")
+	trustedIndexOnly, _ := cmd.Flags().GetBool("trusted-index-only")
+	requireHashes, _ := cmd.Flags().GetBool("require-hashes")
+	transport := transportFlags(cmd)
+
+	reqs, err := collectRequirements(args, reqFile)
+	if err != nil {
+		return err
+	}
+
+	requirements := reqs.requirements
+
+	if len(requirements) == 0 {
+		return fmt.Errorf("no packages specified; use 'pipg download <pkg>' or 'pipg download -r requirements.txt'")
+	}
+
+	if err := checkHashPins(requirements, reqs.hashes, requireHashes); err != nil {
+		return err
+	}
+
+	constraints, err := loadConstraints(conFile, reqs.constraints)
+	if err != nil {
+		return err
+	}
+
+	if indexType != indexTypeJSON && indexType != indexTypeSimple {
+		return fmt.Errorf("unknown --index-type %q; expected %s or %s", indexType, indexTypeJSON, indexTypeSimple)
+	}
+
+	cacheMaxSize, err := parseSize(rawCacheMaxSize)
+	if err != nil {
+		return fmt.Errorf("--cache-max-size: %w", err)
+	}
+
+	baseURL, err := indexURL(rawIndexURL, indexType)
+	if err != nil {
+		return err
+	}
+
+	extraURLs, err := extraIndexURLs(rawExtraURLs, indexType)
+	if err != nil {
+		return err
+	}
+
+	creds, baseURL, extraURLs := indexCredentials(indexAuth, baseURL, extraURLs)
+
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return fmt.Errorf("creating download directory: %w", err)
+	}
+
+	logger := newLogger(verbose)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env, err := detectEnv(ctx, pythonBin, "", logger)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := newHTTPClient(transport)
+	if err != nil {
+		return err
+	}
+
+	sem := semaphore.NewWeighted(int64(workerCount(jobs)))
+
+	pypiClient := newIndexClient(indexType,
+		pypi.WithHTTPClient(httpClient),
+		pypi.WithBaseURL(baseURL),
+		pypi.WithExtraBaseURLs(extraURLs),
+		pypi.WithCredentials(creds),
+		pypi.WithSemaphore(sem),
+		pypi.WithLogger(logger),
+	)
+
+	compatTags := buildCompatTags(env)
+	out := cmd.OutOrStdout()
+
+	_, _ = fmt.Fprintf(out, "Resolving dependencies...\n")
+
+	resolved, err := resolveDeps(ctx, requirements, pypiClient, env, compatTags, logger,
+		resolver.WithNoDeps(noDeps),
+		resolver.WithAllowPrerelease(pre),
+		resolver.WithConstraints(constraints),
+	)
+	if err != nil {
+		return err
+	}
+
+	printResolution(out, requirements, resolved)
+
+	plans, err := selectWheels(ctx, resolved, pypiClient, compatTags, env, trustedIndexOnly)
+	if err != nil {
+		return err
+	}
+
+	if err := pinHashes(plans, reqs.hashes, requireHashes); err != nil {
+		return err
+	}
+
+	// Wheels already in dest count as downloaded.
+	cacheOpts := cacheOptions{
+		disabled:  noCache,
+		dir:       cacheDir,
+		namespace: cacheNamespace(baseURL),
+		wheelDir:  dest,
+		maxSize:   cacheMaxSize,
+	}
+
+	results, err := downloadInto(ctx, out, dest, plans, jobs, retryOptions{strategy: backoffExponential, attempts: 3, stall: httpTimeout, perHost: perHost},
+		cacheOpts, downloadClient(httpClient), creds, downloader.VerifyEnforce, sem, logger, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := placeWheels(dest, results); err != nil {
+		return err
+	}
+
+	printDownloadResults(out, results)
+
+	_, _ = fmt.Fprintf(out, "\nSaved %d wheels to %s\n", len(results), dest)
+
+	return nil
+}
+
+// placeWheels copies the wheels served from the cache into dir, so dir holds
+// every downloaded wheel, and points results at the copies.
+func placeWheels(dir string, results []downloader.Result) error {
+	for i, r := range results {
+		dst := filepath.Join(dir, filepath.Base(r.FilePath))
+		if same, _ := sameFile(r.FilePath, dst); same {
+			continue
+		}
+
+		if err := copyWheel(r.FilePath, dst); err != nil {
+			return fmt.Errorf("copying %s to %s: %w", filepath.Base(r.FilePath), dir, err)
+		}
+
+		results[i].FilePath = dst
+	}
+
+	return nil
+}
+
+// sameFile reports whether a and b name the same existing file.
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(ai, bi), nil
+}
+
+// copyWheel copies src to dst through a temporary file, so an interrupted
+// copy never leaves a truncated wheel at dst.
+func copyWheel(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), ".pipg-*.whl.part")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		_ = os.Remove(out.Name())
+
+		return err
+	}
+
+	return os.Rename(out.Name(), dst)
+}
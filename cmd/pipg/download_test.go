@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+)
+
+func TestPlaceWheels(t *testing.T) {
+	dest := t.TempDir()
+	cacheDir := t.TempDir()
+
+	cached := filepath.Join(cacheDir, "six-1.17.0-py3-none-any.whl")
+	downloaded := filepath.Join(dest, "idna-3.7-py3-none-any.whl")
+
+	for _, path := range []string{cached, downloaded} {
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := []downloader.Result{
+		{Name: "six", Version: "1.17.0", FilePath: cached, Cached: true},
+		{Name: "idna", Version: "3.7", FilePath: downloaded},
+	}
+
+	if err := placeWheels(dest, results); err != nil {
+		t.Fatalf("placeWheels() error: %v", err)
+	}
+
+	for _, r := range results {
+		if filepath.Dir(r.FilePath) != dest {
+			t.Errorf("%s left at %s, want it in %s", r.Name, r.FilePath, dest)
+		}
+
+		data, err := os.ReadFile(r.FilePath)
+		if err != nil || string(data) != filepath.Base(r.FilePath) {
+			t.Errorf("%s content = %q, %v", r.FilePath, data, err)
+		}
+	}
+
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("the cached wheel was moved rather than copied: %v", err)
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Errorf("dest holds %d entries, want 2 wheels and no leftovers", len(entries))
+	}
+}
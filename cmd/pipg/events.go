@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+// Event types written by --events, in the order an install emits them.
+const (
+	eventResolveStart     = "resolve_start"
+	eventPackageResolved  = "package_resolved"
+	eventDownloadStart    = "download_start"
+	eventDownloadProgress = "download_progress"
+	eventDownloadDone     = "download_done"
+	eventInstallDone      = "install_done"
+	eventSummary          = "summary"
+)
+
+// event is one line of the --events stream. The JSON field names are a
+// stable protocol for programmatic consumers: fields may be added, never
+// renamed or removed.
+type event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Package events: package_resolved and the download_* events.
+	Package string `json:"package,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// download_progress and download_done. Total is -1 when unknown.
+	Downloaded int64 `json:"downloaded,omitempty"`
+	Total      int64 `json:"total,omitempty"`
+	Cached     bool  `json:"cached,omitempty"`
+
+	// resolve_start (requirements), install_done and summary (packages).
+	Requirements []string `json:"requirements,omitempty"`
+	Packages     int      `json:"packages,omitempty"`
+	Seconds      float64  `json:"seconds,omitempty"`
+}
+
+// progressEventInterval is the least time between two download_progress
+// events for the same file; the last chunk of a download is always reported.
+const progressEventInterval = 250 * time.Millisecond
+
+// openEvents opens the --events target: a file descriptor the caller has
+// left open, given as a number, e.g., "3", or else a file path, which is
+// created or truncated. Events share stdout or stderr with pipg's own output
+// only when the caller asks for it with "1" or "2"; those are not closed.
+func openEvents(target string) (io.WriteCloser, error) {
+	if fd, err := strconv.Atoi(target); err == nil {
+		switch {
+		case fd == 1:
+			return nopWriteCloser{os.Stdout}, nil
+		case fd == 2:
+			return nopWriteCloser{os.Stderr}, nil
+		case fd < 3:
+			return nil, fmt.Errorf("invalid --events file descriptor %d", fd)
+		}
+
+		return os.NewFile(uintptr(fd), "events"), nil
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, fmt.Errorf("opening --events file: %w", err)
+	}
+
+	return f, nil
+}
+
+// nopWriteCloser is a writer whose Close does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// eventWriter encodes events as newline-delimited JSON. It is safe for
+// concurrent use, and a nil *eventWriter discards everything so callers
+// need not check whether --events is set.
+type eventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+
+	lastProgress map[string]time.Time // by file, when it was last reported
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w), now: time.Now, lastProgress: make(map[string]time.Time)}
+}
+
+func (e *eventWriter) emit(ev event) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ev.Time = e.now().UTC()
+	_ = e.enc.Encode(ev)
+}
+
+func (e *eventWriter) resolveStart(requirements []string) {
+	e.emit(event{Type: eventResolveStart, Requirements: requirements})
+}
+
+func (e *eventWriter) resolved(pkgs []resolver.ResolvedPackage) {
+	for _, pkg := range pkgs {
+		e.emit(event{Type: eventPackageResolved, Package: pkg.Name, Version: pkg.Version})
+	}
+}
+
+func (e *eventWriter) downloadStart(requests []downloader.Request) {
+	for _, req := range requests {
+		e.emit(event{Type: eventDownloadStart, Package: req.Name, Version: req.Version})
+	}
+}
+
+// progress returns a downloader.ProgressFunc emitting download_progress
+// events, or nil when events are disabled.
+func (e *eventWriter) progress() downloader.ProgressFunc {
+	if e == nil {
+		return nil
+	}
+
+	return func(req downloader.Request, downloaded, total int64) {
+		if !e.progressDue(req.URL, downloaded == total) {
+			return
+		}
+
+		e.emit(event{Type: eventDownloadProgress, Package: req.Name, Version: req.Version, Downloaded: downloaded, Total: total})
+	}
+}
+
+// progressDue reports whether a download_progress event for the file at url
+// should be written now, at most one per progressEventInterval unless done.
+func (e *eventWriter) progressDue(url string, done bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if last, ok := e.lastProgress[url]; ok && !done && now.Sub(last) < progressEventInterval {
+		return false
+	}
+
+	e.lastProgress[url] = now
+
+	return true
+}
+
+func (e *eventWriter) downloadDone(results []downloader.Result) {
+	for _, r := range results {
+		e.emit(event{Type: eventDownloadDone, Package: r.Name, Version: r.Version, Downloaded: r.Size, Total: r.Size, Cached: r.Cached})
+	}
+}
+
+func (e *eventWriter) installDone(packages int) {
+	e.emit(event{Type: eventInstallDone, Packages: packages})
+}
+
+func (e *eventWriter) summary(packages int, elapsed time.Duration) {
+	e.emit(event{Type: eventSummary, Packages: packages, Seconds: elapsed.Seconds()})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+type nopInstaller struct{}
+
+func (nopInstaller) Install(context.Context, []downloader.Result) error { return nil }
+
+func TestEventStreamOrder(t *testing.T) {
+	t.Setenv("PIPG_CACHE_DIR", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("wheel"))
+	}))
+	t.Cleanup(srv.Close)
+
+	wheel := wheelURL("six", "1.17.0", "")
+	wheel.URL = srv.URL + "/" + wheel.Filename
+
+	client := &mockClient{packages: map[string]*pypi.PackageInfo{
+		"six": {
+			Info: pypi.Info{Name: "six", Version: "1.17.0"},
+			URLs: []pypi.URL{wheel},
+		},
+	}}
+
+	var buf bytes.Buffer
+
+	events := newEventWriter(&buf)
+	ctx := context.Background()
+	env := testEnv()
+	tags := buildCompatTags(env)
+	logger := slog.New(slog.DiscardHandler)
+	requirements := []string{"six"}
+
+	events.resolveStart(requirements)
+
+	resolved, err := resolveDeps(ctx, requirements, client, env, tags, logger)
+	if err != nil {
+		t.Fatalf("resolveDeps() error: %v", err)
+	}
+
+	events.resolved(resolved)
+
+	plans, err := selectWheels(ctx, resolved, client, tags, env, false)
+	if err != nil {
+		t.Fatalf("selectWheels() error: %v", err)
+	}
+
+	results, _, err := downloadPackages(ctx, io.Discard, plans, 1, retryOptions{strategy: backoffExponential, attempts: 3}, cacheOptions{}, srv.Client(), nil, downloader.VerifyEnforce, nil, logger, events)
+	if err != nil {
+		t.Fatalf("downloadPackages() error: %v", err)
+	}
+
+	if err := installPackages(ctx, io.Discard, nopInstaller{}, results, &installProgress{}, events); err != nil {
+		t.Fatalf("installPackages() error: %v", err)
+	}
+
+	events.summary(len(results), time.Second)
+
+	var types []string
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var ev event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+
+		if ev.Time.IsZero() {
+			t.Errorf("%s event has no time", ev.Type)
+		}
+
+		types = append(types, ev.Type)
+	}
+
+	// Progress may arrive in several chunks; compare the collapsed sequence.
+	want := []string{
+		eventResolveStart,
+		eventPackageResolved,
+		eventDownloadStart,
+		eventDownloadProgress,
+		eventDownloadDone,
+		eventInstallDone,
+		eventSummary,
+	}
+
+	if got := slices.Compact(types); !slices.Equal(got, want) {
+		t.Errorf("event types = %v, want %v", got, want)
+	}
+}
+
+func TestNilEventWriterDiscards(t *testing.T) {
+	var events *eventWriter
+
+	events.resolveStart([]string{"six"})
+	events.summary(1, time.Second)
+
+	if events.progress() != nil {
+		t.Error("nil writer should not install a progress callback")
+	}
+}
+
+func TestProgressEventsThrottled(t *testing.T) {
+	var buf bytes.Buffer
+
+	events := newEventWriter(&buf)
+
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	events.now = func() time.Time { return now }
+
+	progress := events.progress()
+	req := downloader.Request{Name: "six", Version: "1.17.0", URL: "https://files.example/six.whl"}
+
+	for downloaded := int64(1); downloaded <= 100; downloaded++ {
+		if downloaded == 50 {
+			now = now.Add(progressEventInterval)
+		}
+
+		progress(req, downloaded, 100)
+	}
+
+	var got []int64
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var ev event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+
+		got = append(got, ev.Downloaded)
+	}
+
+	if want := []int64{1, 50, 100}; !slices.Equal(got, want) {
+		t.Errorf("progress events at %v bytes, want %v", got, want)
+	}
+}
+
+func TestOpenEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+
+	out, err := openEvents(path)
+	if err != nil {
+		t.Fatalf("openEvents(%q) error: %v", path, err)
+	}
+
+	newEventWriter(out).installDone(1)
+
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(data, []byte(`"type":"install_done"`)) {
+		t.Errorf("events file = %q, %v; want an install_done event", data, err)
+	}
+
+	if _, err := openEvents("0"); err == nil {
+		t.Error("expected an error for --events 0 (stdin)")
+	}
+}
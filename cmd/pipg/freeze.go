@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+func newFreezeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "freeze",
+		Short: "Output installed packages in requirements format",
+		Args:  cobra.NoArgs,
+		RunE:  runFreeze,
+	}
+
+	cmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	cmd.Flags().String("target", "", "Target directory (default: auto-detect site-packages)")
+	cmd.Flags().CountP("verbose", "v", "Verbose output")
+	cmd.Flags().Bool("all", false, "Include packages not installed by pipg")
+
+	return cmd
+}
+
+func runFreeze(cmd *cobra.Command, _ []string) error {
+	pythonBin, _ := cmd.Flags().GetString("python")
+	targetDir, _ := cmd.Flags().GetString("target")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+	all, _ := cmd.Flags().GetBool("all")
+
+	logger := newLogger(verbose)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env, err := detectEnv(ctx, pythonBin, targetDir, logger)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := installer.New(env, installer.WithLogger(logger)).Installed()
+	if err != nil {
+		return err
+	}
+
+	return writeFreeze(cmd.OutOrStdout(), pkgs, all)
+}
+
+// writeFreeze writes name==version lines sorted case-insensitively. Unless
+// all is set, only packages installed by pipg are listed.
+func writeFreeze(w io.Writer, pkgs []installer.Installed, all bool) error {
+	pkgs = slices.Clone(pkgs)
+	if !all {
+		pkgs = slices.DeleteFunc(pkgs, func(p installer.Installed) bool { return p.Installer != "pipg" })
+	}
+
+	sortInstalled(pkgs)
+
+	for _, p := range pkgs {
+		if _, err := fmt.Fprintf(w, "%s==%s\n", p.Name, p.Version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortInstalled sorts packages by name, case-insensitively.
+func sortInstalled(pkgs []installer.Installed) {
+	slices.SortFunc(pkgs, func(a, b installer.Installed) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+}
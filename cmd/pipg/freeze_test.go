@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+func TestWriteFreeze(t *testing.T) {
+	pkgs := []installer.Installed{
+		{Name: "six", Version: "1.16.0"},
+		{Name: "Werkzeug", Version: "3.0.1", Installer: "pipg"},
+		{Name: "flask", Version: "3.0.0", Installer: "pipg"},
+		{Name: "Jinja2", Version: "3.1.2", Installer: "pip"},
+	}
+
+	tests := []struct {
+		name string
+		all  bool
+		want string
+	}{
+		{"pipg only", false, "flask==3.0.0\nWerkzeug==3.0.1\n"},
+		{"all", true, "flask==3.0.0\nJinja2==3.1.2\nsix==1.16.0\nWerkzeug==3.0.1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeFreeze(&buf, pkgs, tt.all); err != nil {
+				t.Fatalf("writeFreeze() error: %v", err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("writeFreeze() =\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+
+			// The output must round-trip through pipg install -r.
+			path := filepath.Join(t.TempDir(), "requirements.txt")
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			reqs, err := parseRequirementsFile(path)
+			if err != nil {
+				t.Fatalf("parseRequirementsFile() error: %v", err)
+			}
+
+			for i, r := range reqs {
+				if req := resolver.ParseRequirement(r); req.Specifier == "" || req.Name == "" {
+					t.Errorf("requirement %d %q did not parse as a pin: %+v", i, r, req)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+// Exit codes returned by the CLI.
+const (
+	exitError       = 1
+	exitInterrupted = 130 // 128 + SIGINT, matching shells and pip
+)
+
+// installProgress tracks how far an install run got, for interrupt reporting.
+type installProgress struct {
+	phase      string
+	downloaded int
+	installed  int
+}
+
+// interruptedError is returned when the run was canceled (e.g., Ctrl-C).
+// It is reported as a summary rather than as a failure.
+type interruptedError struct {
+	progress installProgress
+}
+
+func (e *interruptedError) Error() string {
+	return fmt.Sprintf("Interrupted during %s — %d packages downloaded, %d installed, cleaning up",
+		e.progress.phase, e.progress.downloaded, e.progress.installed)
+}
+
+// checkInterrupted converts err into an *interruptedError when ctx was
+// canceled, recording any partial progress carried by the error.
+func checkInterrupted(ctx context.Context, err error, progress *installProgress) error {
+	var dlErr *downloader.PartialError
+	if errors.As(err, &dlErr) {
+		progress.downloaded = dlErr.Completed
+	}
+
+	var instErr *installer.PartialError
+	if errors.As(err, &instErr) {
+		progress.installed = len(instErr.Installed)
+	}
+
+	if ctx.Err() == nil || !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return &interruptedError{progress: *progress}
+}
+
+// exitCode reports err on w and returns the process exit status.
+func exitCode(err error, w io.Writer) int {
+	var ie *interruptedError
+	if errors.As(err, &ie) {
+		_, _ = fmt.Fprintf(w, "\n%s\n", ie.Error())
+
+		return exitInterrupted
+	}
+
+	_, _ = fmt.Fprintf(w, "error: %v\n", err)
+
+	return exitError
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+// cancelingInstaller installs the first n packages, then cancels the run.
+type cancelingInstaller struct {
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingInstaller) Install(ctx context.Context, downloads []downloader.Result) error {
+	var installed []string
+
+	for i, dl := range downloads {
+		if i == c.n {
+			c.cancel()
+		}
+
+		if err := ctx.Err(); err != nil {
+			return &installer.PartialError{Installed: installed, Err: fmt.Errorf("installation canceled: %w", err)}
+		}
+
+		installed = append(installed, dl.Name)
+	}
+
+	return nil
+}
+
+func TestInstallInterruptedSummary(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := []downloader.Result{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	progress := &installProgress{phase: "install", downloaded: len(results)}
+
+	err := installPackages(ctx, io.Discard, &cancelingInstaller{n: 1, cancel: cancel}, results, progress, nil)
+
+	var ie *interruptedError
+	if !errors.As(err, &ie) {
+		t.Fatalf("expected *interruptedError, got %T: %v", err, err)
+	}
+
+	var buf bytes.Buffer
+	if code := exitCode(err, &buf); code != exitInterrupted {
+		t.Errorf("exitCode() = %d, want %d", code, exitInterrupted)
+	}
+
+	want := "Interrupted during install — 3 packages downloaded, 1 installed, cleaning up"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("summary = %q, want it to contain %q", buf.String(), want)
+	}
+
+	if strings.Contains(buf.String(), "error:") {
+		t.Errorf("interrupt should not be reported as an error: %q", buf.String())
+	}
+}
+
+func TestCheckInterruptedDownloadProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	progress := &installProgress{phase: "download"}
+	err := checkInterrupted(ctx, &downloader.PartialError{Completed: 2, Err: context.Canceled}, progress)
+
+	var ie *interruptedError
+	if !errors.As(err, &ie) {
+		t.Fatalf("expected *interruptedError, got %T: %v", err, err)
+	}
+
+	if ie.progress.downloaded != 2 {
+		t.Errorf("downloaded = %d, want 2", ie.progress.downloaded)
+	}
+}
+
+func TestExitCodeGenuineError(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := checkInterrupted(context.Background(), errors.New("no compatible wheel"), &installProgress{})
+
+	if code := exitCode(err, &buf); code != exitError {
+		t.Errorf("exitCode() = %d, want %d", code, exitError)
+	}
+
+	if got := buf.String(); got != "error: no compatible wheel\n" {
+		t.Errorf("output = %q", got)
+	}
+}
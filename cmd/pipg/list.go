@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+// formatColumns is the human-readable table output of the list command.
+const formatColumns = "columns"
+
+var listFormats = []string{formatColumns, formatJSON}
+
+// installedEntry is the JSON form of an installed package, the schema of
+// "pip list --format json".
+type installedEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// verboseEntry adds the fields of "pip list --verbose --format json".
+type verboseEntry struct {
+	installedEntry
+
+	Location  string `json:"location"`
+	Installer string `json:"installer"`
+}
+
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List installed packages",
+		Args:  cobra.NoArgs,
+		RunE:  runList,
+	}
+
+	cmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	cmd.Flags().String("target", "", "Target directory (default: auto-detect site-packages)")
+	cmd.Flags().CountP("verbose", "v", "Verbose output; adds location and installer to --format json")
+	cmd.Flags().String("format", formatColumns, "Output format: columns or json")
+
+	return cmd
+}
+
+func runList(cmd *cobra.Command, _ []string) error {
+	pythonBin, _ := cmd.Flags().GetString("python")
+	targetDir, _ := cmd.Flags().GetString("target")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+	format, _ := cmd.Flags().GetString("format")
+
+	if !slices.Contains(listFormats, format) {
+		return fmt.Errorf("unknown --format %q; expected one of: %s", format, strings.Join(listFormats, ", "))
+	}
+
+	logger := newLogger(verbose)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env, err := detectEnv(ctx, pythonBin, targetDir, logger)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := installer.New(env, installer.WithLogger(logger)).Installed()
+	if err != nil {
+		return err
+	}
+
+	return writeList(cmd.OutOrStdout(), format, pkgs, verbose > 0)
+}
+
+// writeList writes installed packages sorted by name as a two-column table
+// or, with formatJSON, as a JSON array of {"name", "version"} objects like
+// pip's. verbose adds each package's "location" and "installer" to the JSON.
+func writeList(w io.Writer, format string, pkgs []installer.Installed, verbose bool) error {
+	pkgs = slices.Clone(pkgs)
+	sortInstalled(pkgs)
+
+	if format == formatJSON {
+		return json.NewEncoder(w).Encode(listEntries(pkgs, verbose))
+	}
+
+	nameWidth, versionWidth := len("Package"), len("Version")
+	for _, p := range pkgs {
+		nameWidth = max(nameWidth, len(p.Name))
+		versionWidth = max(versionWidth, len(p.Version))
+	}
+
+	rows := [][2]string{
+		{"Package", "Version"},
+		{strings.Repeat("-", nameWidth), strings.Repeat("-", versionWidth)},
+	}
+	for _, p := range pkgs {
+		rows = append(rows, [2]string{p.Name, p.Version})
+	}
+
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "%-*s %s\n", nameWidth, row[0], row[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listEntries converts pkgs to their JSON entries.
+func listEntries(pkgs []installer.Installed, verbose bool) any {
+	if !verbose {
+		entries := make([]installedEntry, len(pkgs))
+		for i, p := range pkgs {
+			entries[i] = installedEntry{Name: p.Name, Version: p.Version}
+		}
+
+		return entries
+	}
+
+	entries := make([]verboseEntry, len(pkgs))
+	for i, p := range pkgs {
+		entries[i] = verboseEntry{
+			installedEntry: installedEntry{Name: p.Name, Version: p.Version},
+			Location:       filepath.Dir(p.DistInfo),
+			Installer:      p.Installer,
+		}
+	}
+
+	return entries
+}
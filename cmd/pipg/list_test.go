@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+func TestWriteList(t *testing.T) {
+	pkgs := []installer.Installed{
+		{Name: "six", Version: "1.16.0"},
+		{Name: "Flask", Version: "3.0.0", Installer: "pipg"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{formatColumns, "Package Version\n------- -------\nFlask   3.0.0\nsix     1.16.0\n"},
+		{formatJSON, `[{"name":"Flask","version":"3.0.0"},{"name":"six","version":"1.16.0"}]` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeList(&buf, tt.format, pkgs, false); err != nil {
+				t.Fatalf("writeList() error: %v", err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("writeList() =\n%q\nwant:\n%q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestWriteListJSONMatchesPip checks the keys and values against the schema
+// of "pip list --format json", with and without --verbose.
+func TestWriteListJSONMatchesPip(t *testing.T) {
+	site := filepath.Join("venv", "lib", "python3.12", "site-packages")
+	pkgs := []installer.Installed{
+		{Name: "six", Version: "1.16.0", DistInfo: filepath.Join(site, "six-1.16.0.dist-info")},
+		{Name: "Flask", Version: "3.0.0", Installer: "pip", DistInfo: filepath.Join(site, "flask-3.0.0.dist-info")},
+	}
+
+	tests := []struct {
+		verbose bool
+		want    []map[string]string
+	}{
+		{false, []map[string]string{
+			{"name": "Flask", "version": "3.0.0"},
+			{"name": "six", "version": "1.16.0"},
+		}},
+		{true, []map[string]string{
+			{"name": "Flask", "version": "3.0.0", "location": site, "installer": "pip"},
+			{"name": "six", "version": "1.16.0", "location": site, "installer": ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeList(&buf, formatJSON, pkgs, tt.verbose); err != nil {
+			t.Fatalf("writeList() error: %v", err)
+		}
+
+		var got []map[string]string
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("output is not a JSON array of string objects: %v\n%s", err, buf.String())
+		}
+
+		if !slices.EqualFunc(got, tt.want, maps.Equal) {
+			t.Errorf("verbose=%v: got %v, want %v", tt.verbose, got, tt.want)
+		}
+	}
+}
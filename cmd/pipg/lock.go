@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+	"github.com/bilusteknoloji/pipg/internal/python"
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+// lockfileVersion is the schema version written to and accepted from
+// lockfiles. It changes only when a field changes meaning or goes away.
+const lockfileVersion = 1
+
+const defaultLockFile = "pipg.lock"
+
+// lockfile is the JSON form of a resolved install: exactly which wheel to
+// fetch for every package, and a hash of the requirements it came from.
+//
+//	{
+//	  "version": 1,
+//	  "requirements_hash": "sha256:…",
+//	  "packages": [
+//	    {"name": "flask", "version": "3.0.3", "filename": "flask-3.0.3-py3-none-any.whl",
+//	     "url": "https://…", "sha256": "…"}
+//	  ]
+//	}
+type lockfile struct {
+	Version          int           `json:"version"`
+	RequirementsHash string        `json:"requirements_hash"`
+	Packages         []lockedWheel `json:"packages"`
+}
+
+// lockedWheel pins one package to one wheel. SHA256 is empty when the index
+// provided no digest. DirectURL is set for a "name @ url" requirement, so a
+// locked install still records it in direct_url.json.
+type lockedWheel struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Filename  string `json:"filename"`
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	DirectURL string `json:"direct_url,omitempty"`
+}
+
+// newLockfile builds a lockfile from download plans, sorted by name.
+func newLockfile(requirements []string, plans []downloadPlan) *lockfile {
+	lock := &lockfile{
+		Version:          lockfileVersion,
+		RequirementsHash: requirementsHash(requirements),
+		Packages:         make([]lockedWheel, len(plans)),
+	}
+
+	for i, p := range plans {
+		lock.Packages[i] = lockedWheel{
+			Name:      p.pkg.Name,
+			Version:   p.pkg.Version,
+			Filename:  p.wheelURL.Filename,
+			URL:       p.wheelURL.URL,
+			SHA256:    p.wheelURL.Digests.SHA256,
+			DirectURL: p.pkg.URL,
+		}
+	}
+
+	slices.SortFunc(lock.Packages, func(a, b lockedWheel) int { return strings.Compare(a.Name, b.Name) })
+
+	return lock
+}
+
+// requirementsHash digests the requirements independently of their order and
+// surrounding whitespace, so reordering a requirements file is not drift.
+func requirementsHash(requirements []string) string {
+	lines := make([]string, 0, len(requirements))
+	for _, r := range requirements {
+		if r = strings.TrimSpace(r); r != "" {
+			lines = append(lines, r)
+		}
+	}
+
+	slices.Sort(lines)
+	lines = slices.Compact(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// writeLockfile writes lock to w as indented JSON.
+func writeLockfile(w io.Writer, lock *lockfile) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(lock)
+}
+
+// readLockfile loads and validates the lockfile at path.
+func readLockfile(path string) (*lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading lockfile: %w", err)
+	}
+
+	var lock lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parsing lockfile %s: %w", path, err)
+	}
+
+	if lock.Version != lockfileVersion {
+		return nil, fmt.Errorf("lockfile %s has version %d; this pipg reads version %d", path, lock.Version, lockfileVersion)
+	}
+
+	for i, p := range lock.Packages {
+		if p.Name == "" || p.Version == "" || p.Filename == "" || p.URL == "" {
+			return nil, fmt.Errorf("lockfile %s: package %d needs name, version, filename and url", path, i+1)
+		}
+	}
+
+	return &lock, nil
+}
+
+// resolved returns the locked packages in the form the resolver produces.
+func (l *lockfile) resolved() []resolver.ResolvedPackage {
+	pkgs := make([]resolver.ResolvedPackage, len(l.Packages))
+	for i, p := range l.Packages {
+		pkgs[i] = resolver.ResolvedPackage{Name: p.Name, Version: p.Version, URL: p.DirectURL}
+	}
+
+	return pkgs
+}
+
+// plans returns download plans for the locked wheels of pkgs, failing when a
+// wheel does not suit env, e.g., a lockfile written on another platform.
+func (l *lockfile) plans(pkgs []resolver.ResolvedPackage, compatTags []downloader.WheelTag, env *python.Environment) ([]downloadPlan, error) {
+	wheels := make(map[string]lockedWheel, len(l.Packages))
+	for _, p := range l.Packages {
+		wheels[p.Name] = p
+	}
+
+	plans := make([]downloadPlan, 0, len(pkgs))
+
+	for _, pkg := range pkgs {
+		w := wheels[pkg.Name]
+		u := pypi.URL{
+			Filename:    w.Filename,
+			URL:         w.URL,
+			PackageType: "bdist_wheel",
+			Digests:     pypi.Digests{SHA256: w.SHA256},
+		}
+
+		if _, err := downloader.SelectWheel([]pypi.URL{u}, compatTags, fullPythonVersion(env)); err != nil {
+			return nil, fmt.Errorf("locked wheel %s does not suit this environment (platform: %s, python: cp%s): %w",
+				w.Filename, wheelPlatform(env.PlatformTag), env.PythonVersion, err)
+		}
+
+		plans = append(plans, downloadPlan{pkg: pkg, wheelURL: u})
+	}
+
+	return plans, nil
+}
+
+func newLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock [packages...]",
+		Short: "Resolve requirements and write the selected wheels to a lockfile",
+		RunE:  runLock,
+	}
+
+	cmd.Flags().StringP("requirements", "r", "", "Lock the requirements in this file")
+	cmd.Flags().StringP("output", "o", defaultLockFile, "Lockfile to write")
+	cmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	cmd.Flags().CountP("verbose", "v", "Verbose output")
+	cmd.Flags().Bool("pre", false, "Include pre-release and development versions")
+	cmd.Flags().String("index-url", "", "Base URL of the package index (default: $PIP_INDEX_URL or https://pypi.org/pypi)")
+	cmd.Flags().StringArray("extra-index-url", nil, "Fallback package index queried when a package is not on --index-url (repeatable)")
+	cmd.Flags().String("index-auth", "", "Bearer token sent to the package index hosts (default: $PIPG_INDEX_TOKEN)")
+	cmd.Flags().String("index-type", indexTypeJSON, "API of the package indexes: json, or simple for PEP 503 HTML indexes without the JSON API")
+	cmd.Flags().Bool("trusted-index-only", false, "Refuse to lock unless every wheel has an index-provided sha256")
+	cmd.Flags().Duration("timeout", httpTimeout, "Abort an index request after this long")
+	addTransportFlags(cmd)
+
+	return cmd
+}
+
+func runLock(cmd *cobra.Command, args []string) error {
+	reqFile, _ := cmd.Flags().GetString("requirements")
+	output, _ := cmd.Flags().GetString("output")
+	pythonBin, _ := cmd.Flags().GetString("python")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+	pre, _ := cmd.Flags().GetBool("pre")
+	rawIndexURL, _ := cmd.Flags().GetString("index-url")
+	rawExtraURLs, _ := cmd.Flags().GetStringArray("extra-index-url")
+	indexAuth, _ := cmd.Flags().GetString("index-auth")
+	indexType, _ := cmd.Flags().GetString("index-type")
+	trustedIndexOnly, _ := cmd.Flags().GetBool("trusted-index-only")
+	transport := transportFlags(cmd)
+	transport.timeout, _ = cmd.Flags().GetDuration("timeout")
+
+	reqs, err := collectRequirements(args, reqFile)
+	if err != nil {
+		return err
+	}
+
+	requirements := reqs.requirements
+
+	constraints, err := loadConstraints("", reqs.constraints)
+	if err != nil {
+		return err
+	}
+
+	if len(requirements) == 0 {
+		return fmt.Errorf("no packages specified; use 'pipg lock <pkg>' or 'pipg lock -r requirements.txt'")
+	}
+
+	if indexType != indexTypeJSON && indexType != indexTypeSimple {
+		return fmt.Errorf("unknown --index-type %q; expected %s or %s", indexType, indexTypeJSON, indexTypeSimple)
+	}
+
+	baseURL, err := indexURL(rawIndexURL, indexType)
+	if err != nil {
+		return err
+	}
+
+	extraURLs, err := extraIndexURLs(rawExtraURLs, indexType)
+	if err != nil {
+		return err
+	}
+
+	creds, baseURL, extraURLs := indexCredentials(indexAuth, baseURL, extraURLs)
+
+	logger := newLogger(verbose)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env, err := detectEnv(ctx, pythonBin, "", logger)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := newHTTPClient(transport)
+	if err != nil {
+		return err
+	}
+
+	pypiClient := newIndexClient(indexType,
+		pypi.WithHTTPClient(httpClient),
+		pypi.WithBaseURL(baseURL),
+		pypi.WithExtraBaseURLs(extraURLs),
+		pypi.WithCredentials(creds),
+		pypi.WithLogger(logger),
+	)
+
+	compatTags := buildCompatTags(env)
+
+	resolved, err := resolveDeps(ctx, requirements, pypiClient, env, compatTags, logger, resolver.WithAllowPrerelease(pre), resolver.WithConstraints(constraints))
+	if err != nil {
+		return err
+	}
+
+	plans, err := selectWheels(ctx, resolved, pypiClient, compatTags, env, trustedIndexOnly)
+	if err != nil {
+		return err
+	}
+
+	if unverified := unverifiedWheels(plans); len(unverified) > 0 {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "WARNING: the index provides no sha256 digest for these wheels, so installing the lockfile cannot verify them:\n  %s\n",
+			strings.Join(unverified, "\n  "))
+	}
+
+	lock := newLockfile(requirements, plans)
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("creating lockfile: %w", err)
+	}
+
+	if err := writeLockfile(f, lock); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Locked %d packages to %s\n", len(lock.Packages), output)
+
+	return nil
+}
+
+// warnLockDrift warns when requirements given alongside --locked differ from
+// those the lockfile was written from.
+func warnLockDrift(w io.Writer, lock *lockfile, path string, requirements []string) {
+	if len(requirements) == 0 || requirementsHash(requirements) == lock.RequirementsHash {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "WARNING: the requirements differ from those %s was locked from; run 'pipg lock' to update it\n", path)
+}
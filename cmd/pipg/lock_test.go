@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+func TestLockfileRoundTrip(t *testing.T) {
+	requirements := []string{"flask>=3.0", "requests"}
+	plans := []downloadPlan{
+		{pkg: resolver.ResolvedPackage{Name: "werkzeug", Version: "3.0.1"}, wheelURL: wheelURL("werkzeug", "3.0.1", "bbbb")},
+		{pkg: resolver.ResolvedPackage{Name: "flask", Version: "3.0.3"}, wheelURL: wheelURL("flask", "3.0.3", "aaaa")},
+	}
+
+	var buf bytes.Buffer
+	if err := writeLockfile(&buf, newLockfile(requirements, plans)); err != nil {
+		t.Fatalf("writeLockfile() error: %v", err)
+	}
+
+	for _, field := range []string{`"version": 1`, `"requirements_hash": "sha256:`, `"filename": "flask-3.0.3-py3-none-any.whl"`, `"sha256": "aaaa"`} {
+		if !strings.Contains(buf.String(), field) {
+			t.Errorf("lockfile missing %s:\n%s", field, buf.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "pipg.lock")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := readLockfile(path)
+	if err != nil {
+		t.Fatalf("readLockfile() error: %v", err)
+	}
+
+	env := testEnv()
+
+	got, err := lock.plans(lock.resolved(), buildCompatTags(env), env)
+	if err != nil {
+		t.Fatalf("plans() error: %v", err)
+	}
+
+	// The lockfile is sorted by name; everything needed to download survives.
+	want := []downloadPlan{plans[1], plans[0]}
+	if len(got) != len(want) {
+		t.Fatalf("got %d plans, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.pkg.Name != w.pkg.Name || g.pkg.Version != w.pkg.Version ||
+			g.wheelURL.Filename != w.wheelURL.Filename || g.wheelURL.URL != w.wheelURL.URL ||
+			g.wheelURL.Digests.SHA256 != w.wheelURL.Digests.SHA256 {
+			t.Errorf("plan[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+
+	var again bytes.Buffer
+	if err := writeLockfile(&again, lock); err != nil {
+		t.Fatalf("writeLockfile() error: %v", err)
+	}
+
+	if again.String() != buf.String() {
+		t.Errorf("lockfile changed on round trip:\n%s\nwant:\n%s", again.String(), buf.String())
+	}
+}
+
+func TestLockfileRoundTripDirectURL(t *testing.T) {
+	const directURL = "https://example.com/wheels/mypkg-1.0-py3-none-any.whl#sha256=cccc"
+
+	file, _, err := pypi.WheelFromURL(directURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plans := []downloadPlan{
+		{pkg: resolver.ResolvedPackage{Name: "mypkg", Version: "1.0", URL: directURL}, wheelURL: file},
+		{pkg: resolver.ResolvedPackage{Name: "six", Version: "1.17.0"}, wheelURL: wheelURL("six", "1.17.0", "aaaa")},
+	}
+
+	var buf bytes.Buffer
+	if err := writeLockfile(&buf, newLockfile([]string{"mypkg @ " + directURL}, plans)); err != nil {
+		t.Fatalf("writeLockfile() error: %v", err)
+	}
+
+	if got := strings.Count(buf.String(), `"direct_url"`); got != 1 {
+		t.Errorf("lockfile has %d direct_url fields, want 1:\n%s", got, buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "pipg.lock")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := readLockfile(path)
+	if err != nil {
+		t.Fatalf("readLockfile() error: %v", err)
+	}
+
+	env := testEnv()
+
+	got, err := lock.plans(lock.resolved(), buildCompatTags(env), env)
+	if err != nil {
+		t.Fatalf("plans() error: %v", err)
+	}
+
+	if got[0].pkg.URL != directURL || got[0].wheelURL.URL != file.URL || got[0].wheelURL.Digests.SHA256 != "cccc" {
+		t.Errorf("plan[0] = %+v, want the direct URL package", got[0])
+	}
+
+	// Only the direct URL package gets a direct_url.json on install.
+	if names := directURLPackages(got); !slices.Equal(names, []string{"mypkg"}) {
+		t.Errorf("directURLPackages() = %v, want [mypkg]", names)
+	}
+}
+
+func TestRequirementsHash(t *testing.T) {
+	a := requirementsHash([]string{"flask>=3.0", "requests"})
+	b := requirementsHash([]string{" requests", "flask>=3.0", "requests"})
+
+	if a != b {
+		t.Errorf("order and duplicates changed the hash: %s != %s", a, b)
+	}
+
+	if c := requirementsHash([]string{"flask>=3.1", "requests"}); c == a {
+		t.Error("a changed specifier kept the same hash")
+	}
+}
+
+func TestLockDriftWarning(t *testing.T) {
+	lock := newLockfile([]string{"flask"}, nil)
+
+	tests := []struct {
+		name         string
+		requirements []string
+		wantWarning  bool
+	}{
+		{name: "no requirements given", requirements: nil},
+		{name: "same requirements", requirements: []string{"flask"}},
+		{name: "drifted requirements", requirements: []string{"flask", "requests"}, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			warnLockDrift(&buf, lock, "pipg.lock", tt.requirements)
+
+			if got := strings.Contains(buf.String(), "WARNING"); got != tt.wantWarning {
+				t.Errorf("warning = %q, want warning %v", buf.String(), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestReadLockfileRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown version", content: `{"version": 2, "packages": []}`, wantErr: "version 2"},
+		{name: "missing url", content: `{"version": 1, "packages": [{"name": "six", "version": "1.17.0", "filename": "six-1.17.0-py3-none-any.whl"}]}`, wantErr: "package 1"},
+		{name: "not json", content: `six==1.17.0`, wantErr: "parsing lockfile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pipg.lock")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := readLockfile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readLockfile() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLockedPlansRejectIncompatibleWheel(t *testing.T) {
+	lock := &lockfile{Version: lockfileVersion, Packages: []lockedWheel{{
+		Name:     "numpy",
+		Version:  "2.0.0",
+		Filename: "numpy-2.0.0-cp312-cp312-win_amd64.whl",
+		URL:      "https://files.example.com/numpy-2.0.0-cp312-cp312-win_amd64.whl",
+	}}}
+
+	env := testEnv()
+
+	if _, err := lock.plans(lock.resolved(), buildCompatTags(env), env); err == nil {
+		t.Error("expected an error for a wheel from another platform, got nil")
+	}
+}
+
+func TestLockedPlansWithoutDigestFailTrustedIndexOnly(t *testing.T) {
+	lock := &lockfile{Version: lockfileVersion, Packages: []lockedWheel{
+		{Name: "flask", Version: "3.0.3", Filename: "flask-3.0.3-py3-none-any.whl", URL: "https://files.example.com/flask-3.0.3-py3-none-any.whl", SHA256: "aaaa"},
+		{Name: "six", Version: "1.16.0", Filename: "six-1.16.0-py2.py3-none-any.whl", URL: "https://files.example.com/six-1.16.0-py2.py3-none-any.whl"},
+	}}
+
+	env := testEnv()
+
+	plans, err := lock.plans(lock.resolved(), buildCompatTags(env), env)
+	if err != nil {
+		t.Fatalf("plans() error: %v", err)
+	}
+
+	err = requireDigests(plans)
+	if err == nil {
+		t.Fatal("expected an error for a locked wheel without sha256, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "six 1.16.0") || strings.Contains(err.Error(), "flask") {
+		t.Errorf("error = %q, want only six named", err)
+	}
+}
@@ -3,18 +3,25 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/bilusteknoloji/pipg/internal/cache"
 	"github.com/bilusteknoloji/pipg/internal/downloader"
@@ -26,10 +33,37 @@ import (
 
 var version = "0.1.2"
 
+// Values for --backoff-strategy.
+const (
+	backoffExponential = "exponential"
+	backoffConstant    = "constant"
+
+	// constantBackoffDelay is the flat delay between download retries with
+	// --backoff-strategy constant.
+	constantBackoffDelay = 100 * time.Millisecond
+	// rampStartWorkers is how many downloads start at once with --ramp-up.
+	rampStartWorkers = 2
+)
+
+// Values for --upgrade-strategy.
+const (
+	upgradeOnlyIfNeeded = "only-if-needed"
+	upgradeEager        = "eager"
+)
+
+// retryOptions holds the download retry and pacing flags.
+type retryOptions struct {
+	strategy string        // --backoff-strategy: exponential or constant
+	attempts int           // --retries: total attempts per file
+	delay    time.Duration // --retry-backoff: exponential base or flat delay; 0 keeps the default
+	stall    time.Duration // --timeout: abandon an attempt after this long without data; 0 disables
+	rampUp   time.Duration // --ramp-up: grow to the full worker count over this long; 0 disables
+	perHost  int           // --max-per-host: concurrent downloads from one host; 0 keeps the default
+}
+
 func main() {
 	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err, os.Stderr))
 	}
 }
 
@@ -50,317 +84,1522 @@ func run() error {
 		RunE:  runInstall,
 	}
 
-	installCmd.Flags().StringP("requirements", "r", "", "Install from requirements file")
-	installCmd.Flags().IntP("jobs", "j", 0, "Max concurrent downloads (default: GOMAXPROCS)")
-	installCmd.Flags().String("python", "python3", "Python binary to use")
-	installCmd.Flags().String("target", "", "Target directory (default: auto-detect site-packages)")
-	installCmd.Flags().BoolP("verbose", "v", false, "Verbose output")
-	installCmd.Flags().Bool("dry-run", false, "Show the plan without downloading or installing")
-	installCmd.Flags().Bool("no-deps", false, "Skip dependencies, install only specified packages")
+	installCmd.Flags().StringP("requirements", "r", "", "Install from requirements file")
+	installCmd.Flags().StringP("constraint", "c", "", "Constrain versions using a constraints file without installing its entries")
+	installCmd.Flags().IntP("jobs", "j", 0, "Max concurrent network requests across resolution and downloads; downloads from one host are further capped by --max-per-host (default: GOMAXPROCS)")
+	installCmd.Flags().Int("max-per-host", 0, "Max concurrent downloads from any one host, to stay under its rate limits (default: 6)")
+	installCmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	installCmd.Flags().String("target", "", "Target directory (default: auto-detect site-packages)")
+	installCmd.Flags().String("flat-target", "", "Extract only importable modules from every wheel into this directory, e.g., to zip into a .pyz")
+	installCmd.Flags().CountP("verbose", "v", "Verbose output (-vv also logs per-file install details)")
+	installCmd.Flags().Bool("dry-run", false, "Show the plan without downloading or installing")
+	installCmd.Flags().String("locked", "", "Install exactly the wheels pinned in this lockfile (see 'pipg lock'), skipping resolution; requirements given too are only checked for drift")
+	installCmd.Flags().Bool("only-resolve", false, "Print the resolved pins and exit without selecting or downloading wheels")
+	installCmd.Flags().String("format", formatPlain, "Output format for --only-resolve: plain, annotated, or json")
+	installCmd.Flags().Int("max-versions", 0, "Try only the N newest releases of each package that match its specifiers (default: all)")
+	installCmd.Flags().Bool("pre", false, "Include pre-release and development versions")
+	installCmd.Flags().Bool("only-deps", false, "Install the dependencies of the requested packages but not the packages themselves")
+	installCmd.Flags().BoolP("upgrade", "U", false, "Upgrade installed packages to the newest matching version")
+	installCmd.Flags().String("upgrade-strategy", upgradeOnlyIfNeeded, "With --upgrade, which dependencies to upgrade: only-if-needed keeps installed versions that still satisfy, eager upgrades all")
+	installCmd.Flags().Bool("validate", false, "Check that the resolved versions satisfy every package's dependencies before downloading")
+	installCmd.Flags().Bool("force-reinstall", false, "Reinstall resolved packages even when already installed, re-extracting every file and regenerating scripts (repairs a damaged install)")
+	installCmd.Flags().Bool("strict-conflicts", false, "Fail instead of warning when a wheel would overwrite a file, with different content, that another installed package owns")
+	installCmd.Flags().Bool("atomic", false, "Roll back every package installed by this run if any of them fails")
+	installCmd.Flags().Bool("no-deps", false, "Skip dependencies, install only specified packages")
+	installCmd.Flags().Bool("abi3-only", false, "Select only stable-ABI (abi3) and pure-Python wheels, which survive minor Python upgrades")
+	installCmd.Flags().Bool("compatible", false, "Keep unpinned requested packages within their installed major version")
+	installCmd.Flags().Bool("with-recommended", false, "Activate the recommended, full or all extra of every package that declares one")
+	installCmd.Flags().StringArray("auto-extra", nil, "Extra to activate on every package that declares it, replacing the --with-recommended names (repeatable)")
+	installCmd.Flags().Bool("trusted-index-only", false, "Refuse to install unless every wheel has an index-provided sha256")
+	installCmd.Flags().Bool("no-verify-hashes", false, "UNSAFE: only warn when a download does not match its index sha256 instead of failing; for debugging a broken index")
+	installCmd.Flags().Bool("require-hashes", false, "Refuse to install any package without a --hash pin in the requirements file; implied by any --hash")
+	installCmd.Flags().String("index-url", "", "Base URL of the package index (default: $PIP_INDEX_URL or https://pypi.org/pypi)")
+	installCmd.Flags().StringArray("extra-index-url", nil, "Fallback package index queried when a package is not on --index-url (repeatable)")
+	installCmd.Flags().StringArray("find-links", nil, "Directory of wheels merged with the index, taking the place of its files for the versions it holds, e.g., one filled by 'pipg download' (repeatable)")
+	installCmd.Flags().Bool("no-index", false, "Don't contact any package index; install only from --find-links directories")
+	installCmd.Flags().String("index-auth", "", "Bearer token sent to the package index hosts (default: $PIPG_INDEX_TOKEN)")
+	installCmd.Flags().String("index-type", indexTypeJSON, "API of the package indexes: json, or simple for PEP 503 HTML indexes without the JSON API")
+	addTransportFlags(installCmd)
+	installCmd.Flags().String("record", "", "Save every index request and response to this JSON file")
+	installCmd.Flags().String("replay", "", "Answer index requests from a file saved with --record instead of the network")
+	installCmd.Flags().String("backoff-strategy", backoffExponential, "Delay between download retries: exponential, or constant for fast local mirrors")
+	installCmd.Flags().Int("retries", 3, "Download attempts per file; 0 or 1 disables retrying")
+	installCmd.Flags().Int("extract-retries", 0, "Retry writing a file this many more times when another process, e.g., antivirus software, briefly holds it")
+	installCmd.Flags().Duration("timeout", httpTimeout, "Abort a download attempt after this long without receiving data; unlike the fixed 30s limit on index requests it restarts as data arrives, so large wheels on slow links are not cut off (0 disables)")
+	installCmd.Flags().Duration("retry-backoff", 0, "Base delay for exponential backoff, or the flat delay with --backoff-strategy constant (default 500ms or 100ms)")
+	installCmd.Flags().Duration("ramp-up", 0, "Start with 2 concurrent downloads and add workers evenly over this long up to --jobs, or --max-per-host per host if lower, to avoid opening every connection at once (0 disables)")
+	installCmd.Flags().Bool("compile", true, "Byte-compile installed .py files into __pycache__")
+	installCmd.Flags().Bool("no-compile", false, "Don't byte-compile installed .py files")
+	installCmd.Flags().String("script-launcher", launcherPlain, "Form of generated console scripts: plain, or pkg-resources to pin the distribution with __requires__ (needs setuptools at run time)")
+	installCmd.Flags().Bool("no-warn-script-location", false, "Don't warn when scripts are installed to a directory not on PATH")
+	installCmd.Flags().Bool("summary-only", false, "Print only the final summary line, or a single error line on failure")
+	installCmd.Flags().String("events", "", "Write newline-delimited JSON progress events to this file, or to an open file descriptor given as a number, e.g., 3")
+	installCmd.Flags().Bool("no-cache", false, "Don't read or write the wheel cache")
+	installCmd.Flags().String("wheel-dir", "", "Use wheels already in this directory instead of downloading them")
+	installCmd.Flags().String("cache-dir", "", "Wheel cache directory (default: $PIPG_CACHE_DIR or the platform cache dir)")
+	installCmd.Flags().String("cache-max-size", "", "Evict the least recently used wheels once the cache, across all indexes, exceeds this size, e.g., 5GB (default: unbounded)")
+	installCmd.Flags().String("allow-only", "", "Fail if resolution needs any package not listed in this manifest")
+
+	rootCmd.AddCommand(installCmd, newUninstallCmd(), newListCmd(), newFreezeCmd(), newCleanCmd(), newCacheCmd(), newLockCmd(), newDownloadCmd(), newDebugCmd())
+
+	return rootCmd.Execute()
+}
+
+// installFlags holds parsed CLI flags for the install command.
+type installFlags struct {
+	reqFile   string
+	conFile   string
+	allowOnly string
+	jobs      int
+	pythonBin string
+	targetDir string
+	verbose   int
+	dryRun    bool
+	format    string
+	noDeps    bool
+	pre       bool
+	compat    bool
+	upgrade   bool
+	events    string
+	validate  bool
+	noWarnBin bool
+	launcher  string
+	retry     retryOptions
+	indexURL  string
+	indexType string
+	indexAuth string
+	transport transportOptions
+
+	trustedIndexOnly bool
+	noVerifyHashes   bool
+	requireHashes    bool
+	withRecommended  bool
+	autoExtras       []string
+	onlyResolve      bool
+	lockFile         string
+	extraIndexURLs   []string
+	findLinks        []string
+	noIndex          bool
+	maxVersions      int
+	onlyDeps         bool
+	noCache          bool
+	cacheDir         string
+	cacheMaxSize     string
+	upgradeStrategy  string
+	wheelDir         string
+	flatTarget       string
+	abi3Only         bool
+	recordFile       string
+	replayFile       string
+	compile          bool
+	summaryOnly      bool
+	atomic           bool
+	strictConflicts  bool
+	forceReinstall   bool
+	extractRetries   int
+}
+
+func parseInstallFlags(cmd *cobra.Command) installFlags {
+	var f installFlags
+
+	f.reqFile, _ = cmd.Flags().GetString("requirements")
+	f.conFile, _ = cmd.Flags().GetString("constraint")
+	f.allowOnly, _ = cmd.Flags().GetString("allow-only")
+	f.jobs, _ = cmd.Flags().GetInt("jobs")
+	f.pythonBin, _ = cmd.Flags().GetString("python")
+	f.targetDir, _ = cmd.Flags().GetString("target")
+	f.flatTarget, _ = cmd.Flags().GetString("flat-target")
+	f.verbose, _ = cmd.Flags().GetCount("verbose")
+	f.dryRun, _ = cmd.Flags().GetBool("dry-run")
+	f.onlyResolve, _ = cmd.Flags().GetBool("only-resolve")
+	f.lockFile, _ = cmd.Flags().GetString("locked")
+	f.format, _ = cmd.Flags().GetString("format")
+	f.noDeps, _ = cmd.Flags().GetBool("no-deps")
+	f.onlyDeps, _ = cmd.Flags().GetBool("only-deps")
+	f.noCache, _ = cmd.Flags().GetBool("no-cache")
+	f.cacheDir, _ = cmd.Flags().GetString("cache-dir")
+	f.cacheMaxSize, _ = cmd.Flags().GetString("cache-max-size")
+	f.upgradeStrategy, _ = cmd.Flags().GetString("upgrade-strategy")
+	f.wheelDir, _ = cmd.Flags().GetString("wheel-dir")
+	f.pre, _ = cmd.Flags().GetBool("pre")
+	f.maxVersions, _ = cmd.Flags().GetInt("max-versions")
+	f.compat, _ = cmd.Flags().GetBool("compatible")
+	f.abi3Only, _ = cmd.Flags().GetBool("abi3-only")
+	f.upgrade, _ = cmd.Flags().GetBool("upgrade")
+	f.events, _ = cmd.Flags().GetString("events")
+	f.validate, _ = cmd.Flags().GetBool("validate")
+	f.noWarnBin, _ = cmd.Flags().GetBool("no-warn-script-location")
+	f.compile, _ = cmd.Flags().GetBool("compile")
+	if noCompile, _ := cmd.Flags().GetBool("no-compile"); noCompile {
+		f.compile = false
+	}
+	f.retry.strategy, _ = cmd.Flags().GetString("backoff-strategy")
+	f.retry.attempts, _ = cmd.Flags().GetInt("retries")
+	f.extractRetries, _ = cmd.Flags().GetInt("extract-retries")
+	f.retry.delay, _ = cmd.Flags().GetDuration("retry-backoff")
+	f.retry.stall, _ = cmd.Flags().GetDuration("timeout")
+	f.retry.rampUp, _ = cmd.Flags().GetDuration("ramp-up")
+	f.retry.perHost, _ = cmd.Flags().GetInt("max-per-host")
+	f.indexURL, _ = cmd.Flags().GetString("index-url")
+	f.extraIndexURLs, _ = cmd.Flags().GetStringArray("extra-index-url")
+	f.findLinks, _ = cmd.Flags().GetStringArray("find-links")
+	f.noIndex, _ = cmd.Flags().GetBool("no-index")
+	f.indexType, _ = cmd.Flags().GetString("index-type")
+	f.indexAuth, _ = cmd.Flags().GetString("index-auth")
+	f.trustedIndexOnly, _ = cmd.Flags().GetBool("trusted-index-only")
+	f.noVerifyHashes, _ = cmd.Flags().GetBool("no-verify-hashes")
+	f.requireHashes, _ = cmd.Flags().GetBool("require-hashes")
+	f.withRecommended, _ = cmd.Flags().GetBool("with-recommended")
+	f.autoExtras, _ = cmd.Flags().GetStringArray("auto-extra")
+	f.transport = transportFlags(cmd)
+	f.recordFile, _ = cmd.Flags().GetString("record")
+	f.replayFile, _ = cmd.Flags().GetString("replay")
+	f.summaryOnly, _ = cmd.Flags().GetBool("summary-only")
+	f.atomic, _ = cmd.Flags().GetBool("atomic")
+	f.strictConflicts, _ = cmd.Flags().GetBool("strict-conflicts")
+	f.launcher, _ = cmd.Flags().GetString("script-launcher")
+	f.forceReinstall, _ = cmd.Flags().GetBool("force-reinstall")
+
+	return f
+}
+
+func runInstall(cmd *cobra.Command, args []string) error {
+	start := time.Now()
+	flags := parseInstallFlags(cmd)
+
+	reqs, err := collectRequirements(args, flags.reqFile)
+	if err != nil {
+		return err
+	}
+
+	requirements := reqs.requirements
+
+	if len(requirements) == 0 && flags.lockFile == "" {
+		return fmt.Errorf("no packages specified; use 'pipg install <pkg>' or 'pipg install -r requirements.txt'")
+	}
+
+	if err := checkHashPins(requirements, reqs.hashes, flags.requireHashes); err != nil {
+		return err
+	}
+
+	var lock *lockfile
+	if flags.lockFile != "" {
+		if flags.onlyResolve || flags.onlyDeps || flags.noDeps {
+			return fmt.Errorf("--locked cannot be used with --only-resolve, --only-deps or --no-deps")
+		}
+
+		if lock, err = readLockfile(flags.lockFile); err != nil {
+			return err
+		}
+
+		warnLockDrift(os.Stderr, lock, flags.lockFile, requirements)
+	}
+
+	if !slices.Contains(resolveFormats, flags.format) {
+		return fmt.Errorf("unknown --format %q; expected one of: %s", flags.format, strings.Join(resolveFormats, ", "))
+	}
+
+	if flags.upgradeStrategy != upgradeOnlyIfNeeded && flags.upgradeStrategy != upgradeEager {
+		return fmt.Errorf("unknown --upgrade-strategy %q; expected %s or %s", flags.upgradeStrategy, upgradeOnlyIfNeeded, upgradeEager)
+	}
+
+	if flags.flatTarget != "" && flags.targetDir != "" {
+		return fmt.Errorf("--flat-target and --target cannot be used together")
+	}
+
+	if flags.onlyDeps && flags.noDeps {
+		return fmt.Errorf("--only-deps and --no-deps cannot be used together")
+	}
+
+	if flags.retry.strategy != backoffExponential && flags.retry.strategy != backoffConstant {
+		return fmt.Errorf("unknown --backoff-strategy %q; expected %s or %s", flags.retry.strategy, backoffExponential, backoffConstant)
+	}
+
+	if flags.retry.attempts < 0 || flags.retry.delay < 0 || flags.retry.stall < 0 || flags.retry.rampUp < 0 {
+		return fmt.Errorf("--retries, --retry-backoff, --timeout and --ramp-up must not be negative")
+	}
+
+	if flags.trustedIndexOnly && flags.noVerifyHashes {
+		return fmt.Errorf("--trusted-index-only and --no-verify-hashes cannot be used together")
+	}
+
+	if flags.requireHashes && flags.noVerifyHashes {
+		return fmt.Errorf("--require-hashes and --no-verify-hashes cannot be used together")
+	}
+
+	if flags.noIndex && len(flags.findLinks) == 0 && (lock == nil || flags.wheelDir == "") {
+		return fmt.Errorf("--no-index needs --find-links, or --locked with --wheel-dir, to find packages")
+	}
+
+	cacheMaxSize, err := parseSize(flags.cacheMaxSize)
+	if err != nil {
+		return fmt.Errorf("--cache-max-size: %w", err)
+	}
+
+	verify := downloader.VerifyEnforce
+	if flags.noVerifyHashes {
+		verify = downloader.VerifyWarn
+
+		_, _ = fmt.Fprintln(os.Stderr, "WARNING: --no-verify-hashes is set; downloads that fail sha256 verification will be installed anyway. Do not use this outside debugging.")
+	}
+
+	style, err := scriptStyle(flags.launcher)
+	if err != nil {
+		return err
+	}
+
+	if flags.indexType != indexTypeJSON && flags.indexType != indexTypeSimple {
+		return fmt.Errorf("unknown --index-type %q; expected %s or %s", flags.indexType, indexTypeJSON, indexTypeSimple)
+	}
+
+	baseURL, err := indexURL(flags.indexURL, flags.indexType)
+	if err != nil {
+		return err
+	}
+
+	extraURLs, err := extraIndexURLs(flags.extraIndexURLs, flags.indexType)
+	if err != nil {
+		return err
+	}
+
+	creds, baseURL, extraURLs := indexCredentials(flags.indexAuth, baseURL, extraURLs)
+
+	allowlist, err := loadAllowlist(flags.allowOnly)
+	if err != nil {
+		return err
+	}
+
+	constraints, err := loadConstraints(flags.conFile, reqs.constraints)
+	if err != nil {
+		return err
+	}
+
+	// --summary-only keeps warnings out of the output too; -v still shows
+	// them for debugging.
+	quiet := slog.LevelWarn
+	if flags.summaryOnly {
+		quiet = slog.LevelError
+	}
+
+	logger := newLeveledLogger(flags.verbose, quiet)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	targetDir := flags.targetDir
+	if flags.flatTarget != "" {
+		targetDir = flags.flatTarget
+	}
+
+	env, err := detectEnv(ctx, flags.pythonBin, targetDir, logger)
+	if err != nil {
+		return err
+	}
+
+	if flags.compat {
+		requirements = compatibleRequirements(requirements, env.LibDirs(), logger)
+	}
+
+	httpClient, err := newHTTPClient(flags.transport)
+	if err != nil {
+		return err
+	}
+
+	indexHTTPClient, recorder, err := indexClient(httpClient, flags.recordFile, flags.replayFile)
+	if err != nil {
+		return err
+	}
+
+	if recorder != nil {
+		defer func() {
+			if err := recorder.save(); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+			}
+		}()
+	}
+
+	// One limit shared by metadata fetches and downloads.
+	sem := semaphore.NewWeighted(int64(workerCount(flags.jobs)))
+
+	pypiClient := withFindLinks(newIndexClient(flags.indexType,
+		pypi.WithHTTPClient(indexHTTPClient),
+		pypi.WithBaseURL(baseURL),
+		pypi.WithExtraBaseURLs(extraURLs),
+		pypi.WithCredentials(creds),
+		pypi.WithSemaphore(sem),
+		pypi.WithLogger(logger),
+	), flags.findLinks, flags.noIndex, logger)
+
+	compatTags := buildCompatTags(env)
+	if flags.abi3Only {
+		compatTags = abi3OnlyTags(compatTags)
+	}
+
+	progress := &installProgress{phase: "resolution"}
+
+	var events *eventWriter
+	if flags.events != "" {
+		out, err := openEvents(flags.events)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = out.Close() }()
+
+		events = newEventWriter(out)
+	}
+
+	report := newReporter(os.Stdout, flags.summaryOnly)
+
+	var resolved []resolver.ResolvedPackage
+
+	if lock != nil {
+		resolved = lock.resolved()
+	} else {
+		if !flags.onlyResolve {
+			report.printf("Resolving dependencies...\n")
+		}
+
+		events.resolveStart(requirements)
+
+		resolved, err = resolveDeps(ctx, requirements, pypiClient, env, compatTags, logger,
+			resolver.WithNoDeps(flags.noDeps),
+			resolver.WithAllowlist(allowlist),
+			resolver.WithAllowPrerelease(flags.pre),
+			resolver.WithMaxVersions(flags.maxVersions),
+			resolver.WithConstraints(constraints),
+			resolver.WithValidation(flags.validate),
+			resolver.WithAutoExtras(autoExtras(flags.withRecommended, flags.autoExtras)),
+			resolver.WithPreferred(preferredVersions(env, requirements, flags.upgrade, flags.upgradeStrategy, logger)),
+		)
+		if err != nil {
+			return checkInterrupted(ctx, err, progress)
+		}
+
+		events.resolved(resolved)
+
+		if flags.onlyResolve {
+			return writeResolution(os.Stdout, flags.format, resolved)
+		}
+
+		printResolution(report.out, requirements, resolved)
+	}
+
+	// A locked install only skips packages installed at their locked version.
+	if !flags.forceReinstall {
+		var satisfied []string
+
+		resolved, satisfied = skipSatisfied(requirements, resolved, env.LibDirs(), flags.upgrade || lock != nil)
+		for _, s := range satisfied {
+			report.printf("Requirement already satisfied: %s\n", s)
+		}
+	}
+
+	if len(resolved) == 0 {
+		report.nothingToInstall()
+		events.summary(0, time.Since(start))
+
+		return nil
+	}
+
+	var plans []downloadPlan
+	if lock != nil {
+		plans, err = lock.plans(resolved, compatTags, env)
+		if err == nil && flags.trustedIndexOnly {
+			err = requireDigests(plans)
+		}
+	} else {
+		plans, err = selectWheels(ctx, resolved, pypiClient, compatTags, env, flags.trustedIndexOnly)
+	}
+
+	if err != nil {
+		return checkInterrupted(ctx, err, progress)
+	}
+
+	if err := pinHashes(plans, reqs.hashes, flags.requireHashes); err != nil {
+		return err
+	}
+
+	if flags.onlyDeps {
+		plans = withoutRoots(plans)
+	}
+
+	cacheOpts := cacheOptions{
+		disabled:  flags.noCache,
+		dir:       flags.cacheDir,
+		namespace: cacheNamespace(baseURL),
+		wheelDir:  flags.wheelDir,
+		maxSize:   cacheMaxSize,
+	}
+
+	if flags.dryRun {
+		printDryRun(os.Stdout, plans, cacheOpts.localWheel)
+
+		return nil
+	}
+
+	progress.phase = "download"
+
+	results, tmpDir, err := downloadPackages(ctx, report.out, plans, flags.jobs, flags.retry, cacheOpts, downloadClient(httpClient), creds, verify, sem, logger, events)
+	if err != nil {
+		return checkInterrupted(ctx, err, progress)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	printDownloadResults(report.out, results)
+
+	progress.phase = "install"
+	progress.downloaded = len(results)
+
+	inst := installer.New(env,
+		installer.WithLogger(logger),
+		installer.WithFlat(flags.flatTarget != ""),
+		installer.WithCompile(flags.compile),
+		installer.WithAtomic(flags.atomic),
+		installer.WithStrictConflicts(flags.strictConflicts),
+		installer.WithExtractRetries(flags.extractRetries),
+		installer.WithScriptStyle(style),
+		installer.WithDirectURLs(directURLPackages(plans)),
+	)
+	if err := installPackages(ctx, report.out, inst, results, progress, events); err != nil {
+		return err
+	}
+
+	if !flags.noWarnBin && !flags.summaryOnly && flags.flatTarget == "" {
+		warnScriptLocation(os.Stderr, installedScripts(env.LibDirs(), results), installer.BinDir(env), os.Getenv("PATH"))
+	}
+
+	report.done(results, time.Since(start))
+	events.summary(len(results), time.Since(start))
+
+	return nil
+}
+
+// Values of --script-launcher.
+const (
+	launcherPlain        = "plain"
+	launcherPkgResources = "pkg-resources"
+)
+
+// scriptStyle maps a --script-launcher value to the installer's script style.
+func scriptStyle(launcher string) (installer.ScriptStyle, error) {
+	switch launcher {
+	case launcherPlain:
+		return installer.ScriptPlain, nil
+	case launcherPkgResources:
+		return installer.ScriptPkgResources, nil
+	default:
+		return 0, fmt.Errorf("unknown --script-launcher %q; expected %s or %s", launcher, launcherPlain, launcherPkgResources)
+	}
+}
+
+// installPackages installs downloaded wheels and prints the result line to w.
+func installPackages(ctx context.Context, w io.Writer, inst installer.Installer, results []downloader.Result, progress *installProgress, events *eventWriter) error {
+	_, _ = fmt.Fprintln(w, "\nInstalling...")
+
+	if err := inst.Install(ctx, results); err != nil {
+		return checkInterrupted(ctx, fmt.Errorf("installing packages: %w", err), progress)
+	}
+
+	progress.installed = len(results)
+	_, _ = fmt.Fprintf(w, "  ✓ %d packages installed\n", len(results))
+	events.installDone(len(results))
+
+	return nil
+}
+
+// newLogger returns the stderr logger for the -v count: warnings by default,
+// debug with -v and trace with -vv.
+func newLogger(verbosity int) *slog.Logger {
+	return newLeveledLogger(verbosity, slog.LevelWarn)
+}
+
+// newLeveledLogger is newLogger with quiet as the level without -v.
+func newLeveledLogger(verbosity int, quiet slog.Level) *slog.Logger {
+	logLevel := quiet
+
+	switch {
+	case verbosity >= 2:
+		logLevel = installer.LevelTrace
+	case verbosity == 1:
+		logLevel = slog.LevelDebug
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+}
+
+func detectEnv(ctx context.Context, pythonBin, targetDir string, logger *slog.Logger) (*python.Environment, error) {
+	pyDetector := python.New(python.WithPythonBin(pythonBin))
+
+	env, err := pyDetector.Detect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("detecting Python environment: %w", err)
+	}
+
+	if targetDir != "" {
+		absTarget, err := filepath.Abs(targetDir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving target directory: %w", err)
+		}
+
+		env.SitePackages = absTarget
+		env.PlatLib = "" // the target holds platform-specific packages too
+	}
+
+	logger.Debug("detected Python environment",
+		slog.String("prefix", env.Prefix),
+		slog.String("site-packages", env.SitePackages),
+		slog.String("scripts", installer.BinDir(env)),
+		slog.String("platform", env.PlatformTag),
+		slog.String("version", env.PythonVersion),
+		slog.Bool("venv", env.IsVirtualEnv),
+		slog.Bool("free-threaded", env.FreeThreaded),
+	)
+
+	return env, nil
+}
+
+// defaultAutoExtras are the extras --with-recommended activates: the names
+// packages commonly give their batteries-included dependency sets.
+var defaultAutoExtras = []string{"recommended", "full", "all"}
+
+// autoExtras returns the extras to activate on every package declaring them:
+// the --auto-extra names when given, the defaults with --with-recommended,
+// or none.
+func autoExtras(withRecommended bool, names []string) []string {
+	switch {
+	case len(names) > 0:
+		return names
+	case withRecommended:
+		return defaultAutoExtras
+	default:
+		return nil
+	}
+}
+
+// resolveDeps resolves requirements for env; opts carry the flag-dependent
+// resolver settings.
+func resolveDeps(ctx context.Context, requirements []string, pypiClient pypi.Client, env *python.Environment, compatTags []downloader.WheelTag, logger *slog.Logger, opts ...resolver.Option) ([]resolver.ResolvedPackage, error) {
+	markerEnv := buildMarkerEnv(env)
+
+	var wheelMetadata resolver.WheelMetadata
+	if r, ok := pypiClient.(wheelMetadataReader); ok {
+		wheelMetadata = r.GetWheelMetadata
+	}
+
+	resolverSvc := resolver.New(pypiClient, append([]resolver.Option{
+		resolver.WithMarkerEnv(markerEnv),
+		resolver.WithWheelCheck(hasCompatibleWheel(compatTags, fullPythonVersion(env))),
+		resolver.WithWheelMetadata(wheelMetadata),
+		resolver.WithCollectConflicts(true),
+		resolver.WithCollectSkipped(true),
+		resolver.WithMaintenanceWarnings(true),
+		resolver.WithLogger(logger),
+	}, opts...)...)
+
+	resolved, err := resolverSvc.Resolve(ctx, requirements)
+	if err != nil {
+		return nil, fmt.Errorf("resolving dependencies: %w", err)
+	}
+
+	for _, pkg := range resolved {
+		for _, skip := range pkg.Skipped {
+			logger.Debug("skipped dependency",
+				slog.String("package", pkg.Name),
+				slog.String("dependency", skip.Name),
+				slog.String("marker", skip.Marker),
+				slog.String("reason", skip.Reason),
+			)
+		}
+	}
+
+	return resolved, nil
+}
+
+// wheelMetadataReader is implemented by the index clients that can read the
+// metadata of a wheel by URL, which direct URL requirements need.
+type wheelMetadataReader interface {
+	GetWheelMetadata(ctx context.Context, file pypi.URL) (*pypi.Info, error)
+}
+
+// printResolution prints the dependency tree rooted at the requested packages to w.
+func printResolution(w io.Writer, requirements []string, resolved []resolver.ResolvedPackage) {
+	resolvedMap := make(map[string]resolver.ResolvedPackage, len(resolved))
+	for _, pkg := range resolved {
+		resolvedMap[pkg.Name] = pkg
+	}
+
+	rootNames := make([]string, 0, len(requirements))
+	for _, r := range requirements {
+		rootNames = append(rootNames, resolver.NormalizeName(resolver.ParseRequirement(r).Name))
+	}
+
+	printDependencyTree(w, rootNames, resolvedMap)
+}
+
+func printDryRun(w io.Writer, plans []downloadPlan, localWheel func(filename string) string) {
+	_, _ = fmt.Fprintf(w, "\nWould download %d packages:\n", len(plans))
+
+	var downloadSize int64
+
+	for _, p := range plans {
+		_, _ = fmt.Fprintf(w, "  %s (%s)\n", p.wheelURL.Filename, formatSize(p.wheelURL.Size))
+		downloadSize += p.wheelURL.Size
+	}
+
+	installSize, estimated := estimateInstallSize(plans, localWheel)
+
+	_, _ = fmt.Fprintf(w, "\nDownload size: %s\n", formatSize(downloadSize))
+
+	if estimated > 0 {
+		_, _ = fmt.Fprintf(w, "Install size:  ~%s (estimated for %d of %d wheels)\n", formatSize(installSize), estimated, len(plans))
+	} else {
+		_, _ = fmt.Fprintf(w, "Install size:  %s\n", formatSize(installSize))
+	}
+
+	if warnings := planWarnings(plans); len(warnings) > 0 {
+		_, _ = fmt.Fprintf(w, "\nWarnings:\n")
+
+		for _, warning := range warnings {
+			_, _ = fmt.Fprintf(w, "  %s\n", warning)
+		}
+	}
+
+	_, _ = fmt.Fprintln(w, "\nDry run, no changes made.")
+}
+
+// installSizeFactor approximates how much larger a wheel is once extracted,
+// for wheels that are not available locally to measure.
+const installSizeFactor = 3
+
+// estimateInstallSize sums the uncompressed size of the planned wheels.
+// Wheels found by localWheel are measured from their zip central directory;
+// the others are estimated from their download size, and their count is
+// returned as estimated.
+func estimateInstallSize(plans []downloadPlan, localWheel func(filename string) string) (size int64, estimated int) {
+	for _, p := range plans {
+		if localWheel != nil {
+			if path := localWheel(p.wheelURL.Filename); path != "" {
+				if n, err := installer.UncompressedSize(path); err == nil {
+					size += n
+
+					continue
+				}
+			}
+		}
+
+		size += p.wheelURL.Size * installSizeFactor
+		estimated++
+	}
+
+	return size, estimated
+}
+
+// planWarnings reports selected wheels that tag matching alone does not catch:
+// yanked files. Files whose Requires-Python excludes the target interpreter
+// are never selected.
+func planWarnings(plans []downloadPlan) []string {
+	var warnings []string
+
+	for _, p := range plans {
+		wheel := p.wheelURL
+
+		if wheel.Yanked {
+			warning := "would select yanked wheel " + wheel.Filename
+			if wheel.YankedReason != "" {
+				warning += ": " + wheel.YankedReason
+			}
+
+			warnings = append(warnings, warning)
+		}
+	}
+
+	return warnings
+}
+
+func printDownloadResults(w io.Writer, results []downloader.Result) {
+	for _, r := range results {
+		suffix := ""
+		if r.Cached {
+			suffix = " (cached)"
+		}
+
+		_, _ = fmt.Fprintf(w, "  ✓ %s (%s)%s\n", filepath.Base(r.FilePath), formatSize(r.Size), suffix)
+	}
+}
+
+// hasCompatibleWheel returns a resolver.WheelCheck backed by SelectWheel, so that
+// versions without an installable wheel are skipped during resolution.
+func hasCompatibleWheel(compatTags []downloader.WheelTag, pythonVersion string) resolver.WheelCheck {
+	return func(files []pypi.URL) bool {
+		_, err := downloader.SelectWheel(files, compatTags, pythonVersion)
+
+		return err == nil
+	}
+}
+
+type downloadPlan struct {
+	pkg      resolver.ResolvedPackage
+	wheelURL pypi.URL
+}
+
+// directURLPackages returns the names of the planned packages that come from
+// direct URL requirements.
+func directURLPackages(plans []downloadPlan) []string {
+	var names []string
+
+	for _, p := range plans {
+		if p.pkg.URL != "" {
+			names = append(names, p.pkg.Name)
+		}
+	}
+
+	return names
+}
+
+// skipSatisfied drops resolved packages that need no install. A package
+// already installed at the resolved version is always dropped; without
+// upgrade, so is one whose installed version meets every specifier placed on
+// it. The dropped packages are returned as name==version strings.
+func skipSatisfied(requirements []string, resolved []resolver.ResolvedPackage, libDirs []string, upgrade bool) ([]resolver.ResolvedPackage, []string) {
+	rootSpecs := make(map[string][]string, len(requirements))
+	for _, r := range requirements {
+		req := resolver.ParseRequirement(r)
+		if req.Specifier != "" {
+			rootSpecs[req.Name] = append(rootSpecs[req.Name], req.Specifier)
+		}
+	}
+
+	var install []resolver.ResolvedPackage
+	var satisfied []string
+
+	for _, pkg := range resolved {
+		installed := installer.InstalledVersion(libDirs, pkg.Name)
+		if installed == "" || !keepInstalled(installed, pkg, rootSpecs[pkg.Name], upgrade) {
+			install = append(install, pkg)
+
+			continue
+		}
+
+		satisfied = append(satisfied, pkg.Name+"=="+installed)
+	}
+
+	return install, satisfied
+}
+
+// preferredVersions returns the installed versions the resolver should keep
+// when they still satisfy: all of them without --upgrade, those of packages
+// not requested directly with --upgrade-strategy only-if-needed, and none
+// with eager.
+func preferredVersions(env *python.Environment, requirements []string, upgrade bool, strategy string, logger *slog.Logger) map[string]string {
+	if upgrade && strategy == upgradeEager {
+		return nil
+	}
+
+	pkgs, err := installer.New(env, installer.WithLogger(logger)).Installed()
+	if err != nil {
+		logger.Debug("cannot list installed packages", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	versions := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		versions[resolver.NormalizeName(pkg.Name)] = pkg.Version
+	}
+
+	if upgrade {
+		for _, r := range requirements {
+			delete(versions, resolver.ParseRequirement(r).Name)
+		}
+	}
+
+	return versions
+}
+
+// keepInstalled reports whether the installed version of pkg can stay.
+func keepInstalled(installed string, pkg resolver.ResolvedPackage, specs []string, upgrade bool) bool {
+	if resolver.VersionsEqual(installed, pkg.Version) {
+		return true
+	}
+
+	if upgrade {
+		return false
+	}
+
+	for _, d := range pkg.RequiredBy {
+		if d.Specifier != "" {
+			specs = append(specs, d.Specifier)
+		}
+	}
+
+	ok, err := resolver.MatchesAll(installed, specs)
+
+	return err == nil && ok
+}
+
+// withoutRoots drops the directly requested packages from plans, leaving
+// only their dependencies.
+func withoutRoots(plans []downloadPlan) []downloadPlan {
+	return slices.DeleteFunc(plans, func(p downloadPlan) bool { return p.pkg.Root })
+}
+
+// selectWheels finds a compatible wheel for each resolved package. Packages
+// without one are collected and reported together, so they can all be fixed
+// in one go. When trustedOnly is set, the whole selection is refused if any
+// wheel lacks an index-provided sha256 digest.
+func selectWheels(ctx context.Context, resolved []resolver.ResolvedPackage, client pypi.Client, compatTags []downloader.WheelTag, env *python.Environment, trustedOnly bool) ([]downloadPlan, error) {
+	var plans []downloadPlan
+	var missing []error
+
+	for _, pkg := range resolved {
+		files, err := packageFiles(ctx, client, pkg)
+		if err != nil {
+			return nil, err
+		}
+
+		wheel, err := downloader.SelectWheel(files, compatTags, fullPythonVersion(env))
+		if err != nil {
+			missing = append(missing, fmt.Errorf("no compatible wheel for %s %s (platform: %s, python: cp%s): %w",
+				pkg.Name, pkg.Version, wheelPlatform(env.PlatformTag), env.PythonVersion, err))
+
+			continue
+		}
+
+		plans = append(plans, downloadPlan{pkg: pkg, wheelURL: wheel})
+	}
+
+	switch len(missing) {
+	case 0:
+	case 1:
+		return nil, missing[0]
+	default:
+		return nil, fmt.Errorf("%d packages have no compatible wheel:\n%w", len(missing), errors.Join(missing...))
+	}
+
+	if trustedOnly {
+		if err := requireDigests(plans); err != nil {
+			return nil, err
+		}
+	}
+
+	return plans, nil
+}
+
+// requireDigests implements --trusted-index-only: it fails, naming them all,
+// when any planned wheel has no sha256 digest to verify the download with.
+func requireDigests(plans []downloadPlan) error {
+	unverified := unverifiedWheels(plans)
+	if len(unverified) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("--trusted-index-only: the index provides no sha256 digest for:\n  %s",
+		strings.Join(unverified, "\n  "))
+}
+
+// unverifiedWheels lists, sorted, the planned wheels without a sha256 digest.
+func unverifiedWheels(plans []downloadPlan) []string {
+	var unverified []string
+
+	for _, p := range plans {
+		if p.wheelURL.Digests.SHA256 == "" {
+			unverified = append(unverified, fmt.Sprintf("%s %s (%s)", p.pkg.Name, p.pkg.Version, p.wheelURL.Filename))
+		}
+	}
+
+	sort.Strings(unverified)
+
+	return unverified
+}
+
+// packageFiles returns the files a resolved package can be installed from:
+// the release files on the index, or the wheel at its direct URL.
+func packageFiles(ctx context.Context, client pypi.Client, pkg resolver.ResolvedPackage) ([]pypi.URL, error) {
+	if pkg.URL != "" {
+		file, _, err := pypi.WheelFromURL(pkg.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		return []pypi.URL{file}, nil
+	}
+
+	pkgInfo, err := client.GetPackageVersion(ctx, pkg.Name, pkg.Version)
+	if err != nil {
+		return nil, fmt.Errorf("fetching URLs for %s %s: %w", pkg.Name, pkg.Version, err)
+	}
+
+	return pkgInfo.URLs, nil
+}
+
+// hashMode names what turned on hash-checking mode, for error messages.
+// As with pip, --require-hashes or any --hash in the requirements file
+// turns it on.
+func hashMode(requireHashes bool) string {
+	if requireHashes {
+		return "--require-hashes"
+	}
+
+	return "hash-checking mode (a --hash is given)"
+}
+
+// checkHashPins refuses requirements that do not pin one version with == or
+// === in hash-checking mode, since their hashes could only match one release.
+// Direct URL requirements name one file and need no pin.
+func checkHashPins(requirements []string, hashes map[string][]string, requireHashes bool) error {
+	if !requireHashes && len(hashes) == 0 {
+		return nil
+	}
+
+	var unpinned []string
+
+	for _, r := range requirements {
+		req := resolver.ParseRequirement(r)
+		if req.URL == "" && !exactPin(req.Specifier) {
+			unpinned = append(unpinned, r)
+		}
+	}
+
+	if len(unpinned) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s: requirements must pin a version with ==:\n  %s", hashMode(requireHashes), strings.Join(unpinned, "\n  "))
+}
+
+// exactPin reports whether spec allows exactly one version: a single == or
+// === clause without a wildcard.
+func exactPin(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	if !strings.HasPrefix(spec, "==") || strings.Contains(spec, ",") {
+		return false
+	}
+
+	return !strings.HasSuffix(spec, ".*")
+}
+
+// pinHashes checks the wheels of plans against the --hash pins of the
+// requirements file. A wheel whose index digest is not pinned is refused
+// before download; a wheel without an index digest and with a single pin is
+// verified against the pin instead. Pins and index digests are compared as
+// hex, so either may be given in urlsafe base64. In hash-checking mode, turned
+// on by requireHashes or by any pin, every planned package needs a pin.
+func pinHashes(plans []downloadPlan, hashes map[string][]string, requireHashes bool) error {
+	checking := requireHashes || len(hashes) > 0
+
+	var unpinned []string
+
+	for i, p := range plans {
+		pinned := hashes[p.pkg.Name]
+		if len(pinned) == 0 {
+			if checking {
+				unpinned = append(unpinned, p.pkg.Name+" "+p.pkg.Version)
+			}
+
+			continue
+		}
+
+		allowed := make([]string, len(pinned))
+		for j, h := range pinned {
+			allowed[j] = downloader.NormalizeDigest("sha256", h)
+		}
+
+		digest := downloader.NormalizeDigest("sha256", p.wheelURL.Digests.SHA256)
+
+		switch {
+		case digest != "":
+			if !slices.Contains(allowed, digest) {
+				return fmt.Errorf("hash mismatch for %s %s: %s has sha256 %s, which is not one of its --hash values",
+					p.pkg.Name, p.pkg.Version, p.wheelURL.Filename, digest)
+			}
+		case len(allowed) == 1:
+			plans[i].wheelURL.Digests.SHA256 = allowed[0]
+		default:
+			return fmt.Errorf("cannot verify %s %s: the index gives no sha256 for %s to match against its %d --hash values",
+				p.pkg.Name, p.pkg.Version, p.wheelURL.Filename, len(allowed))
+		}
+	}
+
+	if len(unpinned) > 0 {
+		sort.Strings(unpinned)
+
+		return fmt.Errorf("%s: no --hash given for:\n  %s", hashMode(requireHashes), strings.Join(unpinned, "\n  "))
+	}
+
+	return nil
+}
+
+// downloadPackages downloads all planned packages concurrently with cache support.
+// Caller is responsible for cleaning up tmpDir after installation.
+func downloadPackages(ctx context.Context, w io.Writer, plans []downloadPlan, jobs int, retry retryOptions, cacheOpts cacheOptions, httpClient *http.Client, creds *pypi.Credentials, verify downloader.VerifyMode, sem *semaphore.Weighted, logger *slog.Logger, events *eventWriter) ([]downloader.Result, string, error) {
+	tmpDir, err := os.MkdirTemp("", "pipg-downloads-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("creating temp directory: %w", err)
+	}
+
+	results, err := downloadInto(ctx, w, tmpDir, plans, jobs, retry, cacheOpts, httpClient, creds, verify, sem, logger, events)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+
+		return nil, "", err
+	}
+
+	return results, tmpDir, nil
+}
+
+// downloadInto downloads all planned packages into dir. Wheels served from
+// the cache or the wheel dir stay where they are.
+func downloadInto(ctx context.Context, w io.Writer, dir string, plans []downloadPlan, jobs int, retry retryOptions, cacheOpts cacheOptions, httpClient *http.Client, creds *pypi.Credentials, verify downloader.VerifyMode, sem *semaphore.Weighted, logger *slog.Logger, events *eventWriter) ([]downloader.Result, error) {
+	requests := buildDownloadRequests(plans)
+
+	_, _ = fmt.Fprintf(w, "\nDownloading %d packages (%d workers)...\n", len(requests), workerCount(jobs))
+
+	dlManager := newDownloader(dir, jobs, retry, cacheOpts, httpClient, creds, verify, sem, logger, events.progress())
+
+	events.downloadStart(requests)
+
+	results, err := dlManager.Download(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("downloading packages: %w", err)
+	}
+
+	events.downloadDone(results)
+
+	return results, nil
+}
+
+func buildDownloadRequests(plans []downloadPlan) []downloader.Request {
+	requests := make([]downloader.Request, len(plans))
+	for i, p := range plans {
+		requests[i] = downloader.Request{
+			Name:     p.pkg.Name,
+			Version:  p.pkg.Version,
+			URL:      p.wheelURL.URL,
+			SHA256:   p.wheelURL.Digests.SHA256,
+			Filename: p.wheelURL.Filename,
+		}
+	}
+
+	return requests
+}
+
+// workerCount returns the --jobs value, defaulting to GOMAXPROCS.
+func workerCount(jobs int) int {
+	if jobs > 0 {
+		return jobs
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+func newDownloader(tmpDir string, jobs int, retry retryOptions, cacheOpts cacheOptions, httpClient *http.Client, creds *pypi.Credentials, verify downloader.VerifyMode, sem *semaphore.Weighted, logger *slog.Logger, progress downloader.ProgressFunc) *downloader.Manager {
+	dlOpts := []downloader.Option{
+		downloader.WithHTTPClient(httpClient),
+		downloader.WithCredentials(creds),
+		downloader.WithVerifyMode(verify),
+		downloader.WithSemaphore(sem),
+		downloader.WithLogger(logger),
+		downloader.WithProgress(progress),
+		downloader.WithWheelDir(cacheOpts.wheelDir),
+	}
+
+	if !cacheOpts.disabled {
+		wheelCache, err := cache.New(
+			cache.WithDir(cacheOpts.dir),
+			cache.WithNamespace(cacheOpts.namespace),
+			cache.WithMaxSize(cacheOpts.maxSize),
+			cache.WithLogger(logger),
+		)
+		if err != nil {
+			logger.Debug("cache unavailable, continuing without cache", slog.String("error", err.Error()))
+		} else {
+			dlOpts = append(dlOpts, downloader.WithCache(wheelCache))
+		}
+	}
+
+	if jobs > 0 {
+		dlOpts = append(dlOpts, downloader.WithMaxWorkers(jobs))
+	}
+
+	dlOpts = append(dlOpts,
+		downloader.WithRetries(retry.attempts),
+		downloader.WithStallTimeout(retry.stall),
+		downloader.WithRampUp(rampStartWorkers, retry.rampUp),
+		downloader.WithMaxPerHost(retry.perHost),
+	)
+
+	switch {
+	case retry.strategy == backoffConstant && retry.delay > 0:
+		dlOpts = append(dlOpts, downloader.WithConstantBackoff(retry.delay))
+	case retry.strategy == backoffConstant:
+		dlOpts = append(dlOpts, downloader.WithConstantBackoff(constantBackoffDelay))
+	default:
+		dlOpts = append(dlOpts, downloader.WithBackoffBase(retry.delay))
+	}
+
+	return downloader.New(tmpDir, dlOpts...)
+}
+
+// Package index APIs selectable with --index-type.
+const (
+	indexTypeJSON   = "json"
+	indexTypeSimple = "simple"
+)
 
-	rootCmd.AddCommand(installCmd)
+// newIndexClient creates the package index client for indexType.
+func newIndexClient(indexType string, opts ...pypi.Option) pypi.Client {
+	if indexType == indexTypeSimple {
+		return pypi.NewSimple(opts...)
+	}
 
-	return rootCmd.Execute()
+	return pypi.New(opts...)
 }
 
-// installFlags holds parsed CLI flags for the install command.
-type installFlags struct {
-	reqFile   string
-	jobs      int
-	pythonBin string
-	targetDir string
-	verbose   bool
-	dryRun    bool
-	noDeps    bool
-}
+// withFindLinks merges the wheels in the --find-links directories with the
+// releases of the index client. With noIndex, they are the only source.
+func withFindLinks(client pypi.Client, findLinks []string, noIndex bool, logger *slog.Logger) pypi.Client {
+	if noIndex {
+		client = nil
+	}
 
-func parseInstallFlags(cmd *cobra.Command) installFlags {
-	reqFile, _ := cmd.Flags().GetString("requirements")
-	jobs, _ := cmd.Flags().GetInt("jobs")
-	pythonBin, _ := cmd.Flags().GetString("python")
-	targetDir, _ := cmd.Flags().GetString("target")
-	verbose, _ := cmd.Flags().GetBool("verbose")
-	dryRun, _ := cmd.Flags().GetBool("dry-run")
-	noDeps, _ := cmd.Flags().GetBool("no-deps")
+	if len(findLinks) == 0 {
+		return client
+	}
 
-	return installFlags{reqFile, jobs, pythonBin, targetDir, verbose, dryRun, noDeps}
+	return pypi.NewLocal(findLinks, client, pypi.WithLogger(logger))
 }
 
-func runInstall(cmd *cobra.Command, args []string) error {
-	start := time.Now()
-	flags := parseInstallFlags(cmd)
-
-	requirements, err := collectRequirements(args, flags.reqFile)
+// normalizeIndexURL expands environment variable references in an index URL
+// and converts it to the base URL of the indexType API.
+func normalizeIndexURL(raw, indexType string) (string, error) {
+	expanded, err := expandEnv(raw)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("index URL %q: %w", raw, err)
 	}
 
-	if len(requirements) == 0 {
-		return fmt.Errorf("no packages specified; use 'pipg install <pkg>' or 'pipg install -r requirements.txt'")
+	if indexType == indexTypeSimple {
+		return pypi.NormalizeSimpleIndexURL(expanded)
 	}
 
-	logger := newLogger(flags.verbose)
+	return pypi.NormalizeIndexURL(expanded)
+}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+// envVarPattern matches the ${VAR} references expandEnv substitutes. Like
+// pip, only braced, uppercase names count, so a bare $ in a password or URL
+// is kept as written.
+var envVarPattern = regexp.MustCompile(`\$\{([A-Z0-9_]+)\}`)
 
-	env, err := detectEnv(ctx, flags.pythonBin, flags.targetDir, logger)
-	if err != nil {
-		return err
-	}
+// expandEnv substitutes ${VAR} references in s from the environment, so
+// secrets such as index tokens can stay out of command lines and files. A
+// reference to an unset variable is an error rather than an empty string,
+// which would silently drop the credentials.
+func expandEnv(s string) (string, error) {
+	var unset []string
 
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-	pypiClient := pypi.New(pypi.WithHTTPClient(httpClient), pypi.WithLogger(logger))
+	expanded := envVarPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envVarPattern.FindStringSubmatch(ref)[1]
 
-	resolved, err := resolveDeps(ctx, requirements, pypiClient, flags.noDeps, env, logger)
-	if err != nil {
-		return err
-	}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			unset = append(unset, name)
+		}
 
-	compatTags := buildCompatTags(env)
+		return value
+	})
 
-	plans, err := selectWheels(ctx, resolved, pypiClient, compatTags, env)
-	if err != nil {
-		return err
+	if len(unset) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(unset, ", "))
 	}
 
-	if flags.dryRun {
-		printDryRun(plans)
+	return expanded, nil
+}
 
-		return nil
+// indexURL returns the normalized package index base URL from the --index-url
+// flag, falling back to PIP_INDEX_URL. It returns "" when neither is set so the
+// PyPI client keeps its default.
+func indexURL(flag, indexType string) (string, error) {
+	raw := flag
+	if raw == "" {
+		raw = os.Getenv("PIP_INDEX_URL")
 	}
 
-	results, tmpDir, err := downloadPackages(ctx, plans, flags.jobs, httpClient, logger)
-	if err != nil {
-		return err
+	if raw == "" {
+		return "", nil
 	}
-	defer func() { _ = os.RemoveAll(tmpDir) }()
-
-	printDownloadResults(results)
 
-	fmt.Println("\nInstalling...")
+	return normalizeIndexURL(raw, indexType)
+}
 
-	inst := installer.New(env, installer.WithLogger(logger))
-	if err := inst.Install(ctx, results); err != nil {
-		return fmt.Errorf("installing packages: %w", err)
+// cacheNamespace returns the wheel cache namespace for a normalized index
+// URL: its host, or empty for the default index so the shared cache is kept.
+func cacheNamespace(baseURL string) string {
+	if baseURL == "" {
+		return ""
 	}
 
-	fmt.Printf("  ✓ %d packages installed\n", len(results))
-	fmt.Printf("\nDone in %.1fs\n", time.Since(start).Seconds())
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
 
-	return nil
+	return u.Host
 }
 
-func newLogger(verbose bool) *slog.Logger {
-	logLevel := slog.LevelWarn
-	if verbose {
-		logLevel = slog.LevelDebug
+// indexCredentials collects the credentials for the index URLs: basic auth
+// embedded in a URL, or the --index-auth bearer token, falling back to
+// PIPG_INDEX_TOKEN. The URLs are returned without their user info.
+func indexCredentials(token, baseURL string, extraURLs []string) (*pypi.Credentials, string, []string) {
+	if token == "" {
+		token = os.Getenv("PIPG_INDEX_TOKEN")
 	}
 
-	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
-}
-
-func detectEnv(ctx context.Context, pythonBin, targetDir string, logger *slog.Logger) (*python.Environment, error) {
-	pyDetector := python.New(python.WithPythonBin(pythonBin))
+	creds := pypi.NewCredentials(token)
+	baseURL = creds.Add(baseURL)
 
-	env, err := pyDetector.Detect(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("detecting Python environment: %w", err)
+	for i, u := range extraURLs {
+		extraURLs[i] = creds.Add(u)
 	}
 
-	if targetDir != "" {
-		absTarget, err := filepath.Abs(targetDir)
+	return creds, baseURL, extraURLs
+}
+
+// extraIndexURLs normalizes the --extra-index-url values.
+func extraIndexURLs(raw []string, indexType string) ([]string, error) {
+	urls := make([]string, 0, len(raw))
+
+	for _, r := range raw {
+		u, err := normalizeIndexURL(r, indexType)
 		if err != nil {
-			return nil, fmt.Errorf("resolving target directory: %w", err)
+			return nil, err
 		}
 
-		env.SitePackages = absTarget
+		urls = append(urls, u)
 	}
 
-	logger.Debug("detected Python environment",
-		slog.String("prefix", env.Prefix),
-		slog.String("site-packages", env.SitePackages),
-		slog.String("platform", env.PlatformTag),
-		slog.String("version", env.PythonVersion),
-		slog.Bool("venv", env.IsVirtualEnv),
-	)
-
-	return env, nil
+	return urls, nil
 }
 
-func resolveDeps(ctx context.Context, requirements []string, pypiClient pypi.Client, noDeps bool, env *python.Environment, logger *slog.Logger) ([]resolver.ResolvedPackage, error) {
-	fmt.Println("Resolving dependencies...")
-
-	markerEnv := buildMarkerEnv(env)
+// collectRequirements merges CLI args and requirements file entries. The
+// result also carries the constraint lines of files the requirements file
+// pulls in with -c, for loadConstraints, and its --hash pins.
+func collectRequirements(args []string, reqFile string) (*requirementsFile, error) {
+	reqs := &requirementsFile{}
 
-	resolverSvc := resolver.New(pypiClient,
-		resolver.WithNoDeps(noDeps),
-		resolver.WithMarkerEnv(markerEnv),
-		resolver.WithLogger(logger),
-	)
+	if reqFile != "" {
+		file, err := readRequirementsFile(reqFile)
+		if err != nil {
+			return nil, err
+		}
 
-	resolved, err := resolverSvc.Resolve(ctx, requirements)
-	if err != nil {
-		return nil, fmt.Errorf("resolving dependencies: %w", err)
+		reqs = file
 	}
 
-	resolvedMap := make(map[string]resolver.ResolvedPackage, len(resolved))
-	for _, pkg := range resolved {
-		resolvedMap[pkg.Name] = pkg
-	}
+	reqs.requirements = append(slices.Clone(args), reqs.requirements...)
 
-	rootNames := make([]string, 0, len(requirements))
-	for _, r := range requirements {
-		rootNames = append(rootNames, resolver.NormalizeName(resolver.ParseRequirement(r).Name))
+	for i, r := range reqs.requirements {
+		reqs.requirements[i] = normalizeRequirement(r)
 	}
 
-	printDependencyTree(rootNames, resolvedMap)
-
-	return resolved, nil
+	return reqs, nil
 }
 
-func printDryRun(plans []downloadPlan) {
-	fmt.Printf("\nWould download %d packages:\n", len(plans))
+// pypiHosts are the hosts whose project pages normalizeRequirement accepts.
+var pypiHosts = map[string]bool{"pypi.org": true, "test.pypi.org": true}
+
+// normalizeRequirement rewrites PyPI shorthands into standard requirements:
+//
+//	https://pypi.org/project/flask/        → flask
+//	https://pypi.org/project/flask/3.0.0/  → flask==3.0.0
+//	pypi:flask==3.0.0                      → flask==3.0.0
+//
+// Project URLs are recognized on pypi.org and test.pypi.org only. Anything
+// else is returned unchanged.
+func normalizeRequirement(req string) string {
+	if rest, ok := strings.CutPrefix(req, "pypi:"); ok {
+		return strings.TrimSpace(rest)
+	}
 
-	for _, p := range plans {
-		fmt.Printf("  %s (%s)\n", p.wheelURL.Filename, formatSize(p.wheelURL.Size))
+	if !strings.HasPrefix(req, "https://") && !strings.HasPrefix(req, "http://") {
+		return req
 	}
 
-	fmt.Println("\nDry run, no changes made.")
-}
+	u, err := url.Parse(req)
+	if err != nil || !pypiHosts[strings.ToLower(u.Hostname())] {
+		return req
+	}
 
-func printDownloadResults(results []downloader.Result) {
-	for _, r := range results {
-		suffix := ""
-		if r.Cached {
-			suffix = " (cached)"
-		}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "project" || parts[1] == "" {
+		return req
+	}
 
-		fmt.Printf("  ✓ %s (%s)%s\n", filepath.Base(r.FilePath), formatSize(r.Size), suffix)
+	if len(parts) == 3 && parts[2] != "" {
+		return parts[1] + "==" + parts[2]
 	}
-}
 
-type downloadPlan struct {
-	pkg      resolver.ResolvedPackage
-	wheelURL pypi.URL
+	return parts[1]
 }
 
-// selectWheels finds a compatible wheel for each resolved package.
-func selectWheels(ctx context.Context, resolved []resolver.ResolvedPackage, client pypi.Client, compatTags []downloader.WheelTag, env *python.Environment) ([]downloadPlan, error) {
-	var plans []downloadPlan
+// compatibleRequirements caps each requirement that has no specifier or marker
+// to the major version already installed, using "~=<major>.0", so upgrades
+// never cross a major boundary. Packages that are not installed are unchanged.
+func compatibleRequirements(requirements, libDirs []string, logger *slog.Logger) []string {
+	capped := make([]string, len(requirements))
 
-	for _, pkg := range resolved {
-		pkgInfo, err := client.GetPackageVersion(ctx, pkg.Name, pkg.Version)
-		if err != nil {
-			return nil, fmt.Errorf("fetching URLs for %s %s: %w", pkg.Name, pkg.Version, err)
+	for i, r := range requirements {
+		capped[i] = r
+
+		req := resolver.ParseRequirement(r)
+		if req.Specifier != "" || req.Marker != "" {
+			continue
 		}
 
-		wheel, err := downloader.SelectWheel(pkgInfo.URLs, compatTags)
-		if err != nil {
-			return nil, fmt.Errorf("no compatible wheel for %s %s (platform: %s, python: cp%s): %w",
-				pkg.Name, pkg.Version, wheelPlatform(env.PlatformTag), env.PythonVersion, err)
+		installed := installer.InstalledVersion(libDirs, req.Name)
+
+		major := majorVersion(installed)
+		if major == "" {
+			continue
 		}
 
-		plans = append(plans, downloadPlan{pkg: pkg, wheelURL: wheel})
+		capped[i] = strings.TrimSpace(r) + "~=" + major + ".0"
+		logger.Debug("capping to installed major version",
+			slog.String("package", req.Name),
+			slog.String("installed", installed),
+		)
 	}
 
-	return plans, nil
+	return capped
 }
 
-// downloadPackages downloads all planned packages concurrently with cache support.
-// Caller is responsible for cleaning up tmpDir after installation.
-func downloadPackages(ctx context.Context, plans []downloadPlan, jobs int, httpClient *http.Client, logger *slog.Logger) ([]downloader.Result, string, error) {
-	tmpDir, err := os.MkdirTemp("", "pipg-downloads-*")
-	if err != nil {
-		return nil, "", fmt.Errorf("creating temp directory: %w", err)
+// majorVersion returns the major release number of a version, keeping any
+// epoch: "2.31.0" → "2", "1!3.0" → "1!3". Returns "" if there is none.
+func majorVersion(v string) string {
+	epoch := ""
+	if e, rest, ok := strings.Cut(v, "!"); ok {
+		epoch, v = e+"!", rest
 	}
 
-	requests := buildDownloadRequests(plans)
-
-	workers := runtime.GOMAXPROCS(0)
-	if jobs > 0 {
-		workers = jobs
+	major, _, _ := strings.Cut(v, ".")
+	if _, err := strconv.Atoi(major); err != nil {
+		return ""
 	}
 
-	fmt.Printf("\nDownloading %d packages (%d workers)...\n", len(requests), workers)
+	return epoch + major
+}
 
-	dlManager := newDownloader(tmpDir, jobs, httpClient, logger)
+// loadAllowlist reads package names from a requirements-style manifest.
+// Version specifiers, extras, and markers are ignored; only names matter.
+// Returns nil when no manifest is given.
+func loadAllowlist(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
 
-	results, err := dlManager.Download(ctx, requests)
+	reqs, err := parseRequirementsFile(path)
 	if err != nil {
-		_ = os.RemoveAll(tmpDir)
-
-		return nil, "", fmt.Errorf("downloading packages: %w", err)
+		return nil, err
 	}
 
-	return results, tmpDir, nil
-}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("allowlist %s lists no packages", path)
+	}
 
-func buildDownloadRequests(plans []downloadPlan) []downloader.Request {
-	requests := make([]downloader.Request, len(plans))
-	for i, p := range plans {
-		requests[i] = downloader.Request{
-			Name:     p.pkg.Name,
-			Version:  p.pkg.Version,
-			URL:      p.wheelURL.URL,
-			SHA256:   p.wheelURL.Digests.SHA256,
-			Filename: p.wheelURL.Filename,
-		}
+	names := make([]string, 0, len(reqs))
+	for _, r := range reqs {
+		names = append(names, resolver.ParseRequirement(r).Name)
 	}
 
-	return requests
+	return names, nil
 }
 
-func newDownloader(tmpDir string, jobs int, httpClient *http.Client, logger *slog.Logger) *downloader.Manager {
-	wheelCache, err := cache.New(cache.WithLogger(logger))
-	if err != nil {
-		logger.Debug("cache unavailable, continuing without cache", slog.String("error", err.Error()))
+// loadConstraints parses a constraints file, plus constraint lines gathered
+// from requirements files, into version specifiers keyed by
+// normalized package name. Entries without a specifier constrain nothing.
+func loadConstraints(path string, extra []string) (map[string][]string, error) {
+	if path == "" && len(extra) == 0 {
+		return nil, nil
 	}
 
-	dlOpts := []downloader.Option{
-		downloader.WithHTTPClient(httpClient),
-		downloader.WithLogger(logger),
-	}
+	lines := extra
+
+	if path != "" {
+		// Every entry of a constraints file constrains, even one pulled in with -r.
+		file, err := readRequirementsFile(path)
+		if err != nil {
+			return nil, err
+		}
 
-	if wheelCache != nil {
-		dlOpts = append(dlOpts, downloader.WithCache(wheelCache))
+		lines = slices.Concat(lines, file.requirements, file.constraints)
 	}
 
-	if jobs > 0 {
-		dlOpts = append(dlOpts, downloader.WithMaxWorkers(jobs))
+	constraints := make(map[string][]string, len(lines))
+
+	for _, line := range lines {
+		req := resolver.ParseRequirement(normalizeRequirement(line))
+		if req.Specifier != "" {
+			constraints[req.Name] = append(constraints[req.Name], req.Specifier)
+		}
 	}
 
-	return downloader.New(tmpDir, dlOpts...)
+	return constraints, nil
 }
 
-// collectRequirements merges CLI args and requirements file entries.
-func collectRequirements(args []string, reqFile string) ([]string, error) {
-	var requirements []string
+// parseRequirementsFile reads a pip-compatible requirements file, following
+// its -r includes, and returns the requirements. Constraints pulled in with
+// -c are dropped; use readRequirementsFile to keep them.
+func parseRequirementsFile(path string) ([]string, error) {
+	file, err := readRequirementsFile(path)
+	if err != nil {
+		return nil, err
+	}
 
-	requirements = append(requirements, args...)
+	return file.requirements, nil
+}
 
-	if reqFile != "" {
-		fileReqs, err := parseRequirementsFile(reqFile)
-		if err != nil {
-			return nil, err
-		}
+// requirementsFile holds the entries of a requirements file and of the files
+// it includes.
+type requirementsFile struct {
+	requirements []string
+	constraints  []string            // from files included with -c
+	hashes       map[string][]string // sha256 digests allowed by --hash options, keyed by normalized name
+}
 
-		requirements = append(requirements, fileReqs...)
+// readRequirementsFile reads a pip-compatible requirements file. -r and -c
+// directives (and their long forms) include another file, relative to the
+// including one; an include cycle is an error. Lines ending in a backslash
+// continue on the next line, and --hash options after a requirement pin the
+// digests its wheel may have. Comments, empty lines and other pip options
+// (lines starting with -) are skipped.
+func readRequirementsFile(path string) (*requirementsFile, error) {
+	file := &requirementsFile{hashes: make(map[string][]string)}
+	if err := file.read(path, nil, false); err != nil {
+		return nil, err
 	}
 
-	return requirements, nil
+	return file, nil
 }
 
-// parseRequirementsFile reads a pip-compatible requirements file.
-// Skips comments, empty lines, and pip options (lines starting with -).
-func parseRequirementsFile(path string) ([]string, error) {
+// read adds the entries of path, as constraints when constraint is set.
+// stack holds the files being read, outermost first, to detect cycles.
+func (r *requirementsFile) read(path string, stack []string, constraint bool) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("opening requirements file %s: %w", path, err)
+	}
+
+	if slices.Contains(stack, abs) {
+		return fmt.Errorf("requirements file include cycle: %s", strings.Join(append(stack, abs), " -> "))
+	}
+
+	stack = append(slices.Clip(stack), abs)
+
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("opening requirements file %s: %w", path, err)
+		return fmt.Errorf("opening requirements file %s: %w", path, err)
 	}
 	defer func() { _ = f.Close() }()
 
-	var reqs []string
+	var continued string
 
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
@@ -371,19 +1610,139 @@ func parseRequirementsFile(path string) ([]string, error) {
 			line = strings.TrimSpace(line[:idx])
 		}
 
-		// Skip empty lines and pip options (e.g., --index-url, -e, -c).
-		if line == "" || strings.HasPrefix(line, "-") {
+		// Join continuation lines, as written by pip-compile --generate-hashes.
+		if rest, ok := strings.CutSuffix(line, "\\"); ok {
+			continued += rest + " "
+
+			continue
+		}
+
+		line, continued = strings.TrimSpace(continued+line), ""
+
+		if line == "" {
+			continue
+		}
+
+		if include, isConstraint, ok := includeDirective(line); ok {
+			include, err := expandEnv(include)
+			if err != nil {
+				return fmt.Errorf("requirements file %s: %w", path, err)
+			}
+
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+
+			if err := r.read(include, stack, constraint || isConstraint); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// Skip other pip options (e.g., --index-url, -e).
+		if strings.HasPrefix(line, "-") {
 			continue
 		}
 
-		reqs = append(reqs, line)
+		line, hashes, err := splitHashOptions(line)
+		if err != nil {
+			return fmt.Errorf("requirements file %s: %w", path, err)
+		}
+
+		line, err = expandEnv(line)
+		if err != nil {
+			return fmt.Errorf("requirements file %s: %w", path, err)
+		}
+
+		if len(hashes) > 0 {
+			name := resolver.ParseRequirement(normalizeRequirement(line)).Name
+			r.hashes[name] = append(r.hashes[name], hashes...)
+		}
+
+		if constraint {
+			r.constraints = append(r.constraints, line)
+		} else {
+			r.requirements = append(r.requirements, line)
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("reading requirements file %s: %w", path, err)
+		return fmt.Errorf("reading requirements file %s: %w", path, err)
 	}
 
-	return reqs, nil
+	return nil
+}
+
+// splitHashOptions splits the options that follow a requirement, e.g.,
+// "flask==3.0.3 --hash=sha256:…", from it and returns the requirement and the
+// digests of its --hash options. Other per-requirement options are ignored.
+func splitHashOptions(line string) (string, []string, error) {
+	fields := strings.Fields(line)
+
+	i := slices.IndexFunc(fields, func(f string) bool { return strings.HasPrefix(f, "--") })
+	if i < 0 {
+		return line, nil, nil
+	}
+
+	var hashes []string
+
+	opts := fields[i:]
+	for j := 0; j < len(opts); j++ {
+		opt, value, hasValue := strings.Cut(opts[j], "=")
+		if opt != "--hash" {
+			continue
+		}
+
+		if !hasValue {
+			if j+1 == len(opts) {
+				return "", nil, fmt.Errorf("--hash needs a value: %s", line)
+			}
+
+			j++
+			value = opts[j]
+		}
+
+		algo, digest, ok := strings.Cut(value, ":")
+		if !ok || digest == "" {
+			return "", nil, fmt.Errorf("invalid --hash %q; expected sha256:<digest>", value)
+		}
+
+		if algo != "sha256" {
+			return "", nil, fmt.Errorf("unsupported --hash algorithm %q; only sha256 is supported", algo)
+		}
+
+		hashes = append(hashes, digest)
+	}
+
+	return strings.Join(fields[:i], " "), hashes, nil
+}
+
+// includeDirective parses a -r/--requirement or -c/--constraint line, in any
+// of the forms "-r file", "-rfile" or "--requirement=file", into the named
+// file and whether it holds constraints.
+func includeDirective(line string) (path string, constraint, ok bool) {
+	for _, d := range []struct {
+		short, long string
+		constraint  bool
+	}{
+		{"-r", "--requirement", false},
+		{"-c", "--constraint", true},
+	} {
+		for _, prefix := range []string{d.long + "=", d.long, d.short} {
+			if rest, found := strings.CutPrefix(line, prefix); found {
+				if prefix == d.long && rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+					continue // e.g., --requirements-foo
+				}
+
+				if rest = strings.TrimSpace(rest); rest != "" {
+					return rest, d.constraint, true
+				}
+			}
+		}
+	}
+
+	return "", false, false
 }
 
 // buildMarkerEnv creates a PEP 508 marker environment from the detected Python env.
@@ -399,18 +1758,33 @@ func buildMarkerEnv(env *python.Environment) resolver.MarkerEnv {
 	case strings.HasPrefix(env.PlatformTag, "linux"):
 		sysPlatform = "linux"
 		osName = "posix"
+	case env.IsWindows():
+		sysPlatform = "win32"
+		osName = "nt"
 	default:
 		sysPlatform = "linux"
 		osName = "posix"
 	}
 
 	return resolver.MarkerEnv{
-		PythonVersion: pyVer,
-		SysPlatform:   sysPlatform,
-		OsName:        osName,
+		PythonVersion:     pyVer,
+		PythonFullVersion: env.FullVersion,
+		SysPlatform:       sysPlatform,
+		OsName:            osName,
 	}
 }
 
+// fullPythonVersion returns the version Requires-Python is checked against:
+// the interpreter's full version, e.g., "3.12.1", or major.minor when the
+// probe did not report it.
+func fullPythonVersion(env *python.Environment) string {
+	if env.FullVersion != "" {
+		return env.FullVersion
+	}
+
+	return resolver.FormatPythonVersion(env.PythonVersion)
+}
+
 // buildCompatTags generates PEP 425 compatible wheel tags ordered by priority.
 func buildCompatTags(env *python.Environment) []downloader.WheelTag {
 	pyVer := env.PythonVersion                 // e.g., "312"
@@ -418,18 +1792,27 @@ func buildCompatTags(env *python.Environment) []downloader.WheelTag {
 	cp := "cp" + pyVer                         // e.g., "cp312"
 	pyMajor := "py" + pyVer[:1]                // e.g., "py3"
 
+	// Free-threaded builds have their own ABI (e.g., "cp313t") and cannot
+	// load stable-ABI extensions.
+	abi := cp
+	if env.FreeThreaded {
+		abi += "t"
+	}
+
 	var tags []downloader.WheelTag
 
 	platforms := expandPlatform(platform)
 
 	// Native CPython + platform.
 	for _, plat := range platforms {
-		tags = append(tags, downloader.WheelTag{Python: cp, ABI: cp, Platform: plat})
+		tags = append(tags, downloader.WheelTag{Python: cp, ABI: abi, Platform: plat})
 	}
 
 	// Stable ABI + platform.
-	for _, plat := range platforms {
-		tags = append(tags, downloader.WheelTag{Python: cp, ABI: "abi3", Platform: plat})
+	if !env.FreeThreaded {
+		for _, plat := range platforms {
+			tags = append(tags, downloader.WheelTag{Python: cp, ABI: "abi3", Platform: plat})
+		}
 	}
 
 	// CPython, no ABI, specific platform.
@@ -437,6 +1820,16 @@ func buildCompatTags(env *python.Environment) []downloader.WheelTag {
 		tags = append(tags, downloader.WheelTag{Python: cp, ABI: "none", Platform: plat})
 	}
 
+	// Stable ABI built for older CPython 3 versions, newest first (e.g.,
+	// "cp36-abi3" on 3.12); abi3 first appeared in 3.2.
+	if minor, err := strconv.Atoi(pyVer[1:]); err == nil && pyVer[:1] == "3" && !env.FreeThreaded {
+		for m := minor - 1; m >= 2; m-- {
+			for _, plat := range platforms {
+				tags = append(tags, downloader.WheelTag{Python: "cp3" + strconv.Itoa(m), ABI: "abi3", Platform: plat})
+			}
+		}
+	}
+
 	// Pure Python, specific platform.
 	for _, plat := range platforms {
 		tags = append(tags, downloader.WheelTag{Python: pyMajor, ABI: "none", Platform: plat})
@@ -449,6 +1842,15 @@ func buildCompatTags(env *python.Environment) []downloader.WheelTag {
 	return tags
 }
 
+// abi3OnlyTags drops the version-specific native ABI tier (e.g.,
+// "cp312-cp312") from tags, leaving stable-ABI and ABI-less wheels that stay
+// importable across minor Python upgrades.
+func abi3OnlyTags(tags []downloader.WheelTag) []downloader.WheelTag {
+	return slices.DeleteFunc(slices.Clone(tags), func(tag downloader.WheelTag) bool {
+		return tag.ABI != "abi3" && tag.ABI != "none"
+	})
+}
+
 // expandPlatform expands a platform tag into a priority-ordered list including
 // manylinux variants (Linux) and lower macOS version variants.
 func expandPlatform(platform string) []string {
@@ -507,8 +1909,8 @@ func wheelPlatform(sysTag string) string {
 	return strings.ReplaceAll(s, ".", "_")
 }
 
-// printDependencyTree prints the resolved packages as a dependency tree.
-func printDependencyTree(roots []string, resolved map[string]resolver.ResolvedPackage) {
+// printDependencyTree prints the resolved packages as a dependency tree to w.
+func printDependencyTree(w io.Writer, roots []string, resolved map[string]resolver.ResolvedPackage) {
 	visited := make(map[string]bool)
 
 	for _, root := range roots {
@@ -517,15 +1919,15 @@ func printDependencyTree(roots []string, resolved map[string]resolver.ResolvedPa
 			continue
 		}
 
-		fmt.Printf("  %s %s\n", pkg.Name, pkg.Version)
+		_, _ = fmt.Fprintf(w, "  %s\n", treeLabel(pkg))
 
 		visited[root] = true
 
-		printSubTree(pkg.Dependencies, resolved, "  ", visited)
+		printSubTree(w, pkg.Dependencies, resolved, "  ", visited)
 	}
 }
 
-func printSubTree(deps []string, resolved map[string]resolver.ResolvedPackage, prefix string, visited map[string]bool) {
+func printSubTree(w io.Writer, deps []string, resolved map[string]resolver.ResolvedPackage, prefix string, visited map[string]bool) {
 	for i, depName := range deps {
 		pkg, ok := resolved[depName]
 		if !ok {
@@ -542,15 +1944,33 @@ func printSubTree(deps []string, resolved map[string]resolver.ResolvedPackage, p
 			childPrefix = "    "
 		}
 
-		fmt.Printf("%s%s%s %s\n", prefix, connector, pkg.Name, pkg.Version)
+		_, _ = fmt.Fprintf(w, "%s%s%s\n", prefix, connector, treeLabel(pkg))
 
 		if !visited[depName] && len(pkg.Dependencies) > 0 {
 			visited[depName] = true
-			printSubTree(pkg.Dependencies, resolved, prefix+childPrefix, visited)
+			printSubTree(w, pkg.Dependencies, resolved, prefix+childPrefix, visited)
 		}
 	}
 }
 
+// treeLabel returns "name version" for the dependency tree, noting a yanked
+// selection, e.g., "flask 3.0.0 (yanked: security)", or a newer yanked
+// release that was passed over.
+func treeLabel(pkg resolver.ResolvedPackage) string {
+	label := pkg.Name + " " + pkg.Version
+
+	switch {
+	case pkg.Yanked && pkg.YankedReason != "":
+		label += " (yanked: " + pkg.YankedReason + ")"
+	case pkg.Yanked:
+		label += " (yanked)"
+	case pkg.YankedSkipped != "":
+		label += " (skipped yanked " + pkg.YankedSkipped + ")"
+	}
+
+	return label
+}
+
 // formatSize returns a human-readable file size.
 func formatSize(bytes int64) string {
 	switch {
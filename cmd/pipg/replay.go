@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// interaction is one recorded index request and its response.
+type interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+
+	// BodyBase64 holds a body that is not valid UTF-8, such as a range of
+	// a wheel, which Body would corrupt; it is encoded as base64 in JSON.
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+}
+
+// interactionLog is the file format shared by --record and --replay.
+type interactionLog struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// recordTransport passes requests through to next and keeps a copy of every
+// exchange, so a run against a moving index can be replayed exactly.
+type recordTransport struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	log  interactionLog
+	path string
+}
+
+func newRecordTransport(next http.RoundTripper, path string) *recordTransport {
+	return &recordTransport{next: next, path: path}
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("recording %s: %w", req.URL, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	in := interaction{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header.Clone(),
+	}
+
+	if utf8.Valid(body) {
+		in.Body = string(body)
+	} else {
+		in.BodyBase64 = body
+	}
+
+	t.mu.Lock()
+	t.log.Interactions = append(t.log.Interactions, in)
+	t.mu.Unlock()
+
+	return resp, nil
+}
+
+// save writes the recorded interactions to the --record file.
+func (t *recordTransport) save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.MarshalIndent(t.log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding recorded interactions: %w", err)
+	}
+
+	if err := os.WriteFile(t.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", t.path, err)
+	}
+
+	return nil
+}
+
+// replayTransport serves recorded responses instead of using the network. A
+// URL requested several times gets its recorded responses in order, then the
+// last one again. A request that was never recorded fails.
+type replayTransport struct {
+	mu        sync.Mutex
+	responses map[string][]interaction
+}
+
+func newReplayTransport(path string) (*replayTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading replay file: %w", err)
+	}
+
+	var log interactionLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("parsing replay file %s: %w", path, err)
+	}
+
+	t := &replayTransport{responses: make(map[string][]interaction)}
+	for _, in := range log.Interactions {
+		key := in.Method + " " + in.URL
+		t.responses[key] = append(t.responses[key], in)
+	}
+
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.String()
+
+	t.mu.Lock()
+	recorded := t.responses[key]
+
+	if len(recorded) == 0 {
+		t.mu.Unlock()
+
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+
+	in := recorded[0]
+	if len(recorded) > 1 {
+		t.responses[key] = recorded[1:]
+	}
+	t.mu.Unlock()
+
+	body := []byte(in.Body)
+	if in.BodyBase64 != nil {
+		body = in.BodyBase64
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// indexClient returns the HTTP client for index requests: client itself, or
+// a copy whose transport records to recordPath or replays from replayPath.
+// The returned recorder is nil unless recording.
+func indexClient(client *http.Client, recordPath, replayPath string) (*http.Client, *recordTransport, error) {
+	switch {
+	case recordPath != "" && replayPath != "":
+		return nil, nil, fmt.Errorf("--record and --replay cannot be used together")
+	case replayPath != "":
+		replay, err := newReplayTransport(replayPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		indexed := *client
+		indexed.Transport = replay
+
+		return &indexed, nil, nil
+	case recordPath != "":
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+
+		recorder := newRecordTransport(transport, recordPath)
+
+		indexed := *client
+		indexed.Transport = recorder
+
+		return &indexed, recorder, nil
+	default:
+		return client, nil, nil
+	}
+}
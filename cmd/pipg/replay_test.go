@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+func resolvedPins(t *testing.T, httpClient *http.Client, baseURL string) []string {
+	t.Helper()
+
+	client := pypi.New(pypi.WithHTTPClient(httpClient), pypi.WithBaseURL(baseURL))
+	env := testEnv()
+
+	resolved, err := resolveDeps(context.Background(), []string{"flask"}, client, env, buildCompatTags(env), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("resolveDeps() error: %v", err)
+	}
+
+	pins := make([]string, 0, len(resolved))
+	for _, pkg := range resolved {
+		pins = append(pins, pkg.Name+"=="+pkg.Version)
+	}
+
+	slices.Sort(pins)
+
+	return pins
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	packages := map[string]*pypi.PackageInfo{
+		"flask": {
+			Info:     pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{"six>=1.0"}},
+			URLs:     []pypi.URL{wheelURL("flask", "3.0.0", "")},
+			Releases: map[string][]pypi.URL{"3.0.0": {wheelURL("flask", "3.0.0", "")}},
+		},
+		"six": {
+			Info:     pypi.Info{Name: "six", Version: "1.17.0"},
+			URLs:     []pypi.URL{wheelURL("six", "1.17.0", "")},
+			Releases: map[string][]pypi.URL{"1.17.0": {wheelURL("six", "1.17.0", "")}},
+		},
+	}
+
+	var hits atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		name, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+		info, ok := packages[name]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(info)
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "interactions.json")
+
+	recordClient, recorder, err := indexClient(srv.Client(), path, "")
+	if err != nil {
+		t.Fatalf("indexClient() error: %v", err)
+	}
+
+	recorded := resolvedPins(t, recordClient, srv.URL)
+
+	if err := recorder.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	srv.Close()
+	before := hits.Load()
+
+	replayClient, _, err := indexClient(&http.Client{}, "", path)
+	if err != nil {
+		t.Fatalf("indexClient() error: %v", err)
+	}
+
+	replayed := resolvedPins(t, replayClient, srv.URL)
+
+	if !slices.Equal(replayed, recorded) {
+		t.Errorf("replayed resolution %v, want %v", replayed, recorded)
+	}
+
+	if want := []string{"flask==3.0.0", "six==1.17.0"}; !slices.Equal(recorded, want) {
+		t.Errorf("recorded resolution %v, want %v", recorded, want)
+	}
+
+	if hits.Load() != before {
+		t.Error("replay reached the network")
+	}
+
+	if _, err := replayClient.Get(srv.URL + "/unrecorded/json"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("unrecorded request error = %v", err)
+	}
+}
+
+func TestIndexClientRecordAndReplayExclusive(t *testing.T) {
+	if _, _, err := indexClient(&http.Client{}, "a.json", "b.json"); err == nil {
+		t.Error("expected an error for --record with --replay")
+	}
+}
+
+func TestRecordAndReplayBinaryBody(t *testing.T) {
+	body := []byte{'P', 'K', 0x03, 0x04, 0xff, 0xfe, 0x00}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "interactions.json")
+
+	recordClient, recorder, err := indexClient(srv.Client(), path, "")
+	if err != nil {
+		t.Fatalf("indexClient() error: %v", err)
+	}
+
+	resp, err := recordClient.Get(srv.URL + "/demo.whl")
+	if err != nil {
+		t.Fatalf("recording GET error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if err := recorder.save(); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	replayClient, _, err := indexClient(&http.Client{}, "", path)
+	if err != nil {
+		t.Fatalf("indexClient() error: %v", err)
+	}
+
+	resp, err = replayClient.Get(srv.URL + "/demo.whl")
+	if err != nil {
+		t.Fatalf("replayed GET error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading replayed body: %v", err)
+	}
+
+	if !bytes.Equal(got, body) {
+		t.Errorf("replayed body = %x, want %x", got, body)
+	}
+}
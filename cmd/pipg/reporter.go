@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+)
+
+// reporter writes the human-readable output of an install run. With
+// --summary-only, everything but the final line is discarded so CI logs get
+// a single outcome; errors are still printed by exitCode.
+type reporter struct {
+	out         io.Writer // intermediate output: resolution, downloads, progress
+	summary     io.Writer
+	summaryOnly bool
+}
+
+func newReporter(w io.Writer, summaryOnly bool) *reporter {
+	r := &reporter{out: w, summary: w, summaryOnly: summaryOnly}
+	if summaryOnly {
+		r.out = io.Discard
+	}
+
+	return r
+}
+
+func (r *reporter) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(r.out, format, args...)
+}
+
+// nothingToInstall reports a run where every requirement was satisfied.
+func (r *reporter) nothingToInstall() {
+	if r.summaryOnly {
+		_, _ = fmt.Fprintln(r.summary, "Nothing to install.")
+
+		return
+	}
+
+	r.printf("\nNothing to install.\n")
+}
+
+// done reports a successful install of results that took elapsed.
+func (r *reporter) done(results []downloader.Result, elapsed time.Duration) {
+	if !r.summaryOnly {
+		r.printf("\nDone in %.1fs\n", elapsed.Seconds())
+
+		return
+	}
+
+	var size int64
+	for _, res := range results {
+		size += res.Size
+	}
+
+	_, _ = fmt.Fprintf(r.summary, "Installed %d packages (%s) in %.1fs\n", len(results), formatSize(size), elapsed.Seconds())
+}
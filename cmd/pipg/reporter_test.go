@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+func TestSummaryOnlyPrintsSingleLine(t *testing.T) {
+	t.Setenv("PIPG_CACHE_DIR", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("wheel"))
+	}))
+	t.Cleanup(srv.Close)
+
+	wheel := wheelURL("six", "1.17.0", "")
+	wheel.URL = srv.URL + "/" + wheel.Filename
+
+	client := &mockClient{packages: map[string]*pypi.PackageInfo{
+		"six": {
+			Info: pypi.Info{Name: "six", Version: "1.17.0"},
+			URLs: []pypi.URL{wheel},
+		},
+	}}
+
+	var buf bytes.Buffer
+
+	report := newReporter(&buf, true)
+	ctx := context.Background()
+	env := testEnv()
+	tags := buildCompatTags(env)
+	logger := slog.New(slog.DiscardHandler)
+	requirements := []string{"six"}
+
+	report.printf("Resolving dependencies...\n")
+
+	resolved, err := resolveDeps(ctx, requirements, client, env, tags, logger)
+	if err != nil {
+		t.Fatalf("resolveDeps() error: %v", err)
+	}
+
+	printResolution(report.out, requirements, resolved)
+
+	plans, err := selectWheels(ctx, resolved, client, tags, env, false)
+	if err != nil {
+		t.Fatalf("selectWheels() error: %v", err)
+	}
+
+	results, _, err := downloadPackages(ctx, report.out, plans, 1, retryOptions{strategy: backoffExponential, attempts: 3}, cacheOptions{}, srv.Client(), nil, downloader.VerifyEnforce, nil, logger, nil)
+	if err != nil {
+		t.Fatalf("downloadPackages() error: %v", err)
+	}
+
+	printDownloadResults(report.out, results)
+
+	if err := installPackages(ctx, report.out, nopInstaller{}, results, &installProgress{}, nil); err != nil {
+		t.Fatalf("installPackages() error: %v", err)
+	}
+
+	report.done(results, 1500*time.Millisecond)
+
+	if got, want := buf.String(), "Installed 1 packages (5 B) in 1.5s\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestReporterFullOutput(t *testing.T) {
+	var buf bytes.Buffer
+
+	report := newReporter(&buf, false)
+	report.printf("Resolving dependencies...\n")
+	report.done(nil, time.Second)
+
+	if got, want := buf.String(), "Resolving dependencies...\n\nDone in 1.0s\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+// Output formats for --only-resolve.
+const (
+	formatPlain     = "plain"
+	formatAnnotated = "annotated"
+	formatJSON      = "json"
+)
+
+var resolveFormats = []string{formatPlain, formatAnnotated, formatJSON}
+
+// resolvedPin is the JSON form of a resolved package.
+type resolvedPin struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	RequiredBy []string `json:"required_by"`
+}
+
+// writeResolution writes resolved packages sorted by name in the given format:
+// plain "name==version" lines, pip-compile style lines annotated with
+// "# via <parent>" comments, or a JSON array.
+func writeResolution(w io.Writer, format string, resolved []resolver.ResolvedPackage) error {
+	pkgs := make([]resolver.ResolvedPackage, len(resolved))
+	copy(pkgs, resolved)
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+
+	switch format {
+	case formatJSON:
+		pins := make([]resolvedPin, len(pkgs))
+		for i, pkg := range pkgs {
+			pins[i] = resolvedPin{Name: pkg.Name, Version: pkg.Version, RequiredBy: parentNames(pkg)}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(pins)
+	case formatAnnotated:
+		for _, pkg := range pkgs {
+			if _, err := fmt.Fprintf(w, "%s==%s\n", pkg.Name, pkg.Version); err != nil {
+				return err
+			}
+
+			if err := writeVia(w, parentNames(pkg)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		for _, pkg := range pkgs {
+			if _, err := fmt.Fprintf(w, "%s==%s\n", pkg.Name, pkg.Version); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// writeVia writes pip-compile's "# via" annotation: inline for a single
+// parent, one parent per line for several, nothing for root-only packages.
+func writeVia(w io.Writer, parents []string) error {
+	switch len(parents) {
+	case 0:
+		return nil
+	case 1:
+		_, err := fmt.Fprintf(w, "    # via %s\n", parents[0])
+
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "    # via"); err != nil {
+		return err
+	}
+
+	for _, p := range parents {
+		if _, err := fmt.Fprintf(w, "    #   %s\n", p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parentNames returns the sorted, de-duplicated names of the packages that
+// required pkg.
+func parentNames(pkg resolver.ResolvedPackage) []string {
+	names := make([]string, 0, len(pkg.RequiredBy))
+
+	for _, d := range pkg.RequiredBy {
+		if !slices.Contains(names, d.Name) {
+			names = append(names, d.Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+func testResolution() []resolver.ResolvedPackage {
+	return []resolver.ResolvedPackage{
+		{Name: "markupsafe", Version: "2.1.5", RequiredBy: []resolver.Dependent{
+			{Name: "jinja2", Specifier: ">=2.0"},
+			{Name: "werkzeug", Specifier: ">=2.1.1"},
+		}},
+		{Name: "flask", Version: "3.0.0"},
+		{Name: "jinja2", Version: "3.1.3", RequiredBy: []resolver.Dependent{{Name: "flask", Specifier: ">=3.1.2"}}},
+		{Name: "werkzeug", Version: "3.0.1", RequiredBy: []resolver.Dependent{{Name: "flask", Specifier: ">=3.0.0"}}},
+	}
+}
+
+func TestWriteResolutionPlain(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResolution(&buf, formatPlain, testResolution()); err != nil {
+		t.Fatalf("writeResolution() error: %v", err)
+	}
+
+	want := "flask==3.0.0\njinja2==3.1.3\nmarkupsafe==2.1.5\nwerkzeug==3.0.1\n"
+	if buf.String() != want {
+		t.Errorf("plain output =\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteResolutionAnnotated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResolution(&buf, formatAnnotated, testResolution()); err != nil {
+		t.Fatalf("writeResolution() error: %v", err)
+	}
+
+	want := `flask==3.0.0
+jinja2==3.1.3
+    # via flask
+markupsafe==2.1.5
+    # via
+    #   jinja2
+    #   werkzeug
+werkzeug==3.0.1
+    # via flask
+`
+	if buf.String() != want {
+		t.Errorf("annotated output =\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteResolutionJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResolution(&buf, formatJSON, testResolution()); err != nil {
+		t.Fatalf("writeResolution() error: %v", err)
+	}
+
+	var pins []resolvedPin
+	if err := json.Unmarshal(buf.Bytes(), &pins); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if len(pins) != 4 || pins[2].Name != "markupsafe" || len(pins[2].RequiredBy) != 2 {
+		t.Errorf("unexpected JSON output: %s", buf.String())
+	}
+}
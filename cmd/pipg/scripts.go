@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+// installedScripts returns the console and GUI entry point scripts declared
+// by the packages just installed into libDirs, sorted by name.
+func installedScripts(libDirs []string, results []downloader.Result) []string {
+	var scripts []string
+
+	for _, r := range results {
+		scripts = append(scripts, installer.InstalledScripts(libDirs, r.Name)...)
+	}
+
+	slices.Sort(scripts)
+
+	return slices.Compact(scripts)
+}
+
+// warnScriptLocation warns on w when scripts were installed to binDir but
+// binDir is not listed in pathEnv, the value of $PATH.
+func warnScriptLocation(w io.Writer, scripts []string, binDir, pathEnv string) {
+	if len(scripts) == 0 || onPath(binDir, pathEnv) {
+		return
+	}
+
+	noun := "script"
+	if len(scripts) > 1 {
+		noun = "scripts"
+	}
+
+	_, _ = fmt.Fprintf(w, "WARNING: installed %s %s to %s which is not on PATH.\n",
+		noun, strings.Join(scripts, ", "), binDir)
+	_, _ = fmt.Fprintln(w, "  Add it to PATH, or use --no-warn-script-location to suppress this warning.")
+}
+
+// onPath reports whether dir is one of the entries of pathEnv.
+func onPath(dir, pathEnv string) bool {
+	dir = filepath.Clean(dir)
+
+	for _, entry := range filepath.SplitList(pathEnv) {
+		if entry != "" && filepath.Clean(entry) == dir {
+			return true
+		}
+	}
+
+	return false
+}
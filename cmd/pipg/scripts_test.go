@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+)
+
+func TestWarnScriptLocation(t *testing.T) {
+	binDir := filepath.Join(t.TempDir(), "bin")
+	otherDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		scripts  []string
+		pathEnv  string
+		wantWarn bool
+	}{
+		{
+			name:     "bin dir not on PATH",
+			scripts:  []string{"mycli"},
+			pathEnv:  strings.Join([]string{otherDir, "/usr/bin"}, string(os.PathListSeparator)),
+			wantWarn: true,
+		},
+		{
+			name:    "bin dir on PATH",
+			scripts: []string{"mycli"},
+			pathEnv: strings.Join([]string{otherDir, binDir + string(filepath.Separator)}, string(os.PathListSeparator)),
+		},
+		{
+			name:    "no scripts installed",
+			pathEnv: otherDir,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			warnScriptLocation(&buf, tt.scripts, binDir, tt.pathEnv)
+
+			if !tt.wantWarn {
+				if buf.Len() > 0 {
+					t.Errorf("unexpected warning:\n%s", buf.String())
+				}
+
+				return
+			}
+
+			want := "installed script mycli to " + binDir + " which is not on PATH"
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("warning = %q, want it to contain %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestInstalledScripts(t *testing.T) {
+	siteDir := t.TempDir()
+	distInfo := filepath.Join(siteDir, "mypkg-1.0.dist-info")
+
+	if err := os.MkdirAll(distInfo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	entryPoints := "[console_scripts]\nmycli = mypkg.cli:main\nmytool = mypkg.tool:run\n"
+	if err := os.WriteFile(filepath.Join(distInfo, "entry_points.txt"), []byte(entryPoints), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := installedScripts([]string{siteDir}, []downloader.Result{{Name: "mypkg"}, {Name: "noscripts"}})
+	if want := []string{"mycli", "mytool"}; !slices.Equal(got, want) {
+		t.Errorf("installedScripts() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// httpTimeout is the overall timeout of index requests, and the default
+// download stall timeout.
+const httpTimeout = 30 * time.Second
+
+// transportOptions holds the TLS and proxy settings shared by the PyPI client
+// and the downloader.
+type transportOptions struct {
+	caCert       string        // path to a PEM bundle added to the system trust store
+	trustedHosts []string      // hosts for which TLS verification is skipped
+	minTLS       string        // minimum TLS version, "1.2" (default) or "1.3"
+	proxy        string        // proxy URL; empty uses HTTP_PROXY/HTTPS_PROXY
+	timeout      time.Duration // overall limit of index requests; 0 uses httpTimeout
+}
+
+// addTransportFlags registers the TLS and proxy flags on cmd.
+func addTransportFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ca-cert", "", "Path to a PEM CA bundle trusted in addition to the system roots")
+	cmd.Flags().StringArray("trusted-host", nil, "Skip TLS verification for this host (repeatable)")
+	cmd.Flags().String("min-tls", "1.2", "Minimum TLS version for all connections: 1.2 or 1.3")
+	cmd.Flags().String("proxy", "", "Proxy URL for all connections (default: $HTTPS_PROXY/$HTTP_PROXY; $NO_PROXY hosts bypass it)")
+}
+
+// transportFlags reads the flags registered by addTransportFlags.
+func transportFlags(cmd *cobra.Command) transportOptions {
+	var opts transportOptions
+
+	opts.caCert, _ = cmd.Flags().GetString("ca-cert")
+	opts.trustedHosts, _ = cmd.Flags().GetStringArray("trusted-host")
+	opts.minTLS, _ = cmd.Flags().GetString("min-tls")
+	opts.proxy, _ = cmd.Flags().GetString("proxy")
+
+	return opts
+}
+
+// newHTTPClient builds the HTTP client used for both metadata and downloads.
+func newHTTPClient(opts transportOptions) (*http.Client, error) {
+	roots, err := loadRootCAs(opts.caCert)
+	if err != nil {
+		return nil, err
+	}
+
+	minVersion, err := parseTLSVersion(opts.minTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := proxyFunc(opts.proxy, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = newTransport(&tls.Config{RootCAs: roots, MinVersion: minVersion}, proxy)
+
+	if len(opts.trustedHosts) > 0 {
+		trusted := make(map[string]bool, len(opts.trustedHosts))
+		for _, h := range opts.trustedHosts {
+			trusted[hostOnly(h)] = true
+		}
+
+		rt = &hostTransport{
+			verified: rt,
+			insecure: newTransport(&tls.Config{RootCAs: roots, MinVersion: minVersion, InsecureSkipVerify: true}, proxy),
+			trusted:  trusted,
+		}
+	}
+
+	timeout := httpTimeout
+	if opts.timeout > 0 {
+		timeout = opts.timeout
+	}
+
+	return &http.Client{Timeout: timeout, Transport: rt}, nil
+}
+
+// downloadClient returns a copy of client without the overall timeout: a
+// large wheel on a slow link can need longer than any fixed limit, so
+// downloads are bounded by the downloader's stall timeout (--timeout) instead.
+func downloadClient(client *http.Client) *http.Client {
+	c := *client
+	c.Timeout = 0
+
+	return &c
+}
+
+// newTransport clones the default transport with the given TLS configuration
+// and proxy selection.
+func newTransport(tlsConfig *tls.Config, proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+
+	return transport
+}
+
+// proxyFunc returns the proxy selection for a --proxy value. An empty value
+// uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment. Otherwise
+// every request goes through the given proxy except requests to hosts listed
+// in NO_PROXY and to loopback addresses, matching the environment behavior.
+func proxyFunc(raw string, getenv func(string) string) (func(*http.Request) (*url.URL, error), error) {
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --proxy %q: %w", raw, err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid --proxy %q: expected an http, https or socks5 URL", raw)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid --proxy %q: missing host", raw)
+	}
+
+	noProxy := getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = getenv("no_proxy")
+	}
+
+	bypass := parseNoProxy(noProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass(req.URL.Host) {
+			return nil, nil
+		}
+
+		return proxyURL, nil
+	}, nil
+}
+
+// parseNoProxy returns a matcher for a NO_PROXY list. Entries are host names,
+// which also match their subdomains (a leading "." is optional), IP addresses,
+// CIDR ranges, any of them with an optional port, or "*" for every host.
+// Loopback hosts always match.
+func parseNoProxy(list string) func(hostport string) bool {
+	var (
+		all     bool
+		domains []string
+		nets    []*net.IPNet
+	)
+
+	for entry := range strings.SplitSeq(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		switch {
+		case entry == "":
+		case entry == "*":
+			all = true
+		default:
+			if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+				nets = append(nets, ipNet)
+
+				continue
+			}
+
+			domains = append(domains, strings.TrimPrefix(hostOnly(entry), "."))
+		}
+	}
+
+	return func(hostport string) bool {
+		host := strings.Trim(hostOnly(hostport), "[]")
+		if all || host == "localhost" {
+			return true
+		}
+
+		if ip := net.ParseIP(host); ip != nil {
+			if ip.IsLoopback() {
+				return true
+			}
+
+			for _, n := range nets {
+				if n.Contains(ip) {
+					return true
+				}
+			}
+		}
+
+		for _, d := range domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// hostTransport relaxes TLS verification for trusted hosts only. Requests to
+// any other host, including redirects away from a trusted host, go through
+// the verifying transport.
+type hostTransport struct {
+	verified http.RoundTripper
+	insecure http.RoundTripper
+	trusted  map[string]bool
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.trusted[strings.ToLower(req.URL.Hostname())] {
+		return t.insecure.RoundTrip(req)
+	}
+
+	return t.verified.RoundTrip(req)
+}
+
+// loadRootCAs returns the system cert pool, extended with the certificates in
+// caCert when a bundle path is given.
+func loadRootCAs(caCert string) (*x509.CertPool, error) {
+	if caCert == "" {
+		return x509.SystemCertPool()
+	}
+
+	pem, err := os.ReadFile(caCert)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle %s: %w", caCert, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", caCert)
+	}
+
+	return pool, nil
+}
+
+// parseTLSVersion converts a --min-tls value to a crypto/tls version constant.
+// An empty value means TLS 1.2.
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported --min-tls %q; expected 1.2 or 1.3", v)
+	}
+}
+
+// hostOnly strips an optional port from a --trusted-host value.
+// "mirror.local:8443" → "mirror.local"
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return strings.ToLower(host)
+	}
+
+	return strings.ToLower(hostport)
+}
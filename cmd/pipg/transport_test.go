@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTLSTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func getStatus(t *testing.T, client *http.Client, rawURL string) error {
+	t.Helper()
+
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func TestSelfSignedHostFailsByDefault(t *testing.T) {
+	srv := newTLSTestServer(t)
+
+	client, err := newHTTPClient(transportOptions{})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+
+	if err := getStatus(t, client, srv.URL); err == nil {
+		t.Fatal("expected TLS verification error for self-signed host, got nil")
+	}
+}
+
+func TestTrustedHostSkipsVerification(t *testing.T) {
+	srv := newTLSTestServer(t)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parsing server URL: %v", err)
+	}
+
+	client, err := newHTTPClient(transportOptions{trustedHosts: []string{u.Host}})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+
+	if err := getStatus(t, client, srv.URL); err != nil {
+		t.Fatalf("request to trusted host failed: %v", err)
+	}
+}
+
+func TestTrustedHostDoesNotRelaxOtherHosts(t *testing.T) {
+	srv := newTLSTestServer(t)
+
+	client, err := newHTTPClient(transportOptions{trustedHosts: []string{"mirror.internal"}})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+
+	if err := getStatus(t, client, srv.URL); err == nil {
+		t.Fatal("expected TLS verification error for untrusted host, got nil")
+	}
+}
+
+func TestCACertBundleTrustsServer(t *testing.T) {
+	srv := newTLSTestServer(t)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	if err := os.WriteFile(bundle, data, 0o644); err != nil {
+		t.Fatalf("writing CA bundle: %v", err)
+	}
+
+	client, err := newHTTPClient(transportOptions{caCert: bundle})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+
+	if err := getStatus(t, client, srv.URL); err != nil {
+		t.Fatalf("request with CA bundle failed: %v", err)
+	}
+}
+
+func TestCACertBundleInvalid(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("writing CA bundle: %v", err)
+	}
+
+	if _, err := newHTTPClient(transportOptions{caCert: bundle}); err == nil {
+		t.Fatal("expected error for invalid CA bundle, got nil")
+	}
+}
+
+func TestMinTLSVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	if err := os.WriteFile(bundle, data, 0o644); err != nil {
+		t.Fatalf("writing CA bundle: %v", err)
+	}
+
+	client12, err := newHTTPClient(transportOptions{caCert: bundle, minTLS: "1.2"})
+	if err != nil {
+		t.Fatalf("newHTTPClient(1.2) error: %v", err)
+	}
+
+	if err := getStatus(t, client12, srv.URL); err != nil {
+		t.Fatalf("TLS 1.2 client failed against a TLS 1.2 server: %v", err)
+	}
+
+	client13, err := newHTTPClient(transportOptions{caCert: bundle, minTLS: "1.3"})
+	if err != nil {
+		t.Fatalf("newHTTPClient(1.3) error: %v", err)
+	}
+
+	if err := getStatus(t, client13, srv.URL); err == nil {
+		t.Fatal("expected --min-tls 1.3 client to refuse a TLS 1.2 server, got nil")
+	}
+}
+
+func TestMinTLSVersionInvalid(t *testing.T) {
+	if _, err := newHTTPClient(transportOptions{minTLS: "1.1"}); err == nil {
+		t.Fatal("expected error for unsupported --min-tls value, got nil")
+	}
+}
+
+func TestHostOnly(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"mirror.local", "mirror.local"},
+		{"Mirror.Local:8443", "mirror.local"},
+		{"127.0.0.1:443", "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		if got := hostOnly(tt.input); got != tt.want {
+			t.Errorf("hostOnly(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestProxyRoutesRequests(t *testing.T) {
+	var proxiedHost string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(proxy.Close)
+
+	client, err := newHTTPClient(transportOptions{proxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+
+	if err := getStatus(t, client, "http://pypi.test/simple/six/"); err != nil {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+
+	if proxiedHost != "pypi.test" {
+		t.Errorf("proxy saw host %q, want %q", proxiedHost, "pypi.test")
+	}
+}
+
+func TestProxyNoProxy(t *testing.T) {
+	getenv := func(key string) string {
+		if key == "NO_PROXY" {
+			return "internal.example, .corp.example,10.0.0.0/8,mirror.local:8080"
+		}
+
+		return ""
+	}
+
+	proxy, err := proxyFunc("http://proxy.example:3128", getenv)
+	if err != nil {
+		t.Fatalf("proxyFunc() error: %v", err)
+	}
+
+	tests := map[string]bool{
+		"https://pypi.org/simple/":            true,
+		"https://internal.example/simple/":    false,
+		"https://pkgs.internal.example/":      false,
+		"https://a.corp.example/":             false,
+		"http://10.1.2.3:8080/":               false,
+		"http://mirror.local/":                false,
+		"http://127.0.0.1:8080/":              false,
+		"http://localhost/":                   false,
+		"https://notinternal.example/simple/": true,
+	}
+
+	for rawURL, wantProxy := range tests {
+		req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+
+		got, err := proxy(req)
+		if err != nil {
+			t.Fatalf("proxy(%s) error: %v", rawURL, err)
+		}
+
+		if (got != nil) != wantProxy {
+			t.Errorf("proxy(%s) = %v, want proxied %v", rawURL, got, wantProxy)
+		}
+	}
+}
+
+func TestProxyInvalid(t *testing.T) {
+	for _, raw := range []string{"proxy.example:3128", "ftp://proxy.example", "http://"} {
+		if _, err := newHTTPClient(transportOptions{proxy: raw}); err == nil {
+			t.Errorf("newHTTPClient(proxy %q) expected error, got nil", raw)
+		}
+	}
+}
+
+func TestDownloadClientHasNoOverallTimeout(t *testing.T) {
+	client, err := newHTTPClient(transportOptions{})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+
+	dl := downloadClient(client)
+	if dl.Timeout != 0 || dl.Transport != client.Transport {
+		t.Errorf("download client: timeout %v, shared transport %v; want no timeout over the same transport",
+			dl.Timeout, dl.Transport == client.Transport)
+	}
+
+	if client.Timeout != httpTimeout {
+		t.Errorf("index client timeout = %v, want %v", client.Timeout, httpTimeout)
+	}
+}
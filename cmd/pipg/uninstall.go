@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+	"github.com/bilusteknoloji/pipg/internal/resolver"
+)
+
+func newUninstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uninstall [packages...]",
+		Short: "Uninstall Python packages",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runUninstall,
+	}
+
+	cmd.Flags().String("python", "python3", "Python binary to use, or a py launcher spec like py:-3.11")
+	cmd.Flags().String("target", "", "Target directory (default: auto-detect site-packages)")
+	cmd.Flags().CountP("verbose", "v", "Verbose output")
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before removing")
+
+	return cmd
+}
+
+func runUninstall(cmd *cobra.Command, args []string) error {
+	pythonBin, _ := cmd.Flags().GetString("python")
+	targetDir, _ := cmd.Flags().GetString("target")
+	verbose, _ := cmd.Flags().GetCount("verbose")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	logger := newLogger(verbose)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env, err := detectEnv(ctx, pythonBin, targetDir, logger)
+	if err != nil {
+		return err
+	}
+
+	// Check every package up front so nothing is removed when one is missing.
+	names := make([]string, len(args))
+	versions := make([]string, len(args))
+
+	for i, arg := range args {
+		names[i] = resolver.NormalizeName(arg)
+
+		versions[i] = installer.InstalledVersion(env.LibDirs(), names[i])
+		if versions[i] == "" {
+			return fmt.Errorf("%s: %w", arg, installer.ErrNotInstalled)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+
+	if !yes {
+		_, _ = fmt.Fprintln(out, "Would remove:")
+
+		for i, name := range names {
+			_, _ = fmt.Fprintf(out, "  %s-%s\n", name, versions[i])
+		}
+
+		if !confirm(cmd.InOrStdin(), out, "Proceed?") {
+			_, _ = fmt.Fprintln(out, "Aborted.")
+
+			return nil
+		}
+	}
+
+	inst := installer.New(env, installer.WithLogger(logger))
+
+	for i, name := range names {
+		if err := inst.Uninstall(ctx, name); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(out, "Successfully uninstalled %s-%s\n", name, versions[i])
+	}
+
+	return nil
+}
+
+// confirm asks a yes/no question on w and reads the answer from r.
+// Only "y" or "yes" (case-insensitive) count as consent.
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	_, _ = fmt.Fprintf(w, "%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -3,12 +3,18 @@ package cache
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Store defines the interface for wheel caching.
@@ -29,6 +35,28 @@ func WithDir(dir string) Option {
 	}
 }
 
+// WithNamespace scopes the cache to a subdirectory named after an index
+// host, so wheels with the same filename from different indexes never serve
+// for each other. An empty host keeps the shared, unscoped cache.
+func WithNamespace(host string) Option {
+	return func(m *Manager) {
+		m.namespace = namespaceDir(host)
+	}
+}
+
+// WithMaxSize bounds the cache at maxBytes, counting the wheels of every
+// namespace: after each Put the least recently used wheels are evicted until
+// the total fits. Get counts as a use. Wheels this Manager has stored or
+// returned are never evicted by it, since callers go on to read them.
+// Non-positive values mean unbounded.
+func WithMaxSize(maxBytes int64) Option {
+	return func(m *Manager) {
+		if maxBytes > 0 {
+			m.maxSize = maxBytes
+		}
+	}
+}
+
 // WithLogger sets the structured logger.
 func WithLogger(l *slog.Logger) Option {
 	return func(m *Manager) {
@@ -40,8 +68,15 @@ func WithLogger(l *slog.Logger) Option {
 
 // Manager manages a local wheel cache directory.
 type Manager struct {
-	dir    string
-	logger *slog.Logger
+	dir       string
+	root      string // dir without the namespace
+	namespace string
+	logger    *slog.Logger
+	maxSize   int64
+	evictMu   sync.Mutex // serializes eviction between concurrent Puts
+
+	pinMu  sync.Mutex
+	pinned map[string]bool // paths handed out by Get or stored by Put
 }
 
 // compile-time proof that Manager implements Store.
@@ -52,14 +87,18 @@ var _ Store = (*Manager)(nil)
 func New(opts ...Option) (*Manager, error) {
 	m := &Manager{
 		logger: slog.Default(),
+		pinned: make(map[string]bool),
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
 
-	if m.dir == "" {
-		m.dir = defaultCacheDir()
+	m.dir = Dir(m.dir)
+	m.root = m.dir
+
+	if m.namespace != "" {
+		m.dir = filepath.Join(m.dir, m.namespace)
 	}
 
 	if err := os.MkdirAll(m.dir, 0o755); err != nil {
@@ -97,6 +136,8 @@ func (m *Manager) Get(filename, expectedSHA256 string) (string, bool) {
 		}
 	}
 
+	m.touch(path)
+	m.pin(path)
 	m.logger.Debug("cache hit", slog.String("file", filename))
 
 	return path, true
@@ -138,10 +179,113 @@ func (m *Manager) Put(srcPath, filename string) error {
 	}
 
 	m.logger.Debug("cached", slog.String("file", filename))
+	m.pin(dstPath)
+
+	if m.maxSize > 0 {
+		m.evict()
+	}
 
 	return nil
 }
 
+// pin protects path from eviction by this Manager.
+func (m *Manager) pin(path string) {
+	m.pinMu.Lock()
+	m.pinned[path] = true
+	m.pinMu.Unlock()
+}
+
+// isPinned reports whether path was pinned.
+func (m *Manager) isPinned(path string) bool {
+	m.pinMu.Lock()
+	defer m.pinMu.Unlock()
+
+	return m.pinned[path]
+}
+
+// touch marks a cached wheel as recently used for eviction.
+func (m *Manager) touch(path string) {
+	if m.maxSize == 0 {
+		return
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		m.logger.Debug("cache touch failed", slog.String("file", path), slog.String("error", err.Error()))
+	}
+}
+
+// evict removes the least recently used wheels of all namespaces until the
+// cache fits in maxSize. Pinned wheels are never evicted.
+func (m *Manager) evict() {
+	m.evictMu.Lock()
+	defer m.evictMu.Unlock()
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var entries []entry
+	var total int64
+
+	err := walkWheels(m.root, func(path string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed concurrently
+		}
+
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		m.logger.Debug("cache eviction skipped", slog.String("error", err.Error()))
+
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].modTime.Equal(entries[j].modTime) {
+			return entries[i].modTime.Before(entries[j].modTime)
+		}
+
+		return entries[i].path < entries[j].path
+	})
+
+	for _, e := range entries {
+		if total <= m.maxSize {
+			break
+		}
+
+		if m.isPinned(e.path) {
+			continue
+		}
+
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			m.logger.Debug("cache eviction failed", slog.String("file", e.path), slog.String("error", err.Error()))
+
+			continue
+		}
+
+		total -= e.size
+		m.logger.Debug("evicted", slog.String("file", filepath.Base(e.path)))
+	}
+}
+
+// namespaceDir turns an index host into a safe directory name. Port
+// separators are replaced since ':' is not allowed in Windows paths.
+func namespaceDir(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || host == "." || host == ".." {
+		return ""
+	}
+
+	return strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(host)
+}
+
 // hashFile computes the SHA256 hex digest of the file at path.
 func hashFile(path string) (string, error) {
 	f, err := os.Open(path)
@@ -158,6 +302,111 @@ func hashFile(path string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// Dir returns dir, or the default cache directory when dir is empty. It does
+// not create the directory.
+func Dir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+
+	return defaultCacheDir()
+}
+
+// Path returns where filename is cached under dir (see Dir) for the index
+// host, as scoped by WithNamespace. It does not check that the file exists.
+func Path(dir, host, filename string) string {
+	return filepath.Join(Dir(dir), namespaceDir(host), filename)
+}
+
+// Info summarizes the wheels stored in a cache directory.
+type Info struct {
+	Files int   // number of cached wheels
+	Size  int64 // their total size in bytes
+}
+
+// Stat reports the wheels under dir, including index namespaces. A missing
+// directory is an empty cache.
+func Stat(dir string) (Info, error) {
+	var info Info
+
+	err := walkWheels(dir, func(_ string, d fs.DirEntry) error {
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		info.Files++
+		info.Size += fi.Size()
+
+		return nil
+	})
+
+	return info, err
+}
+
+// Clear removes every cached wheel under dir, including index namespaces,
+// and returns how many were removed. Directories and any other files are
+// left in place; a missing directory is not an error.
+func Clear(dir string) (int, error) {
+	removed := 0
+
+	err := walkWheels(dir, func(path string, _ fs.DirEntry) error {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+
+		removed++
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// walkWheels calls fn for each .whl file where pipg stores wheels: directly
+// in dir and in its index namespace directories, one level down. Deeper
+// files are never touched, so a --cache-dir that also holds other trees
+// cannot lose their wheels.
+func walkWheels(dir string, fn func(path string, d fs.DirEntry) error) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("reading cache directory %s: %w", dir, err)
+	}
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+
+		if !e.IsDir() {
+			if filepath.Ext(e.Name()) == ".whl" {
+				if err := fn(path, e); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		namespaced, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("reading cache directory %s: %w", path, err)
+		}
+
+		for _, n := range namespaced {
+			if !n.IsDir() && filepath.Ext(n.Name()) == ".whl" {
+				if err := fn(filepath.Join(path, n.Name()), n); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 // defaultCacheDir returns the platform-appropriate cache directory.
 // Priority: PIPG_CACHE_DIR > platform default.
 func defaultCacheDir() string {
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/bilusteknoloji/pipg/internal/cache"
 )
@@ -337,3 +338,265 @@ func TestNewWithEnvVar(t *testing.T) {
 		t.Errorf("file not found in PIPG_CACHE_DIR: %v", err)
 	}
 }
+
+func TestNamespacesKeepSameFilenameDistinct(t *testing.T) {
+	dir := t.TempDir()
+	filename := "pkg-1.0-py3-none-any.whl"
+
+	contentA := []byte("wheel from mirror A")
+	contentB := []byte("wheel from mirror B")
+
+	mirrorA, err := cache.New(cache.WithDir(dir), cache.WithNamespace("mirror-a.example.com"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	mirrorB, err := cache.New(cache.WithDir(dir), cache.WithNamespace("mirror-b.example.com:8443"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	srcA := filepath.Join(t.TempDir(), filename)
+	writeFile(t, srcA, contentA)
+
+	if err := mirrorA.Put(srcA, filename); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	if _, ok := mirrorB.Get(filename, ""); ok {
+		t.Fatal("mirror B served a wheel cached from mirror A")
+	}
+
+	srcB := filepath.Join(t.TempDir(), filename)
+	writeFile(t, srcB, contentB)
+
+	if err := mirrorB.Put(srcB, filename); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	pathA, ok := mirrorA.Get(filename, sha256Hex(contentA))
+	if !ok {
+		t.Fatal("expected mirror A cache hit")
+	}
+
+	pathB, ok := mirrorB.Get(filename, sha256Hex(contentB))
+	if !ok {
+		t.Fatal("expected mirror B cache hit")
+	}
+
+	if pathA == pathB {
+		t.Errorf("namespaces share path %q", pathA)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, filename)); !os.IsNotExist(err) {
+		t.Errorf("namespaced Put wrote to the shared cache root")
+	}
+}
+
+func TestEmptyNamespaceUsesSharedDir(t *testing.T) {
+	dir := t.TempDir()
+	filename := "pkg-1.0-py3-none-any.whl"
+
+	writeFile(t, filepath.Join(dir, filename), []byte("shared"))
+
+	m, err := cache.New(cache.WithDir(dir), cache.WithNamespace(""))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if _, ok := m.Get(filename, ""); !ok {
+		t.Error("expected hit in the shared cache directory")
+	}
+}
+
+func TestStatAndClear(t *testing.T) {
+	dir := t.TempDir()
+	namespaced := filepath.Join(dir, "mirror.example.com")
+
+	if err := os.MkdirAll(namespaced, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(dir, "a-1.0-py3-none-any.whl"), []byte("aaaa"))
+	writeFile(t, filepath.Join(namespaced, "b-1.0-py3-none-any.whl"), []byte("bbbbbb"))
+	writeFile(t, filepath.Join(dir, "notes.txt"), []byte("keep me"))
+
+	// Wheels deeper than a namespace directory are not pipg's.
+	nested := filepath.Join(namespaced, "project", "dist", "c-1.0-py3-none-any.whl")
+	if err := os.MkdirAll(filepath.Dir(nested), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, nested, []byte("cc"))
+
+	info, err := cache.Stat(dir)
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+
+	if info.Files != 2 || info.Size != 10 {
+		t.Errorf("Stat() = %+v, want 2 files, 10 bytes", info)
+	}
+
+	removed, err := cache.Clear(dir)
+	if err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+
+	if removed != 2 {
+		t.Errorf("Clear() removed %d, want 2", removed)
+	}
+
+	for _, keep := range []string{dir, namespaced, filepath.Join(dir, "notes.txt"), nested} {
+		if _, err := os.Stat(keep); err != nil {
+			t.Errorf("Clear() removed %s: %v", keep, err)
+		}
+	}
+
+	if info, _ := cache.Stat(dir); info.Files != 0 {
+		t.Errorf("Stat() after Clear() = %+v, want empty", info)
+	}
+}
+
+func TestStatAndClearMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+
+	if info, err := cache.Stat(dir); err != nil || info.Files != 0 {
+		t.Errorf("Stat() = %+v, %v; want empty cache", info, err)
+	}
+
+	if removed, err := cache.Clear(dir); err != nil || removed != 0 {
+		t.Errorf("Clear() = %d, %v; want 0, nil", removed, err)
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Clear() created the missing cache directory")
+	}
+}
+
+func TestMaxSizeEvictsLeastRecentlyUsed(t *testing.T) {
+	srcDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	data := make([]byte, 100)
+	srcPath := filepath.Join(srcDir, "download.whl")
+
+	writeFile(t, srcPath, data)
+
+	// Each step is a separate run: a Manager never evicts what it handed out.
+	run := func() *cache.Manager {
+		t.Helper()
+
+		m, err := cache.New(cache.WithDir(cacheDir), cache.WithMaxSize(250))
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+
+		return m
+	}
+
+	put := func(filename string, age time.Duration) {
+		t.Helper()
+
+		if putErr := run().Put(srcPath, filename); putErr != nil {
+			t.Fatalf("Put(%s) error: %v", filename, putErr)
+		}
+
+		mtime := time.Now().Add(-age)
+		if chErr := os.Chtimes(filepath.Join(cacheDir, filename), mtime, mtime); chErr != nil {
+			t.Fatal(chErr)
+		}
+	}
+
+	put("a-1.0-py3-none-any.whl", 2*time.Hour)
+	put("b-1.0-py3-none-any.whl", time.Hour)
+
+	// Using a makes b the least recently used wheel.
+	if _, ok := run().Get("a-1.0-py3-none-any.whl", sha256Hex(data)); !ok {
+		t.Fatal("expected cache hit for a")
+	}
+
+	put("c-1.0-py3-none-any.whl", 0)
+
+	if _, statErr := os.Stat(filepath.Join(cacheDir, "b-1.0-py3-none-any.whl")); !os.IsNotExist(statErr) {
+		t.Errorf("least recently used wheel should be evicted, stat error: %v", statErr)
+	}
+
+	for _, name := range []string{"a-1.0-py3-none-any.whl", "c-1.0-py3-none-any.whl"} {
+		if _, statErr := os.Stat(filepath.Join(cacheDir, name)); statErr != nil {
+			t.Errorf("%s should remain cached: %v", name, statErr)
+		}
+	}
+}
+
+func TestMaxSizeKeepsWheelsHandedOut(t *testing.T) {
+	srcDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	data := make([]byte, 100)
+	srcPath := filepath.Join(srcDir, "download.whl")
+
+	writeFile(t, srcPath, data)
+
+	m, err := cache.New(cache.WithDir(cacheDir), cache.WithMaxSize(150))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := m.Put(srcPath, "a-1.0-py3-none-any.whl"); err != nil {
+		t.Fatalf("Put(a) error: %v", err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(cacheDir, "a-1.0-py3-none-any.whl"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Put(srcPath, "b-1.0-py3-none-any.whl"); err != nil {
+		t.Fatalf("Put(b) error: %v", err)
+	}
+
+	// The same run still holds a, so it stays even though the cache is over.
+	if _, err := os.Stat(filepath.Join(cacheDir, "a-1.0-py3-none-any.whl")); err != nil {
+		t.Errorf("wheel stored earlier in the run was evicted: %v", err)
+	}
+}
+
+func TestMaxSizeEvictsAcrossNamespaces(t *testing.T) {
+	srcDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	data := make([]byte, 100)
+	srcPath := filepath.Join(srcDir, "download.whl")
+
+	writeFile(t, srcPath, data)
+
+	mirror, err := cache.New(cache.WithDir(cacheDir), cache.WithNamespace("mirror.example.com"))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := mirror.Put(srcPath, "a-1.0-py3-none-any.whl"); err != nil {
+		t.Fatalf("Put(a) error: %v", err)
+	}
+
+	mirrorWheel := cache.Path(cacheDir, "mirror.example.com", "a-1.0-py3-none-any.whl")
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(mirrorWheel, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := cache.New(cache.WithDir(cacheDir), cache.WithMaxSize(150))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := shared.Put(srcPath, "b-1.0-py3-none-any.whl"); err != nil {
+		t.Fatalf("Put(b) error: %v", err)
+	}
+
+	if _, err := os.Stat(mirrorWheel); !os.IsNotExist(err) {
+		t.Errorf("wheel in another namespace should be evicted, stat error: %v", err)
+	}
+}
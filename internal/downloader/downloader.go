@@ -3,6 +3,7 @@ package downloader
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -10,16 +11,35 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
 )
 
-const maxRetries = 3
+const (
+	// defaultAttempts is how many times a download is tried before giving up.
+	defaultAttempts = 3
+
+	// defaultBackoffBase scales the exponential backoff: the wait before
+	// attempt n is 2^n times the base.
+	defaultBackoffBase = 500 * time.Millisecond
+
+	// defaultMaxPerHost caps concurrent requests to one host, as browsers do.
+	defaultMaxPerHost = 6
+
+	// progressInterval is the least time between progress reports for one
+	// download, so the callback does not run for every buffer read.
+	progressInterval = 100 * time.Millisecond
+)
 
 // retryableError wraps errors that are transient and can be retried.
 type retryableError struct {
@@ -29,6 +49,16 @@ type retryableError struct {
 func (e *retryableError) Error() string { return e.err.Error() }
 func (e *retryableError) Unwrap() error { return e.err }
 
+// PartialError reports a failed Download along with the number of packages
+// that finished downloading before the failure.
+type PartialError struct {
+	Completed int
+	Err       error
+}
+
+func (e *PartialError) Error() string { return e.Err.Error() }
+func (e *PartialError) Unwrap() error { return e.Err }
+
 // Downloader defines the interface for downloading resolved packages.
 type Downloader interface {
 	Download(ctx context.Context, requests []Request) ([]Result, error)
@@ -39,7 +69,7 @@ type Request struct {
 	Name     string // package name
 	Version  string // resolved version
 	URL      string // direct download URL
-	SHA256   string // expected sha256 hex digest
+	SHA256   string // expected sha256 digest, hex or urlsafe base64
 	Filename string // e.g., "flask-3.0.0-py3-none-any.whl"
 }
 
@@ -55,7 +85,9 @@ type Result struct {
 	Version  string
 	FilePath string // path to the downloaded .whl file
 	Size     int64
-	Cached   bool // true if served from cache
+	Cached   bool   // true if served from the cache, the wheel dir or a local file
+	URL      string // the request URL the wheel was fetched from or stands in for
+	SHA256   string // hex sha256 digest of the file, empty if not known
 }
 
 // Option configures a Manager.
@@ -89,6 +121,174 @@ func WithLogger(l *slog.Logger) Option {
 	}
 }
 
+// WithSemaphore bounds concurrent HTTP requests with a semaphore, which may be
+// shared with other components (e.g., the PyPI client) to cap total in-flight
+// requests. Each download holds one unit until the file is written. Cache hits
+// do not take a unit.
+func WithSemaphore(sem *semaphore.Weighted) Option {
+	return func(m *Manager) {
+		if sem != nil {
+			m.sem = sem
+		}
+	}
+}
+
+// WithMaxPerHost caps the concurrent requests to any one host, independently
+// of the worker limit, so downloads that all come from one file host do not
+// trip its rate limits. Requests are grouped by the host of Request.URL, before
+// any redirect. Values below one are ignored. Defaults to 6.
+func WithMaxPerHost(n int) Option {
+	return func(m *Manager) {
+		if n > 0 {
+			m.maxPerHost = n
+		}
+	}
+}
+
+// WithRetries sets how many times each download is attempted in total. Zero
+// and one both mean a single attempt with no retry; negative values are
+// ignored. Defaults to 3.
+func WithRetries(n int) Option {
+	return func(m *Manager) {
+		if n >= 0 {
+			m.attempts = max(n, 1)
+		}
+	}
+}
+
+// WithBackoffBase sets the base of the exponential backoff between retries:
+// the wait before attempt n is 2^n * d. Non-positive values are ignored.
+// Defaults to 500ms.
+func WithBackoffBase(d time.Duration) Option {
+	return func(m *Manager) {
+		if d > 0 {
+			m.backoff = exponentialBackoff(d)
+		}
+	}
+}
+
+// WithConstantBackoff waits a flat d between retry attempts instead of the
+// default exponential backoff, which suits low-latency local mirrors.
+// Negative durations are ignored.
+func WithConstantBackoff(d time.Duration) Option {
+	return func(m *Manager) {
+		if d >= 0 {
+			m.backoff = func(int) time.Duration { return d }
+		}
+	}
+}
+
+// DigestResolver returns the expected sha256 hex digest for a file URL. It
+// lets indexes that publish hashes separately from file metadata keep
+// downloads verifiable.
+type DigestResolver func(url string) (string, error)
+
+// WithDigestResolver consults fn for the expected digest of any request whose
+// SHA256 is empty, before the download starts.
+func WithDigestResolver(fn DigestResolver) Option {
+	return func(m *Manager) {
+		if fn != nil {
+			m.digestResolver = fn
+		}
+	}
+}
+
+// ProgressFunc receives the bytes written so far for a download and its
+// expected total, or -1 when the server sent no Content-Length.
+type ProgressFunc func(req Request, downloaded, total int64)
+
+// WithProgress reports per-file progress to fn as bytes arrive, at most once
+// per 100ms per file, and once more with downloaded == total when a file
+// completes. Calls for one file are sequential, but workers call fn
+// concurrently, so it must be safe for concurrent use. Cache hits are not
+// reported.
+func WithProgress(fn ProgressFunc) Option {
+	return func(m *Manager) {
+		if fn != nil {
+			m.progress = fn
+		}
+	}
+}
+
+// WithRateLimit caps the combined throughput of all download workers at
+// bytesPerSec. Zero or negative values mean unlimited.
+func WithRateLimit(bytesPerSec int64) Option {
+	return func(m *Manager) {
+		if bytesPerSec > 0 {
+			m.limiter = newRateLimiter(bytesPerSec)
+		}
+	}
+}
+
+// WithWheelDir makes the downloader look in dir before the cache and the
+// network: a file there named like the request, whose sha256 matches when a
+// digest is known, is used in place. Files in dir are never modified.
+func WithWheelDir(dir string) Option {
+	return func(m *Manager) {
+		if dir != "" {
+			m.wheelDir = dir
+		}
+	}
+}
+
+// WithStallTimeout abandons a download attempt when no data arrives for d.
+// The timer starts with the request and restarts on every chunk received, so
+// a large file on a slow link is never cut off while it keeps progressing.
+// A stalled attempt is retried like a dropped connection. Zero or negative
+// values disable it; the HTTP client's own timeout still applies.
+func WithStallTimeout(d time.Duration) Option {
+	return func(m *Manager) {
+		if d > 0 {
+			m.stallTimeout = d
+		}
+	}
+}
+
+// WithRampUp starts downloads with start concurrent workers and admits more
+// evenly over period until the worker limit is reached, so a large worker
+// count does not open every connection, and TLS handshake, at once. A start
+// below one or at the limit, or a non-positive period, disables it.
+func WithRampUp(start int, period time.Duration) Option {
+	return func(m *Manager) {
+		if start > 0 && period > 0 {
+			m.rampStart, m.rampPeriod = start, period
+		}
+	}
+}
+
+// WithCredentials authenticates downloads from private index hosts with c.
+func WithCredentials(c *pypi.Credentials) Option {
+	return func(m *Manager) {
+		if c != nil {
+			m.credentials = c
+		}
+	}
+}
+
+// VerifyMode says what a download does when its sha256 does not match the
+// expected digest.
+type VerifyMode int
+
+const (
+	// VerifyEnforce fails the download on a mismatch. It is the default.
+	VerifyEnforce VerifyMode = iota
+	// VerifyWarn logs a warning on a mismatch and keeps the file. Unsafe: a
+	// tampered file is installed; meant only for debugging a broken index.
+	VerifyWarn
+	// VerifySkip does not compare digests at all. Unsafe, like VerifyWarn.
+	VerifySkip
+)
+
+// WithVerifyMode sets how downloads treat a sha256 mismatch. Unknown modes
+// are ignored, so verification stays enforced.
+func WithVerifyMode(mode VerifyMode) Option {
+	return func(m *Manager) {
+		if mode >= VerifyEnforce && mode <= VerifySkip {
+			m.verifyMode = mode
+		}
+	}
+}
+
 // WithCache sets the wheel cache for avoiding redundant downloads.
 func WithCache(c Cache) Option {
 	return func(m *Manager) {
@@ -103,6 +303,25 @@ type Manager struct {
 	httpClient *http.Client
 	logger     *slog.Logger
 	cache      Cache
+	sem        *semaphore.Weighted
+	backoff    func(attempt int) time.Duration
+	attempts   int
+	after      func(d time.Duration) <-chan time.Time // time.After, replaced in tests
+
+	digestResolver DigestResolver
+	limiter        *rateLimiter // shared by all workers; nil means unlimited
+	wheelDir       string
+	credentials    *pypi.Credentials
+	stallTimeout   time.Duration
+	verifyMode     VerifyMode
+	rampStart      int
+	rampPeriod     time.Duration
+
+	maxPerHost int
+	hostMu     sync.Mutex // guards hostSems
+	hostSems   map[string]*semaphore.Weighted
+
+	progress ProgressFunc
 }
 
 // compile-time proof that Manager implements Downloader.
@@ -115,6 +334,11 @@ func New(targetDir string, opts ...Option) *Manager {
 		maxWorkers: runtime.GOMAXPROCS(0),
 		httpClient: &http.Client{},
 		logger:     slog.Default(),
+		backoff:    exponentialBackoff(defaultBackoffBase),
+		attempts:   defaultAttempts,
+		after:      time.After,
+		maxPerHost: defaultMaxPerHost,
+		hostSems:   make(map[string]*semaphore.Weighted),
 	}
 
 	for _, opt := range opts {
@@ -125,8 +349,11 @@ func New(targetDir string, opts ...Option) *Manager {
 }
 
 // Download downloads all requested packages concurrently.
-// Each download verifies the SHA256 hash against the expected digest.
-// Returns the list of downloaded files or the first error encountered.
+// Each download verifies the SHA256 hash against the expected digest. Cache
+// hits are verified inside the worker pool too, so hashing distinct cached
+// files overlaps instead of running one after another.
+// Returns the list of downloaded files or the first error encountered, wrapped
+// in a *PartialError.
 func (m *Manager) Download(ctx context.Context, requests []Request) ([]Result, error) {
 	results := make([]Result, len(requests))
 
@@ -135,8 +362,45 @@ func (m *Manager) Download(ctx context.Context, requests []Request) ([]Result, e
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(m.maxWorkers)
 
+	var ramp *rampGate
+	if limit := m.rampLimit(requests); m.rampStart > 0 && m.rampStart < limit {
+		ramp = newRampGate(ctx, m.rampStart, limit, m.rampPeriod, m.after)
+	}
+
 	for i, req := range requests {
 		g.Go(func() error {
+			if req.SHA256 == "" && m.digestResolver != nil {
+				digest, err := m.digestResolver(req.URL)
+				if err != nil {
+					return fmt.Errorf("resolving digest for %s: %w", req.Name, err)
+				}
+
+				req.SHA256 = digest
+			}
+
+			req.SHA256 = NormalizeDigest("sha256", req.SHA256)
+
+			if result, ok := m.fromWheelDir(req); ok {
+				mu.Lock()
+				results[i] = result
+				mu.Unlock()
+
+				return nil
+			}
+
+			if path, ok := pypi.LocalPath(req.URL); ok {
+				result, err := m.fromLocalFile(req, path)
+				if err != nil {
+					return fmt.Errorf("downloading %s: %w", req.Name, err)
+				}
+
+				mu.Lock()
+				results[i] = result
+				mu.Unlock()
+
+				return nil
+			}
+
 			// Check cache first.
 			if m.cache != nil {
 				if cachedPath, ok := m.cache.Get(req.Filename, req.SHA256); ok {
@@ -149,6 +413,8 @@ func (m *Manager) Download(ctx context.Context, requests []Request) ([]Result, e
 							FilePath: cachedPath,
 							Size:     info.Size(),
 							Cached:   true,
+							URL:      req.URL,
+							SHA256:   req.SHA256,
 						}
 						mu.Unlock()
 
@@ -157,7 +423,14 @@ func (m *Manager) Download(ctx context.Context, requests []Request) ([]Result, e
 				}
 			}
 
-			m.logger.Debug("downloading", slog.String("package", req.Name), slog.String("url", req.URL))
+			m.logger.Debug("downloading", slog.String("package", req.Name), slog.String("url", pypi.RedactURL(req.URL)))
+
+			if ramp != nil {
+				if err := ramp.acquire(ctx); err != nil {
+					return fmt.Errorf("downloading %s: %w", req.Name, err)
+				}
+				defer ramp.release()
+			}
 
 			result, err := m.downloadWithRetry(ctx, req)
 			if err != nil {
@@ -188,20 +461,134 @@ func (m *Manager) Download(ctx context.Context, requests []Request) ([]Result, e
 	}
 
 	if err := g.Wait(); err != nil {
-		return nil, err
+		completed := 0
+
+		for _, r := range results {
+			if r.FilePath != "" {
+				completed++
+			}
+		}
+
+		return nil, &PartialError{Completed: completed, Err: err}
 	}
 
 	return results, nil
 }
 
-// downloadWithRetry attempts to download a file up to maxRetries times
-// with exponential backoff between attempts.
+// fromWheelDir returns the request's file from the wheel directory, if it is
+// there and matches the expected digest.
+func (m *Manager) fromWheelDir(req Request) (Result, bool) {
+	if m.wheelDir == "" {
+		return Result{}, false
+	}
+
+	path := filepath.Join(m.wheelDir, req.Filename)
+
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return Result{}, false
+	}
+
+	if req.SHA256 != "" {
+		got, err := fileSHA256(path)
+		if err != nil || got != req.SHA256 {
+			m.logger.Debug("wheel dir file does not match, downloading", slog.String("file", path))
+
+			return Result{}, false
+		}
+	}
+
+	m.logger.Debug("using wheel from wheel dir", slog.String("file", path))
+
+	return Result{
+		Name:     req.Name,
+		Version:  req.Version,
+		FilePath: path,
+		Size:     info.Size(),
+		Cached:   true,
+		URL:      req.URL,
+		SHA256:   req.SHA256,
+	}, true
+}
+
+// fromLocalFile serves a file:// request, such as a wheel from a find-links
+// directory, from its place on disk without copying it. The file is verified
+// against the expected digest like a download.
+func (m *Manager) fromLocalFile(req Request, path string) (Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("opening local wheel: %w", err)
+	}
+
+	got, err := fileSHA256(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("hashing %s: %w", path, err)
+	}
+
+	if req.SHA256 != "" && got != req.SHA256 {
+		switch m.verifyMode {
+		case VerifyEnforce:
+			return Result{}, fmt.Errorf("sha256 mismatch for %s: expected %s, got %s", path, req.SHA256, got)
+		case VerifyWarn:
+			m.logger.Warn("sha256 mismatch, keeping file because verification is set to warn",
+				slog.String("file", path),
+				slog.String("expected", req.SHA256),
+				slog.String("got", got))
+		case VerifySkip:
+		}
+	}
+
+	m.logger.Debug("using local wheel", slog.String("file", path))
+
+	return Result{
+		Name:     req.Name,
+		Version:  req.Version,
+		FilePath: path,
+		Size:     info.Size(),
+		Cached:   true,
+		URL:      req.URL,
+		SHA256:   got,
+	}, nil
+}
+
+// fileSHA256 returns the sha256 hex digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// exponentialBackoff returns a backoff of 2^attempt * base.
+func exponentialBackoff(base time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		return time.Duration(math.Pow(2, float64(attempt))) * base
+	}
+}
+
+// downloadWithRetry attempts to download a file up to m.attempts times
+// with backoff between attempts, exponential unless WithConstantBackoff is set.
+// A connection dropped mid-body is resumed from the partial file when the
+// server advertises byte ranges.
 func (m *Manager) downloadWithRetry(ctx context.Context, req Request) (Result, error) {
 	var lastErr error
 
-	for attempt := range maxRetries {
+	partPath := filepath.Join(m.targetDir, req.Filename) + ".part"
+	_ = os.Remove(partPath) // never resume a leftover from an earlier run
+
+	var resumable bool
+
+	for attempt := range m.attempts {
 		if attempt > 0 {
-			backoff := time.Duration(math.Pow(2, float64(attempt))) * 500 * time.Millisecond
+			backoff := max(m.backoff(attempt), pypi.RetryAfter(lastErr))
 			m.logger.Debug("retrying download",
 				slog.String("package", req.Name),
 				slog.Int("attempt", attempt+1),
@@ -210,12 +597,14 @@ func (m *Manager) downloadWithRetry(ctx context.Context, req Request) (Result, e
 
 			select {
 			case <-ctx.Done():
+				_ = os.Remove(partPath)
+
 				return Result{}, fmt.Errorf("download canceled: %w", ctx.Err())
-			case <-time.After(backoff):
+			case <-m.after(backoff):
 			}
 		}
 
-		result, err := m.doDownload(ctx, req)
+		result, err := m.doDownload(ctx, req, &resumable)
 		if err == nil {
 			return result, nil
 		}
@@ -224,6 +613,8 @@ func (m *Manager) downloadWithRetry(ctx context.Context, req Request) (Result, e
 		// (4xx, sha256 mismatch) fail immediately.
 		var re *retryableError
 		if !errors.As(err, &re) {
+			_ = os.Remove(partPath)
+
 			return Result{}, err
 		}
 
@@ -235,24 +626,81 @@ func (m *Manager) downloadWithRetry(ctx context.Context, req Request) (Result, e
 		)
 	}
 
-	return Result{}, fmt.Errorf("after %d attempts: %w", maxRetries, lastErr)
+	_ = os.Remove(partPath)
+
+	return Result{}, fmt.Errorf("after %d attempts: %w", m.attempts, lastErr)
 }
 
-// doDownload performs a single download: HTTP GET → temp file → verify hash → rename.
-func (m *Manager) doDownload(ctx context.Context, req Request) (Result, error) {
+// doDownload performs a single download: HTTP GET → .part file → verify hash
+// → rename. When *resumable is set and a partial file exists, only the
+// missing bytes are requested; a 200 reply restarts from zero. *resumable is
+// updated from the server's Accept-Ranges header.
+func (m *Manager) doDownload(ctx context.Context, req Request, resumable *bool) (Result, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
 	if err != nil {
 		return Result{}, fmt.Errorf("creating request: %w", err)
 	}
 
+	destPath := filepath.Join(m.targetDir, req.Filename)
+	partPath := destPath + ".part"
+
+	var offset int64
+	if *resumable {
+		if info, err := os.Stat(partPath); err == nil {
+			offset = info.Size()
+		}
+	}
+
+	if offset > 0 {
+		httpReq.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	m.credentials.Apply(httpReq)
+
+	// Take the host slot first, so a request waiting on a busy host does not
+	// hold a shared slot that requests to other hosts could use.
+	hostSem := m.hostSemaphore(httpReq.URL.Host)
+	if err := hostSem.Acquire(ctx, 1); err != nil {
+		return Result{}, fmt.Errorf("waiting for a request slot on %s: %w", httpReq.URL.Host, err)
+	}
+	defer hostSem.Release(1)
+
+	if m.sem != nil {
+		if err := m.sem.Acquire(ctx, 1); err != nil {
+			return Result{}, fmt.Errorf("waiting for a request slot: %w", err)
+		}
+		defer m.sem.Release(1)
+	}
+
+	var stall *time.Timer
+	if m.stallTimeout > 0 {
+		stall = time.AfterFunc(m.stallTimeout, func() {
+			cancel(fmt.Errorf("%w: no data received for %s", errStalled, m.stallTimeout))
+		})
+		defer stall.Stop()
+	}
+
 	resp, err := m.httpClient.Do(httpReq)
 	if err != nil {
 		// Network errors are transient and retryable.
-		return Result{}, &retryableError{err: fmt.Errorf("requesting %s: %w", req.URL, err)}
+		return Result{}, &retryableError{err: fmt.Errorf("requesting %s: %w", req.URL, stallCause(ctx, err))}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// Resuming: the body holds the bytes after offset.
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file no longer lines up with the remote; start over.
+		*resumable = false
+
+		return Result{}, &retryableError{err: fmt.Errorf("cannot resume %s: status %d", req.URL, resp.StatusCode)}
+	default:
 		statusErr := fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL)
 
 		// 5xx errors are transient; 4xx are permanent.
@@ -260,54 +708,235 @@ func (m *Manager) doDownload(ctx context.Context, req Request) (Result, error) {
 			return Result{}, &retryableError{err: statusErr}
 		}
 
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+			err := pypi.DeniedError(resp, req.URL)
+
+			var rl *pypi.RateLimitError
+			if errors.As(err, &rl) {
+				return Result{}, &retryableError{err: err}
+			}
+
+			return Result{}, err
+		}
+
 		return Result{}, statusErr
 	}
 
-	destPath := filepath.Join(m.targetDir, req.Filename)
-	tmpPath := destPath + ".tmp"
+	*resumable = resp.StatusCode == http.StatusPartialContent || resp.Header.Get("Accept-Ranges") == "bytes"
 
-	f, err := os.Create(tmpPath)
+	// The hash always covers the whole file: a resumed download first hashes
+	// the bytes already on disk.
+	h := sha256.New()
+
+	f, err := openPart(partPath, offset, h)
 	if err != nil {
-		return Result{}, fmt.Errorf("creating temp file: %w", err)
+		return Result{}, err
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
 	}
 
 	// Stream to file and hash simultaneously.
-	h := sha256.New()
-	size, copyErr := io.Copy(io.MultiWriter(f, h), resp.Body)
+	writers := []io.Writer{f, h}
+	if m.progress != nil {
+		writers = append(writers, &progressWriter{m: m, req: req, written: offset, total: total})
+	}
+
+	var body io.Reader = resp.Body
+	if m.limiter != nil {
+		body = &limitedReader{ctx: ctx, r: resp.Body, limiter: m.limiter}
+	}
+
+	if stall != nil {
+		body = &stallReader{r: body, timer: stall, timeout: m.stallTimeout}
+	}
+
+	n, copyErr := io.Copy(io.MultiWriter(writers...), body)
 
 	// Always close the file before handling errors.
 	if err := f.Close(); err != nil && copyErr == nil {
-		copyErr = fmt.Errorf("closing temp file: %w", err)
+		copyErr = fmt.Errorf("closing partial file: %w", err)
 	}
 
 	if copyErr != nil {
-		_ = os.Remove(tmpPath)
-
-		return Result{}, fmt.Errorf("writing %s: %w", req.Filename, copyErr)
+		// Keep what arrived so the next attempt can resume from it.
+		return Result{}, &retryableError{err: fmt.Errorf("writing %s: %w", req.Filename, stallCause(ctx, copyErr))}
 	}
 
+	got := hex.EncodeToString(h.Sum(nil))
+
 	// Verify SHA256 hash.
-	if req.SHA256 != "" {
-		got := hex.EncodeToString(h.Sum(nil))
-		if got != req.SHA256 {
-			_ = os.Remove(tmpPath)
+	if req.SHA256 != "" && got != req.SHA256 {
+		switch m.verifyMode {
+		case VerifyEnforce:
+			_ = os.Remove(partPath)
 
 			return Result{}, fmt.Errorf("sha256 mismatch for %s: expected %s, got %s",
 				req.Filename, req.SHA256, got)
+		case VerifyWarn:
+			m.logger.Warn("sha256 mismatch, keeping file because verification is set to warn",
+				slog.String("file", req.Filename),
+				slog.String("expected", req.SHA256),
+				slog.String("got", got))
+		case VerifySkip:
 		}
 	}
 
 	// Rename to final path.
-	if err := os.Rename(tmpPath, destPath); err != nil {
-		_ = os.Remove(tmpPath)
+	if err := os.Rename(partPath, destPath); err != nil {
+		_ = os.Remove(partPath)
 
 		return Result{}, fmt.Errorf("renaming %s: %w", req.Filename, err)
 	}
 
+	m.reportProgress(req, offset+n, offset+n)
+
 	return Result{
 		Name:     req.Name,
 		Version:  req.Version,
 		FilePath: destPath,
-		Size:     size,
+		Size:     offset + n,
+		URL:      req.URL,
+		SHA256:   got,
 	}, nil
 }
+
+// errStalled reports a download attempt abandoned by WithStallTimeout.
+var errStalled = errors.New("download stalled")
+
+// stallCause returns the stall error when the stall timer canceled ctx, which
+// says more than the "context canceled" err it caused; otherwise err.
+func stallCause(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errStalled) {
+		return cause
+	}
+
+	return err
+}
+
+// stallReader restarts the stall timer whenever data arrives.
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+
+	return n, err
+}
+
+// rampLimit returns the concurrency the ramp grows to: the worker limit, or
+// the per-host cap times the number of hosts requests come from when that is
+// lower, since growing past it would only queue workers on host semaphores.
+func (m *Manager) rampLimit(requests []Request) int {
+	hosts := make(map[string]bool)
+	for _, req := range requests {
+		if u, err := url.Parse(req.URL); err == nil {
+			hosts[u.Host] = true
+		}
+	}
+
+	return min(m.maxWorkers, m.maxPerHost*max(len(hosts), 1))
+}
+
+// hostSemaphore returns the semaphore bounding concurrent requests to host,
+// creating it on first use.
+func (m *Manager) hostSemaphore(host string) *semaphore.Weighted {
+	m.hostMu.Lock()
+	defer m.hostMu.Unlock()
+
+	sem, ok := m.hostSems[host]
+	if !ok {
+		sem = semaphore.NewWeighted(int64(m.maxPerHost))
+		m.hostSems[host] = sem
+	}
+
+	return sem
+}
+
+// reportProgress calls the progress callback, if any.
+func (m *Manager) reportProgress(req Request, downloaded, total int64) {
+	if m.progress != nil {
+		m.progress(req, downloaded, total)
+	}
+}
+
+// progressWriter reports the running byte count of a download as it is
+// written, at most once per progressInterval.
+type progressWriter struct {
+	m        *Manager
+	req      Request
+	written  int64
+	total    int64
+	reported time.Time
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+
+	if now := time.Now(); now.Sub(w.reported) >= progressInterval {
+		w.reported = now
+		w.m.reportProgress(w.req, w.written, w.total)
+	}
+
+	return len(p), nil
+}
+
+// openPart opens the partial file for writing. With a zero offset the file is
+// truncated; otherwise its first offset bytes are fed to h and writes append.
+func openPart(path string, offset int64, h io.Writer) (*os.File, error) {
+	if offset == 0 {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("creating partial file: %w", err)
+		}
+
+		return f, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening partial file: %w", err)
+	}
+
+	if _, err := io.CopyN(h, f, offset); err != nil {
+		_ = f.Close()
+
+		return nil, fmt.Errorf("hashing partial file: %w", err)
+	}
+
+	return f, nil
+}
+
+// digestSizes maps hash algorithms to their digest length in bytes.
+var digestSizes = map[string]int{
+	"sha256": sha256.Size,
+}
+
+// NormalizeDigest canonicalizes a digest to lowercase hex. Values may be hex,
+// as pip's --hash uses, or urlsafe base64 with or without padding, as wheel
+// RECORD files use. Anything that decodes to the wrong length for algo is
+// returned unchanged so verification reports it as a mismatch.
+func NormalizeDigest(algo, value string) string {
+	size, ok := digestSizes[algo]
+	if !ok || value == "" {
+		return value
+	}
+
+	if b, err := hex.DecodeString(value); err == nil && len(b) == size {
+		return hex.EncodeToString(b)
+	}
+
+	if b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "=")); err == nil && len(b) == size {
+		return hex.EncodeToString(b)
+	}
+
+	return value
+}
@@ -0,0 +1,202 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConstantBackoffDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	m := New(t.TempDir(), WithHTTPClient(srv.Client()), WithConstantBackoff(50*time.Millisecond))
+
+	// Fake clock: record each requested delay and fire immediately.
+	var delays []time.Duration
+
+	m.after = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+
+		return ch
+	}
+
+	_, err := m.downloadWithRetry(context.Background(), Request{
+		Name:     "pkg",
+		URL:      srv.URL + "/pkg.whl",
+		Filename: "pkg-1.0.0-py3-none-any.whl",
+	})
+	if err == nil {
+		t.Fatal("expected error after retries exhausted, got nil")
+	}
+
+	want := []time.Duration{50 * time.Millisecond, 50 * time.Millisecond}
+	if !slices.Equal(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+}
+
+func TestExponentialBackoffDefault(t *testing.T) {
+	m := New(t.TempDir())
+
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second} {
+		if got := m.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestBackoffBase(t *testing.T) {
+	m := New(t.TempDir(), WithBackoffBase(100*time.Millisecond))
+
+	for attempt, want := range map[int]time.Duration{1: 200 * time.Millisecond, 2: 400 * time.Millisecond} {
+		if got := m.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestNormalizeDigest(t *testing.T) {
+	const hexDigest = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "hex", value: hexDigest, want: hexDigest},
+		{name: "uppercase hex", value: "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824", want: hexDigest},
+		{name: "urlsafe base64", value: "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ", want: hexDigest},
+		{name: "padded urlsafe base64", value: "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=", want: hexDigest},
+		{name: "wrong length", value: "abcd", want: "abcd"},
+		{name: "empty", value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeDigest("sha256", tt.value); got != tt.want {
+				t.Errorf("NormalizeDigest(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRampUpLimitsInitialConnections(t *testing.T) {
+	const maxWorkers, start = 6, 2
+
+	var (
+		mu       sync.Mutex
+		inFlight int
+		peak     int
+	)
+
+	release := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		_, _ = w.Write([]byte("wheel"))
+	}))
+	t.Cleanup(srv.Close)
+
+	m := New(t.TempDir(),
+		WithHTTPClient(srv.Client()),
+		WithMaxWorkers(maxWorkers),
+		WithRampUp(start, time.Second),
+		WithVerifyMode(VerifySkip),
+	)
+
+	// Fake clock that never fires: the ramp stays at its start.
+	m.after = func(time.Duration) <-chan time.Time { return nil }
+
+	reqs := make([]Request, maxWorkers)
+	for i := range reqs {
+		reqs[i] = Request{
+			Name:     fmt.Sprintf("pkg%d", i),
+			URL:      fmt.Sprintf("%s/pkg%d.whl", srv.URL, i),
+			Filename: fmt.Sprintf("pkg%d-1.0.0-py3-none-any.whl", i),
+		}
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := m.Download(context.Background(), reqs)
+		done <- err
+	}()
+
+	inFlightNow := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return inFlight
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for inFlightNow() < start && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Give any workers past the ramp a chance to connect.
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	gotPeak := peak
+	mu.Unlock()
+
+	if gotPeak != start {
+		t.Errorf("peak concurrent connections = %d, want %d (max %d)", gotPeak, start, maxWorkers)
+	}
+
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Download() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Download() did not finish after the handlers were released")
+	}
+}
+
+func TestRampLimitCappedPerHost(t *testing.T) {
+	m := New(t.TempDir(), WithMaxWorkers(16), WithMaxPerHost(3))
+
+	oneHost := []Request{
+		{URL: "https://files.example.com/a.whl"},
+		{URL: "https://files.example.com/b.whl"},
+	}
+	if got := m.rampLimit(oneHost); got != 3 {
+		t.Errorf("rampLimit(one host) = %d, want 3", got)
+	}
+
+	twoHosts := append(oneHost, Request{URL: "https://mirror.example.com/c.whl"})
+	if got := m.rampLimit(twoHosts); got != 6 {
+		t.Errorf("rampLimit(two hosts) = %d, want 6", got)
+	}
+
+	m = New(t.TempDir(), WithMaxWorkers(4), WithMaxPerHost(3))
+	if got := m.rampLimit(twoHosts); got != 4 {
+		t.Errorf("rampLimit() = %d, want the worker limit 4", got)
+	}
+}
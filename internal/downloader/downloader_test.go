@@ -1,17 +1,24 @@
 package downloader_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/bilusteknoloji/pipg/internal/cache"
 	"github.com/bilusteknoloji/pipg/internal/downloader"
 )
 
@@ -164,12 +171,53 @@ func TestDownloadSHA256Mismatch(t *testing.T) {
 	// Verify temp file was cleaned up.
 	entries, _ := os.ReadDir(dir)
 	for _, e := range entries {
-		if filepath.Ext(e.Name()) == ".tmp" {
+		if filepath.Ext(e.Name()) == ".part" {
 			t.Errorf("temp file %q was not cleaned up", e.Name())
 		}
 	}
 }
 
+func TestDownloadSHA256MismatchWarnMode(t *testing.T) {
+	content := []byte("actual content")
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+
+	var logs bytes.Buffer
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		downloader.WithVerifyMode(downloader.VerifyWarn),
+	)
+
+	results, err := mgr.Download(context.Background(), []downloader.Request{
+		{
+			Name:     "badpkg",
+			Version:  "1.0.0",
+			URL:      srv.URL + "/badpkg.whl",
+			SHA256:   "0000000000000000000000000000000000000000000000000000000000000000",
+			Filename: "badpkg-1.0.0-py3-none-any.whl",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	if data, err := os.ReadFile(results[0].FilePath); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("downloaded file = %q, %v; want %q", data, err, content)
+	}
+
+	if results[0].SHA256 != sha256Hex(content) {
+		t.Errorf("result SHA256 = %q, want the computed digest", results[0].SHA256)
+	}
+
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "sha256 mismatch") {
+		t.Errorf("expected a sha256 mismatch warning, got logs:\n%s", logs.String())
+	}
+}
+
 func TestDownloadEmptySHA256Skips(t *testing.T) {
 	content := []byte("some content no hash check")
 
@@ -196,6 +244,244 @@ func TestDownloadEmptySHA256Skips(t *testing.T) {
 	if len(results) != 1 {
 		t.Fatalf("expected 1 result, got %d", len(results))
 	}
+
+	// The digest is still computed for the installer's direct_url.json.
+	if results[0].SHA256 != sha256Hex(content) || results[0].URL != srv.URL+"/nohash.whl" {
+		t.Errorf("result source = %q %q, want the request URL and computed digest", results[0].URL, results[0].SHA256)
+	}
+}
+
+func TestDownloadSHA256EitherEncoding(t *testing.T) {
+	content := []byte("wheel bytes")
+	sum := sha256.Sum256(content)
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+
+	for name, digest := range map[string]string{
+		"hex":            hex.EncodeToString(sum[:]),
+		"urlsafe base64": base64.RawURLEncoding.EncodeToString(sum[:]),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mgr := downloader.New(t.TempDir(), downloader.WithHTTPClient(srv.Client()))
+
+			_, err := mgr.Download(context.Background(), []downloader.Request{
+				{
+					Name:     "pkg",
+					Version:  "1.0.0",
+					URL:      srv.URL + "/pkg.whl",
+					SHA256:   digest,
+					Filename: "pkg-1.0.0-py3-none-any.whl",
+				},
+			})
+			if err != nil {
+				t.Fatalf("Download() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadDigestResolver(t *testing.T) {
+	content := []byte("hash served separately")
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+
+	req := downloader.Request{
+		Name:     "splithash",
+		Version:  "1.0.0",
+		URL:      srv.URL + "/splithash.whl",
+		Filename: "splithash-1.0.0-py3-none-any.whl",
+	}
+
+	var asked string
+
+	resolve := func(digest string) downloader.DigestResolver {
+		return func(url string) (string, error) {
+			asked = url
+
+			return digest, nil
+		}
+	}
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithDigestResolver(resolve(sha256Hex(content))),
+	)
+
+	if _, err := mgr.Download(context.Background(), []downloader.Request{req}); err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	if asked != req.URL {
+		t.Errorf("digest resolver asked for %q, want %q", asked, req.URL)
+	}
+
+	mgr = downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithDigestResolver(resolve(sha256Hex([]byte("other")))),
+	)
+
+	_, err := mgr.Download(context.Background(), []downloader.Request{req})
+	if err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected sha256 mismatch from the resolved digest, got %v", err)
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	files := map[string][]byte{
+		"/a.whl": bytes.Repeat([]byte("a"), 100_000),
+		"/b.whl": bytes.Repeat([]byte("b"), 50_000),
+		"/c.whl": []byte("small"),
+	}
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(files[r.URL.Path])
+	}))
+
+	type call struct{ downloaded, total int64 }
+
+	var mu sync.Mutex
+
+	calls := make(map[string][]call)
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithMaxWorkers(3),
+		downloader.WithProgress(func(req downloader.Request, downloaded, total int64) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			calls[req.Name] = append(calls[req.Name], call{downloaded, total})
+		}),
+	)
+
+	var requests []downloader.Request
+	for path, content := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "/"), ".whl")
+		requests = append(requests, downloader.Request{
+			Name: name, Version: "1.0", URL: srv.URL + path,
+			SHA256: sha256Hex(content), Filename: name + "-1.0-py3-none-any.whl",
+		})
+	}
+
+	if _, err := mgr.Download(context.Background(), requests); err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	for path, content := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "/"), ".whl")
+		got := calls[name]
+
+		if len(got) < 2 {
+			t.Fatalf("%s: expected progress and a final call, got %v", name, got)
+		}
+
+		size := int64(len(content))
+		if last := got[len(got)-1]; last.downloaded != size || last.total != size {
+			t.Errorf("%s: final call = %+v, want downloaded == total == %d", name, last, size)
+		}
+
+		for i := 1; i < len(got); i++ {
+			if got[i].downloaded < got[i-1].downloaded {
+				t.Errorf("%s: progress went backwards: %v", name, got)
+
+				break
+			}
+		}
+	}
+}
+
+func TestDownloadProgressUnknownLength(t *testing.T) {
+	content := []byte("streamed without a length")
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush() // forces chunked encoding
+		_, _ = w.Write(content)
+	}))
+
+	var totals []int64
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithProgress(func(_ downloader.Request, _, total int64) {
+			totals = append(totals, total)
+		}),
+	)
+
+	_, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "s", Version: "1.0", URL: srv.URL + "/s.whl", Filename: "s-1.0-py3-none-any.whl"},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	if len(totals) < 2 || totals[0] != -1 || totals[len(totals)-1] != int64(len(content)) {
+		t.Errorf("totals = %v, want -1 while streaming and %d at the end", totals, len(content))
+	}
+}
+
+func TestDownloadProgressThrottled(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 4<<20)
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+
+	var calls atomic.Int32
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithProgress(func(downloader.Request, int64, int64) { calls.Add(1) }),
+	)
+
+	_, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "big", Version: "1.0", URL: srv.URL + "/big.whl", SHA256: sha256Hex(content), Filename: "big-1.0-py3-none-any.whl"},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	// Unthrottled, every 32KB read would be reported: over 100 calls.
+	if got := calls.Load(); got < 2 || got > 20 {
+		t.Errorf("progress calls = %d, want a throttled handful", got)
+	}
+}
+
+func TestDownloadRateLimitIsShared(t *testing.T) {
+	const limit = 1000 // bytes per second
+
+	content := bytes.Repeat([]byte("x"), limit)
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithMaxWorkers(2),
+		downloader.WithRateLimit(limit),
+	)
+
+	requests := []downloader.Request{
+		{Name: "a", Version: "1.0", URL: srv.URL + "/a.whl", Filename: "a-1.0-py3-none-any.whl"},
+		{Name: "b", Version: "1.0", URL: srv.URL + "/b.whl", Filename: "b-1.0-py3-none-any.whl"},
+	}
+
+	start := time.Now()
+
+	if _, err := mgr.Download(context.Background(), requests); err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	// Two workers fetch 2*limit bytes in total. With a one-second burst the
+	// aggregate cap makes that take at least one more second; per-connection
+	// limiting would finish both at once.
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("elapsed = %v, want >= 1s under a shared %d B/s limit", elapsed, limit)
+	}
 }
 
 func TestDownloadRetry(t *testing.T) {
@@ -240,6 +526,108 @@ func TestDownloadRetry(t *testing.T) {
 	}
 }
 
+// flakyRangeServer serves content with Accept-Ranges, dropping the connection
+// halfway through the first response. Later requests honor Range headers only
+// when honorRanges is set; otherwise they get the whole file with a 200.
+func flakyRangeServer(t *testing.T, content []byte, honorRanges bool, gotRanges *[]string) *httptest.Server {
+	t.Helper()
+
+	var calls atomic.Int32
+
+	return newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotRanges = append(*gotRanges, r.Header.Get("Range"))
+
+		w.Header().Set("Accept-Ranges", "bytes")
+
+		if calls.Add(1) == 1 {
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			_, _ = w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		var offset int
+		if rng := r.Header.Get("Range"); honorRanges && rng != "" {
+			if _, err := fmt.Sscanf(rng, "bytes=%d-", &offset); err != nil {
+				t.Errorf("bad Range header %q", rng)
+			}
+
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+
+		_, _ = w.Write(content[offset:])
+	}))
+}
+
+func TestDownloadResumesWithRange(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	var gotRanges []string
+
+	srv := flakyRangeServer(t, content, true, &gotRanges)
+
+	dir := t.TempDir()
+	mgr := downloader.New(dir, downloader.WithHTTPClient(srv.Client()), downloader.WithConstantBackoff(0))
+
+	results, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "big", Version: "1.0", URL: srv.URL + "/big.whl", SHA256: sha256Hex(content), Filename: "big-1.0-py3-none-any.whl"},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	want := fmt.Sprintf("bytes=%d-", len(content)/2)
+	if len(gotRanges) != 2 || gotRanges[0] != "" || gotRanges[1] != want {
+		t.Errorf("Range headers = %q, want [\"\" %q]", gotRanges, want)
+	}
+
+	got, err := os.ReadFile(results[0].FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, content) {
+		t.Error("resumed file does not match the served content")
+	}
+
+	if results[0].Size != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", results[0].Size, len(content))
+	}
+}
+
+func TestDownloadRestartsWhenRangeIgnored(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 1000)
+
+	var gotRanges []string
+
+	srv := flakyRangeServer(t, content, false, &gotRanges)
+
+	dir := t.TempDir()
+	mgr := downloader.New(dir, downloader.WithHTTPClient(srv.Client()), downloader.WithConstantBackoff(0))
+
+	results, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "big", Version: "1.0", URL: srv.URL + "/big.whl", SHA256: sha256Hex(content), Filename: "big-1.0-py3-none-any.whl"},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	if len(gotRanges) != 2 || gotRanges[1] == "" {
+		t.Errorf("Range headers = %q, want a resume attempt on retry", gotRanges)
+	}
+
+	got, err := os.ReadFile(results[0].FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, content) {
+		t.Error("restarted file does not match the served content")
+	}
+}
+
 func TestDownloadRetriesExhausted(t *testing.T) {
 	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -262,6 +650,84 @@ func TestDownloadRetriesExhausted(t *testing.T) {
 	}
 }
 
+func TestDownloadWithRetriesLimitsAttempts(t *testing.T) {
+	for _, retries := range []int{0, 1} {
+		t.Run(fmt.Sprint(retries), func(t *testing.T) {
+			var attempts atomic.Int32
+
+			srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+
+			mgr := downloader.New(t.TempDir(), downloader.WithHTTPClient(srv.Client()), downloader.WithRetries(retries))
+
+			_, err := mgr.Download(context.Background(), []downloader.Request{
+				{Name: "failpkg", Version: "1.0.0", URL: srv.URL + "/failpkg.whl", Filename: "failpkg-1.0.0-py3-none-any.whl"},
+			})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			if n := attempts.Load(); n != 1 {
+				t.Errorf("attempts = %d, want exactly 1", n)
+			}
+		})
+	}
+}
+
+func TestDownloadStallTimeout(t *testing.T) {
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithRetries(1),
+		downloader.WithStallTimeout(50*time.Millisecond),
+	)
+
+	_, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "slow", Version: "1.0.0", URL: srv.URL + "/slow.whl", Filename: "slow-1.0.0-py3-none-any.whl"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Fatalf("expected a stall error, got: %v", err)
+	}
+}
+
+func TestDownloadStallTimeoutResetsOnProgress(t *testing.T) {
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for range 8 {
+			_, _ = w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+			time.Sleep(25 * time.Millisecond)
+		}
+	}))
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithHTTPClient(srv.Client()),
+		downloader.WithRetries(1),
+		downloader.WithStallTimeout(100*time.Millisecond),
+	)
+
+	results, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "steady", Version: "1.0.0", URL: srv.URL + "/steady.whl", Filename: "steady-1.0.0-py3-none-any.whl"},
+	})
+	if err != nil {
+		t.Fatalf("Download() error for a slow but progressing transfer: %v", err)
+	}
+
+	if results[0].Size != 40 {
+		t.Errorf("Size = %d, want 40", results[0].Size)
+	}
+}
+
 func TestDownloadContextCanceled(t *testing.T) {
 	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		_, _ = w.Write([]byte("data"))
@@ -309,6 +775,60 @@ func TestDownloadHTTPNotFound(t *testing.T) {
 	}
 }
 
+func TestDownloadForbidden(t *testing.T) {
+	data := []byte("wheel content")
+
+	tests := []struct {
+		name         string
+		header       string
+		body         string
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{"rate limit header", "X-RateLimit-Remaining", "Forbidden", 2, false},
+		{"rate limit body", "", "Too Many Requests", 2, false},
+		{"auth", "", "Forbidden", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if attempts.Add(1) == 1 || tt.wantErr {
+					if tt.header != "" {
+						w.Header().Set(tt.header, "0")
+					}
+
+					http.Error(w, tt.body, http.StatusForbidden)
+
+					return
+				}
+
+				_, _ = w.Write(data)
+			}))
+
+			mgr := downloader.New(t.TempDir(), downloader.WithHTTPClient(srv.Client()))
+
+			_, err := mgr.Download(context.Background(), []downloader.Request{
+				{Name: "pkg", Version: "1.0.0", URL: srv.URL + "/pkg.whl", SHA256: sha256Hex(data), Filename: "pkg-1.0.0-py3-none-any.whl"},
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !strings.Contains(err.Error(), "authentication required or failed") {
+				t.Errorf("expected authentication hint, got: %v", err)
+			}
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
 func TestDownloadEmptyRequests(t *testing.T) {
 	dir := t.TempDir()
 	mgr := downloader.New(dir)
@@ -440,13 +960,13 @@ func TestDownloadFileRename(t *testing.T) {
 		t.Fatalf("Download() error: %v", err)
 	}
 
-	// Verify final file exists and no .tmp remains.
+	// Verify final file exists and no .part remains.
 	finalPath := filepath.Join(dir, "pkg-1.0.0-py3-none-any.whl")
 	if _, err := os.Stat(finalPath); err != nil {
 		t.Errorf("final file not found: %v", err)
 	}
 
-	tmpPath := finalPath + ".tmp"
+	tmpPath := finalPath + ".part"
 	if _, err := os.Stat(tmpPath); err == nil {
 		t.Error("temp file should not exist after successful download")
 	}
@@ -496,6 +1016,88 @@ func TestDownloadPartialFailure(t *testing.T) {
 	fmt.Println("partial failure error:", err)
 }
 
+func TestDownloadWheelDir(t *testing.T) {
+	present := []byte("already downloaded")
+	fresh := []byte("fetched from the index")
+
+	var hits atomic.Int32
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write(fresh)
+	}))
+
+	wheelDir := t.TempDir()
+	writeWheel := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(wheelDir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeWheel("present-1.0-py3-none-any.whl", present)
+	writeWheel("unhashed-1.0-py3-none-any.whl", present)
+	writeWheel("stale-1.0-py3-none-any.whl", []byte("old build"))
+
+	mgr := downloader.New(t.TempDir(), downloader.WithHTTPClient(srv.Client()), downloader.WithWheelDir(wheelDir))
+
+	results, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "present", Version: "1.0", URL: srv.URL + "/present.whl", SHA256: sha256Hex(present), Filename: "present-1.0-py3-none-any.whl"},
+		{Name: "unhashed", Version: "1.0", URL: srv.URL + "/unhashed.whl", Filename: "unhashed-1.0-py3-none-any.whl"},
+		{Name: "stale", Version: "1.0", URL: srv.URL + "/stale.whl", SHA256: sha256Hex(fresh), Filename: "stale-1.0-py3-none-any.whl"},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("network requests = %d, want 1 (only the stale wheel)", n)
+	}
+
+	for _, r := range results[:2] {
+		if filepath.Dir(r.FilePath) != wheelDir || !r.Cached {
+			t.Errorf("%s: FilePath = %s, Cached = %v; want the wheel dir copy", r.Name, r.FilePath, r.Cached)
+		}
+	}
+
+	if filepath.Dir(results[2].FilePath) == wheelDir {
+		t.Error("stale wheel dir file was used despite a digest mismatch")
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(wheelDir, "stale-1.0-py3-none-any.whl")); string(got) != "old build" {
+		t.Error("wheel dir file was modified")
+	}
+}
+
+func TestDownloadLocalFile(t *testing.T) {
+	data := []byte("local wheel")
+	path := filepath.Join(t.TempDir(), "local-1.0-py3-none-any.whl")
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fileURL := "file://" + filepath.ToSlash(path)
+	mgr := downloader.New(t.TempDir())
+
+	results, err := mgr.Download(context.Background(), []downloader.Request{
+		{Name: "local", Version: "1.0", URL: fileURL, SHA256: sha256Hex(data), Filename: filepath.Base(path)},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	if r := results[0]; r.FilePath != path || r.Size != int64(len(data)) || r.SHA256 != sha256Hex(data) {
+		t.Errorf("result = %+v, want the local file served in place", r)
+	}
+
+	_, err = mgr.Download(context.Background(), []downloader.Request{
+		{Name: "local", Version: "1.0", URL: fileURL, SHA256: sha256Hex([]byte("other")), Filename: filepath.Base(path)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Errorf("Download() error = %v, want a sha256 mismatch", err)
+	}
+}
+
 func TestDownloadCacheHit(t *testing.T) {
 	// Create a cached file — no HTTP server needed.
 	cacheDir := t.TempDir()
@@ -619,3 +1221,235 @@ func TestDownloadNilCacheNoEffect(t *testing.T) {
 		t.Error("expected Cached=false with nil cache")
 	}
 }
+
+// barrierCache is a cache whose Get waits until want lookups are in flight,
+// proving that cache-hit verification runs concurrently.
+type barrierCache struct {
+	want    int32
+	dir     string
+	waiting atomic.Int32
+	release chan struct{}
+}
+
+func (c *barrierCache) Get(filename, _ string) (string, bool) {
+	if c.waiting.Add(1) == c.want {
+		close(c.release)
+	}
+
+	select {
+	case <-c.release:
+		return filepath.Join(c.dir, filename), true
+	case <-time.After(2 * time.Second):
+		return "", false
+	}
+}
+
+func (c *barrierCache) Put(string, string) error { return nil }
+
+func TestDownloadCacheHitsVerifiedConcurrently(t *testing.T) {
+	const n = 4
+
+	bc := &barrierCache{want: n, dir: t.TempDir(), release: make(chan struct{})}
+
+	requests := make([]downloader.Request, n)
+	for i := range requests {
+		filename := fmt.Sprintf("pkg%d-1.0.0-py3-none-any.whl", i)
+		if err := os.WriteFile(filepath.Join(bc.dir, filename), []byte(filename), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		requests[i] = downloader.Request{Name: fmt.Sprintf("pkg%d", i), URL: "http://unused/" + filename, Filename: filename}
+	}
+
+	mgr := downloader.New(t.TempDir(), downloader.WithCache(bc), downloader.WithMaxWorkers(n))
+
+	results, err := mgr.Download(context.Background(), requests)
+	if err != nil {
+		t.Fatalf("Download() error: %v (cache lookups did not overlap)", err)
+	}
+
+	for _, r := range results {
+		if !r.Cached {
+			t.Errorf("%s: expected a cache hit; lookups did not overlap", r.Name)
+		}
+	}
+}
+
+func TestDownloadManyCacheHitsWithStaleEntry(t *testing.T) {
+	fresh := []byte("fresh pkg3")
+
+	var fetched atomic.Int32
+
+	srv := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetched.Add(1)
+		_, _ = w.Write(fresh)
+	}))
+
+	wheelCache, requests := populateCache(t, 16)
+
+	// One request expects a digest its cached file no longer matches; only
+	// that package should be downloaded again.
+	requests[3].URL = srv.URL + "/" + requests[3].Filename
+	requests[3].SHA256 = sha256Hex(fresh)
+
+	mgr := downloader.New(t.TempDir(), downloader.WithHTTPClient(srv.Client()), downloader.WithCache(wheelCache))
+
+	results, err := mgr.Download(context.Background(), requests)
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	for i, r := range results {
+		if r.Cached != (i != 3) {
+			t.Errorf("%s: Cached = %v", r.Name, r.Cached)
+		}
+	}
+
+	if got := fetched.Load(); got != 1 {
+		t.Errorf("expected 1 download for the stale entry, got %d", got)
+	}
+}
+
+// populateCache stores n distinct 1 MiB wheels in a real cache and returns
+// requests that hit them.
+func populateCache(tb testing.TB, n int) (*cache.Manager, []downloader.Request) {
+	tb.Helper()
+
+	wheelCache, err := cache.New(cache.WithDir(tb.TempDir()))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	src := tb.TempDir()
+	requests := make([]downloader.Request, n)
+
+	for i := range requests {
+		filename := fmt.Sprintf("pkg%d-1.0.0-py3-none-any.whl", i)
+		data := bytes.Repeat([]byte{byte(i)}, 1<<20)
+
+		path := filepath.Join(src, filename)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+
+		if err := wheelCache.Put(path, filename); err != nil {
+			tb.Fatal(err)
+		}
+
+		requests[i] = downloader.Request{
+			Name:     fmt.Sprintf("pkg%d", i),
+			Version:  "1.0.0",
+			URL:      "http://unused/" + filename,
+			SHA256:   sha256Hex(data),
+			Filename: filename,
+		}
+	}
+
+	return wheelCache, requests
+}
+
+func BenchmarkDownloadCacheHits(b *testing.B) {
+	wheelCache, requests := populateCache(b, 64)
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			mgr := downloader.New(b.TempDir(), downloader.WithCache(wheelCache), downloader.WithMaxWorkers(workers))
+
+			for b.Loop() {
+				if _, err := mgr.Download(context.Background(), requests); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// hostCounter counts the requests in flight on one test server and the most
+// seen at once.
+type hostCounter struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (c *hostCounter) enter() {
+	c.mu.Lock()
+	c.inFlight++
+	c.peak = max(c.peak, c.inFlight)
+	c.mu.Unlock()
+}
+
+func (c *hostCounter) leave() {
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+}
+
+func TestDownloadMaxPerHost(t *testing.T) {
+	const perHost, perServer = 2, 4
+
+	var (
+		total   atomic.Int32
+		once    sync.Once
+		release = make(chan struct{})
+	)
+
+	// Handlers hold their connection until both hosts are at the cap, which
+	// only happens if requests to the two hosts run in parallel.
+	newServer := func(c *hostCounter) *httptest.Server {
+		return newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.enter()
+			defer c.leave()
+
+			if total.Add(1) == 2*perHost {
+				once.Do(func() { close(release) })
+			}
+			defer total.Add(-1)
+
+			select {
+			case <-release:
+			case <-time.After(2 * time.Second):
+			}
+
+			_, _ = w.Write([]byte(r.URL.Path))
+		}))
+	}
+
+	var a, b hostCounter
+
+	srvA, srvB := newServer(&a), newServer(&b)
+
+	var requests []downloader.Request
+	for range perServer {
+		for _, base := range []string{srvA.URL, srvB.URL} {
+			filename := fmt.Sprintf("pkg%d-1.0.0-py3-none-any.whl", len(requests))
+			requests = append(requests, downloader.Request{
+				Name:     fmt.Sprintf("pkg%d", len(requests)),
+				URL:      base + "/" + filename,
+				Filename: filename,
+			})
+		}
+	}
+
+	mgr := downloader.New(t.TempDir(),
+		downloader.WithMaxWorkers(len(requests)),
+		downloader.WithMaxPerHost(perHost),
+		downloader.WithVerifyMode(downloader.VerifySkip),
+	)
+
+	if _, err := mgr.Download(context.Background(), requests); err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+
+	for name, c := range map[string]*hostCounter{"host A": &a, "host B": &b} {
+		if c.peak != perHost {
+			t.Errorf("%s: peak concurrent requests = %d, want %d", name, c.peak, perHost)
+		}
+	}
+
+	select {
+	case <-release:
+	default:
+		t.Errorf("requests to the two hosts never ran in parallel")
+	}
+}
@@ -0,0 +1,52 @@
+package downloader
+
+import (
+	"context"
+	"time"
+)
+
+// rampGate admits a growing number of concurrent downloads: start at once,
+// then one more every step until limit. Each admitted download holds a slot
+// until it finishes, so no more than the current allowance run together.
+type rampGate struct {
+	slots chan struct{}
+}
+
+// newRampGate starts the ramp. It stops growing when ctx is done.
+func newRampGate(ctx context.Context, start, limit int, period time.Duration, after func(time.Duration) <-chan time.Time) *rampGate {
+	g := &rampGate{slots: make(chan struct{}, limit)}
+
+	for range start {
+		g.slots <- struct{}{}
+	}
+
+	step := period / time.Duration(limit-start)
+
+	go func() {
+		for range limit - start {
+			select {
+			case <-after(step):
+				g.slots <- struct{}{}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return g
+}
+
+// acquire blocks until a slot is free or ctx is done.
+func (g *rampGate) acquire(ctx context.Context) error {
+	select {
+	case <-g.slots:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a slot taken by acquire.
+func (g *rampGate) release() {
+	g.slots <- struct{}{}
+}
@@ -0,0 +1,88 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxBurst caps how many bytes a limited read may take at once, so workers
+// sharing a limiter interleave instead of one draining the whole bucket.
+const maxBurst = 32 * 1024
+
+// rateLimiter is a token bucket shared by all download workers. Tokens are
+// bytes; reservations may drive the balance negative, in which case the
+// caller sleeps until the debt is repaid, so waiting callers queue fairly.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	burst := min(bytesPerSec, maxBurst)
+
+	return &rateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// wait takes n bytes from the bucket, blocking until they are paid for or ctx
+// is done.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// limitedReader throttles reads from r through a shared rateLimiter.
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > int(lr.limiter.burst) {
+		p = p[:int(lr.limiter.burst)]
+	}
+
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		if waitErr := lr.limiter.wait(lr.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
@@ -1,55 +1,41 @@
 package downloader
 
 import (
+	"cmp"
 	"fmt"
+	"strconv"
 	"strings"
 
+	pep440 "github.com/aquasecurity/go-pep440-version"
+
 	"github.com/bilusteknoloji/pipg/internal/pypi"
 )
 
 // WheelTag represents a PEP 425 compatibility tag.
-type WheelTag struct {
-	Python   string // e.g., "cp312", "py3"
-	ABI      string // e.g., "cp312", "none"
-	Platform string // e.g., "manylinux_2_17_x86_64", "any"
-}
+type WheelTag = pypi.WheelTag
 
 // ParseWheelFilename parses a wheel filename into its components.
-// Format: {name}-{ver}-{python}-{abi}-{platform}.whl
+// Format: {name}-{ver}(-{build})?-{python}-{abi}-{platform}.whl
 func ParseWheelFilename(filename string) (name, version string, tag WheelTag, err error) {
-	filename = strings.TrimSuffix(filename, ".whl")
-
-	parts := strings.Split(filename, "-")
-	if len(parts) < 5 {
-		return "", "", WheelTag{}, fmt.Errorf("invalid wheel filename %q: expected at least 5 parts", filename)
-	}
-
-	// Last 3 parts are always python-abi-platform.
-	// First part is name, second is version.
-	// Optional build tag is between version and python tag.
-	tag = WheelTag{
-		Python:   parts[len(parts)-3],
-		ABI:      parts[len(parts)-2],
-		Platform: parts[len(parts)-1],
-	}
-
-	name = parts[0]
-	version = parts[1]
-
-	return name, version, tag, nil
+	return pypi.ParseWheelFilename(filename)
 }
 
 // SelectWheel selects the best compatible wheel from the available URLs.
-// compatTags must be ordered by priority (most preferred first).
+// compatTags must be ordered by priority (most preferred first); among wheels
+// matching the same tag, the one with the highest build tag wins. Files whose
+// own Requires-Python excludes pythonVersion are skipped; pass the full
+// interpreter version (e.g., "3.12.1") so specifiers like ">=3.12.2" are
+// judged correctly. An empty pythonVersion disables that check.
 // Returns an error if no compatible wheel is found (does NOT fall back to sdist).
-func SelectWheel(urls []pypi.URL, compatTags []WheelTag) (pypi.URL, error) {
+func SelectWheel(urls []pypi.URL, compatTags []WheelTag, pythonVersion string) (pypi.URL, error) {
 	bestPriority := len(compatTags)
 	var bestURL pypi.URL
+	var bestBuild string
 
 	found := false
 
 	for _, u := range urls {
-		if u.PackageType != "bdist_wheel" {
+		if u.PackageType != "bdist_wheel" || !requiresPythonAllows(u.RequiresPython, pythonVersion) {
 			continue
 		}
 
@@ -59,21 +45,22 @@ func SelectWheel(urls []pypi.URL, compatTags []WheelTag) (pypi.URL, error) {
 		}
 
 		for i, ct := range compatTags {
-			if i >= bestPriority {
+			if i > bestPriority {
 				break
 			}
 
-			if tagMatches(tag, ct) {
+			if !tagMatches(tag, ct) {
+				continue
+			}
+
+			if i < bestPriority || compareBuildTags(tag.Build, bestBuild) > 0 {
 				bestPriority = i
 				bestURL = u
+				bestBuild = tag.Build
 				found = true
-
-				break
 			}
-		}
 
-		if bestPriority == 0 {
-			break // can't do better than the highest priority
+			break
 		}
 	}
 
@@ -84,6 +71,55 @@ func SelectWheel(urls []pypi.URL, compatTags []WheelTag) (pypi.URL, error) {
 	return bestURL, nil
 }
 
+// requiresPythonAllows reports whether a file's Requires-Python specifier
+// admits pythonVersion. A missing or unparseable specifier allows it.
+func requiresPythonAllows(requiresPython, pythonVersion string) bool {
+	if requiresPython == "" || pythonVersion == "" {
+		return true
+	}
+
+	v, err := pep440.Parse(pythonVersion)
+	if err != nil {
+		return true
+	}
+
+	specs, err := pep440.NewSpecifiers(requiresPython)
+	if err != nil {
+		return true
+	}
+
+	return specs.Check(v)
+}
+
+// compareBuildTags orders PEP 427 build tags: by their leading number, then
+// by the remaining string. A missing build tag sorts before any other.
+func compareBuildTags(a, b string) int {
+	if a == "" || b == "" {
+		return cmp.Compare(len(a), len(b))
+	}
+
+	numA, restA := splitBuildTag(a)
+	numB, restB := splitBuildTag(b)
+
+	if c := cmp.Compare(numA, numB); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(restA, restB)
+}
+
+// splitBuildTag splits a build tag like "1a" into its number and suffix.
+func splitBuildTag(build string) (int, string) {
+	end := 0
+	for end < len(build) && build[end] >= '0' && build[end] <= '9' {
+		end++
+	}
+
+	n, _ := strconv.Atoi(build[:end])
+
+	return n, build[end:]
+}
+
 // tagMatches checks if a wheel tag matches a compatibility tag.
 // Wheel tags can have compound values separated by "." (e.g., "py2.py3"),
 // meaning the wheel supports any of those values.
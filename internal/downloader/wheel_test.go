@@ -34,6 +34,16 @@ func TestParseWheelFilename(t *testing.T) {
 			"six", "1.16.0",
 			downloader.WheelTag{Python: "py2.py3", ABI: "none", Platform: "any"},
 		},
+		{
+			"numpy-1.26.0-1-cp312-cp312-linux_x86_64.whl",
+			"numpy", "1.26.0",
+			downloader.WheelTag{Python: "cp312", ABI: "cp312", Platform: "linux_x86_64", Build: "1"},
+		},
+		{
+			"pkg-2.0-12b-py3-none-any.whl",
+			"pkg", "2.0",
+			downloader.WheelTag{Python: "py3", ABI: "none", Platform: "any", Build: "12b"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -64,6 +74,8 @@ func TestParseWheelFilenameInvalid(t *testing.T) {
 		"flask.whl",
 		"flask-3.0.0.whl",
 		"too-few-parts.whl",
+		"pkg-1.0-1-extra-py3-none-any.whl",
+		"pkg-1.0-beta-py3-none-any.whl",
 	}
 
 	for _, filename := range tests {
@@ -89,7 +101,7 @@ func TestSelectWheel(t *testing.T) {
 		{Python: "py3", ABI: "none", Platform: "any"},
 	}
 
-	got, err := downloader.SelectWheel(urls, compatTags)
+	got, err := downloader.SelectWheel(urls, compatTags, "")
 	if err != nil {
 		t.Fatalf("SelectWheel() error: %v", err)
 	}
@@ -109,7 +121,7 @@ func TestSelectWheelPurePython(t *testing.T) {
 		{Python: "py3", ABI: "none", Platform: "any"},
 	}
 
-	got, err := downloader.SelectWheel(urls, compatTags)
+	got, err := downloader.SelectWheel(urls, compatTags, "")
 	if err != nil {
 		t.Fatalf("SelectWheel() error: %v", err)
 	}
@@ -128,7 +140,7 @@ func TestSelectWheelCompoundTag(t *testing.T) {
 		{Python: "py3", ABI: "none", Platform: "any"},
 	}
 
-	got, err := downloader.SelectWheel(urls, compatTags)
+	got, err := downloader.SelectWheel(urls, compatTags, "")
 	if err != nil {
 		t.Fatalf("SelectWheel() error: %v", err)
 	}
@@ -149,7 +161,7 @@ func TestSelectWheelNoMatch(t *testing.T) {
 		{Python: "py3", ABI: "none", Platform: "any"},
 	}
 
-	_, err := downloader.SelectWheel(urls, compatTags)
+	_, err := downloader.SelectWheel(urls, compatTags, "")
 	if err == nil {
 		t.Fatal("SelectWheel() expected error for no compatible wheel, got nil")
 	}
@@ -164,8 +176,72 @@ func TestSelectWheelSkipsSdist(t *testing.T) {
 		{Python: "py3", ABI: "none", Platform: "any"},
 	}
 
-	_, err := downloader.SelectWheel(urls, compatTags)
+	_, err := downloader.SelectWheel(urls, compatTags, "")
 	if err == nil {
 		t.Fatal("SelectWheel() should not select sdist, expected error")
 	}
 }
+
+func TestSelectWheelPrefersHighestBuildTag(t *testing.T) {
+	urls := []pypi.URL{
+		{Filename: "pkg-1.0.0-py3-none-any.whl", PackageType: "bdist_wheel", URL: "https://example.com/nobuild.whl"},
+		{Filename: "pkg-1.0.0-10-py3-none-any.whl", PackageType: "bdist_wheel", URL: "https://example.com/build10.whl"},
+		{Filename: "pkg-1.0.0-2-py3-none-any.whl", PackageType: "bdist_wheel", URL: "https://example.com/build2.whl"},
+		{Filename: "pkg-1.0.0-99-cp312-none-any.whl", PackageType: "bdist_wheel", URL: "https://example.com/lower-priority.whl"},
+	}
+
+	compatTags := []downloader.WheelTag{
+		{Python: "py3", ABI: "none", Platform: "any"},
+		{Python: "cp312", ABI: "none", Platform: "any"},
+	}
+
+	got, err := downloader.SelectWheel(urls, compatTags, "")
+	if err != nil {
+		t.Fatalf("SelectWheel() error: %v", err)
+	}
+
+	if got.URL != "https://example.com/build10.whl" {
+		t.Errorf("SelectWheel() selected %q, want the highest build of the best tag", got.Filename)
+	}
+}
+
+func TestSelectWheelSkipsFileRequiresPython(t *testing.T) {
+	urls := []pypi.URL{
+		{Filename: "pkg-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl", PackageType: "bdist_wheel", URL: "https://example.com/native.whl", RequiresPython: ">=3.13"},
+		{Filename: "pkg-1.0.0-py3-none-any.whl", PackageType: "bdist_wheel", URL: "https://example.com/pure.whl", RequiresPython: ">=3.8"},
+	}
+
+	compatTags := []downloader.WheelTag{
+		{Python: "cp312", ABI: "cp312", Platform: "manylinux_2_17_x86_64"},
+		{Python: "py3", ABI: "none", Platform: "any"},
+	}
+
+	got, err := downloader.SelectWheel(urls, compatTags, "3.12")
+	if err != nil {
+		t.Fatalf("SelectWheel() error: %v", err)
+	}
+
+	if got.URL != "https://example.com/pure.whl" {
+		t.Errorf("SelectWheel() selected %q, want the wheel whose Requires-Python allows 3.12", got.Filename)
+	}
+
+	if _, err := downloader.SelectWheel(urls[:1], compatTags, "3.12"); err == nil {
+		t.Error("SelectWheel() should reject a file whose Requires-Python excludes the target")
+	}
+
+	got, err = downloader.SelectWheel(urls, compatTags, "")
+	if err != nil || got.URL != "https://example.com/native.whl" {
+		t.Errorf("without a target version the check is skipped, got %q, %v", got.Filename, err)
+	}
+
+	// A micro-level bound needs the full interpreter version.
+	micro := []pypi.URL{{Filename: "pkg-1.0.0-py3-none-any.whl", PackageType: "bdist_wheel", URL: "https://example.com/pure.whl", RequiresPython: ">=3.12.2"}}
+
+	if _, err := downloader.SelectWheel(micro, compatTags, "3.12.1"); err == nil {
+		t.Error("SelectWheel() accepted Requires-Python >=3.12.2 for Python 3.12.1")
+	}
+
+	if _, err := downloader.SelectWheel(micro, compatTags, "3.12.3"); err != nil {
+		t.Errorf("SelectWheel() rejected Requires-Python >=3.12.2 for Python 3.12.3: %v", err)
+	}
+}
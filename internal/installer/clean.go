@@ -0,0 +1,94 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BrokenInstall describes a package left inconsistent in site-packages,
+// typically by an interrupted install.
+type BrokenInstall struct {
+	DistInfo string // path to the .dist-info directory
+	Reason   string // e.g., "missing RECORD"
+}
+
+// Name returns the distribution name and version, e.g., "six-1.16.0".
+func (b BrokenInstall) Name() string {
+	return strings.TrimSuffix(filepath.Base(b.DistInfo), ".dist-info")
+}
+
+// FindBroken scans libDirs for packages installed by pipg whose RECORD is
+// missing or malformed, or lists files that no longer exist. Packages
+// installed by other tools, such as a distribution's package manager or
+// conda, are never reported, since their metadata follows other rules.
+func FindBroken(libDirs []string) ([]BrokenInstall, error) {
+	dirs, err := distInfoDirs(libDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var broken []BrokenInstall
+
+	for _, dir := range dirs {
+		if !installedByPipg(dir) {
+			continue
+		}
+
+		records, err := ReadRecord(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			broken = append(broken, BrokenInstall{DistInfo: dir, Reason: "missing RECORD"})
+
+			continue
+		}
+
+		if err != nil {
+			broken = append(broken, BrokenInstall{DistInfo: dir, Reason: "malformed RECORD"})
+
+			continue
+		}
+
+		if missing := missingFiles(filepath.Dir(dir), records); missing > 0 {
+			broken = append(broken, BrokenInstall{
+				DistInfo: dir,
+				Reason:   fmt.Sprintf("%d files listed in RECORD are missing", missing),
+			})
+		}
+	}
+
+	return broken, nil
+}
+
+// RemoveBroken removes a broken install: the files its RECORD lists, if it
+// has one, and its .dist-info directory.
+func (s *Service) RemoveBroken(b BrokenInstall) error {
+	records, _ := ReadRecord(b.DistInfo) // nil when RECORD is missing
+
+	if err := s.removeInstall(filepath.Dir(b.DistInfo), b.DistInfo, records); err != nil {
+		return fmt.Errorf("cleaning %s: %w", b.Name(), err)
+	}
+
+	return nil
+}
+
+// installedByPipg reports whether the INSTALLER file names pipg.
+func installedByPipg(distInfoDir string) bool {
+	data, err := os.ReadFile(filepath.Join(distInfoDir, "INSTALLER"))
+
+	return err == nil && strings.TrimSpace(string(data)) == "pipg"
+}
+
+// missingFiles counts the RECORD entries that do not exist on disk.
+func missingFiles(siteDir string, records []RecordEntry) int {
+	missing := 0
+
+	for _, e := range records {
+		if _, err := os.Stat(recordPath(siteDir, e.Path)); errors.Is(err, os.ErrNotExist) {
+			missing++
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,120 @@
+package installer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+// writeDistInfo creates a dist-info directory in siteDir holding files.
+func writeDistInfo(t *testing.T, siteDir, name string, files map[string]string) string {
+	t.Helper()
+
+	distInfo := filepath.Join(siteDir, name+".dist-info")
+	if err := os.MkdirAll(distInfo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(distInfo, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return distInfo
+}
+
+func TestFindBrokenMissingRecord(t *testing.T) {
+	env := testEnv(t)
+
+	distInfo := writeDistInfo(t, env.SitePackages, "half-1.0", map[string]string{
+		"METADATA":  "Name: half\n",
+		"INSTALLER": "pipg\n",
+	})
+
+	// Packages from other installers are theirs to keep consistent.
+	writeDistInfo(t, env.SitePackages, "distro-2.0", map[string]string{"METADATA": "Name: distro\n"})
+	writeDistInfo(t, env.SitePackages, "conda-3.0", map[string]string{"METADATA": "Name: conda\n", "INSTALLER": "conda\n"})
+
+	broken, err := installer.FindBroken(env.LibDirs())
+	if err != nil {
+		t.Fatalf("FindBroken() error: %v", err)
+	}
+
+	if len(broken) != 1 || broken[0].Name() != "half-1.0" || broken[0].Reason != "missing RECORD" {
+		t.Fatalf("FindBroken() = %+v, want half-1.0 with missing RECORD", broken)
+	}
+
+	if err := installer.New(env).RemoveBroken(broken[0]); err != nil {
+		t.Fatalf("RemoveBroken() error: %v", err)
+	}
+
+	if _, err := os.Stat(distInfo); !os.IsNotExist(err) {
+		t.Errorf("dist-info should be removed, stat error: %v", err)
+	}
+}
+
+func TestFindBrokenMissingFiles(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+
+	for _, name := range []string{"good", "bad"} {
+		wheelPath := filepath.Join(wheelDir, name+"-1.0.0-py3-none-any.whl")
+		createWheel(t, wheelPath, map[string]string{
+			name + "/__init__.py":              "# " + name + "\n",
+			name + "-1.0.0.dist-info/METADATA": "Name: " + name + "\nVersion: 1.0.0\n",
+		})
+
+		dl := []downloader.Result{{Name: name, Version: "1.0.0", FilePath: wheelPath}}
+		if err := installer.New(env).Install(context.Background(), dl); err != nil {
+			t.Fatalf("Install(%s) error: %v", name, err)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(env.SitePackages, "bad", "__init__.py")); err != nil {
+		t.Fatal(err)
+	}
+
+	broken, err := installer.FindBroken(env.LibDirs())
+	if err != nil {
+		t.Fatalf("FindBroken() error: %v", err)
+	}
+
+	if len(broken) != 1 || broken[0].Name() != "bad-1.0.0" {
+		t.Fatalf("FindBroken() = %+v, want only bad-1.0.0", broken)
+	}
+}
+
+func TestFindBrokenMalformedRecord(t *testing.T) {
+	env := testEnv(t)
+
+	writeDistInfo(t, env.SitePackages, "odd-1.0", map[string]string{
+		"METADATA":  "Name: odd\n",
+		"INSTALLER": "pipg\n",
+		"RECORD":    "odd/__init__.py,\"unterminated\n",
+	})
+
+	broken, err := installer.FindBroken(env.LibDirs())
+	if err != nil {
+		t.Fatalf("FindBroken() error: %v", err)
+	}
+
+	if len(broken) != 1 || broken[0].Reason != "malformed RECORD" {
+		t.Fatalf("FindBroken() = %+v, want odd-1.0 with malformed RECORD", broken)
+	}
+}
+
+func TestFindBrokenClean(t *testing.T) {
+	broken, err := installer.FindBroken(testEnv(t).LibDirs())
+	if err != nil {
+		t.Fatalf("FindBroken() error: %v", err)
+	}
+
+	if len(broken) != 0 {
+		t.Errorf("FindBroken() = %+v, want none", broken)
+	}
+}
@@ -0,0 +1,125 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// byteCompile compiles the .py files among records, which are relative to
+// siteDir, and returns RECORD entries for the .pyc files that were written.
+// Compilation errors are only logged: vendored code written for another
+// Python version often fails to compile yet installs fine. The __pycache__
+// directories and .pyc files the interpreter writes are journaled in tx.
+func (s *Service) byteCompile(ctx context.Context, siteDir string, records []RecordEntry, tx *transaction) []RecordEntry {
+	var sources []string
+
+	for _, r := range records {
+		path := filepath.Join(siteDir, r.Path)
+		if filepath.Ext(path) == ".py" && isInsideDir(path, siteDir) {
+			sources = append(sources, path)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil
+	}
+
+	// Existing .pyc files are backed up before the interpreter replaces them.
+	newCacheDirs := make(map[string]bool)
+
+	for _, src := range sources {
+		if cacheDir := filepath.Join(filepath.Dir(src), "__pycache__"); !newCacheDirs[cacheDir] {
+			if _, err := os.Stat(cacheDir); err != nil {
+				newCacheDirs[cacheDir] = true
+			}
+		}
+
+		matches, _ := filepath.Glob(pycPattern(src))
+		for _, pyc := range matches {
+			if err := tx.track(pyc); err != nil {
+				s.logger.Warn("skipping byte-compilation", slog.String("error", err.Error()))
+
+				return nil
+			}
+		}
+	}
+
+	if err := s.runCompileall(ctx, sources); err != nil {
+		s.logger.Warn("some files could not be byte-compiled", slog.String("error", err.Error()))
+	}
+
+	var entries []RecordEntry
+
+	for _, src := range sources {
+		if cacheDir := filepath.Join(filepath.Dir(src), "__pycache__"); newCacheDirs[cacheDir] {
+			tx.created(cacheDir)
+		}
+
+		matches, _ := filepath.Glob(pycPattern(src))
+		for _, pyc := range matches {
+			tx.created(pyc)
+
+			rel, err := filepath.Rel(siteDir, pyc)
+			if err != nil {
+				continue
+			}
+
+			// A .pyc embeds the mtime of its source, which is the install
+			// time, so like pip it is listed without a hash or size and
+			// RECORD stays reproducible.
+			entries = append(entries, RecordEntry{Path: rel})
+		}
+	}
+
+	return entries
+}
+
+// pycPattern returns the glob matching the .pyc files of the source file
+// src for any interpreter, e.g., "__pycache__/mod.*.pyc".
+func pycPattern(src string) string {
+	dir, file := filepath.Split(src)
+
+	return filepath.Join(dir, "__pycache__", strings.TrimSuffix(file, ".py")+".*.pyc")
+}
+
+// runCompileall runs "python -m compileall" over sources. The file list is
+// passed through a temporary file rather than arguments to stay clear of
+// command-line length limits on large packages.
+func (s *Service) runCompileall(ctx context.Context, sources []string) error {
+	list, err := os.CreateTemp("", "pipg-compile-*.txt")
+	if err != nil {
+		return fmt.Errorf("creating compile list: %w", err)
+	}
+	defer func() { _ = os.Remove(list.Name()) }()
+
+	_, err = list.WriteString(strings.Join(sources, "\n") + "\n")
+	if closeErr := list.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("writing compile list: %w", err)
+	}
+
+	out, err := s.runCmd(ctx, s.env.PythonPath, "-m", "compileall", "-q", "-i", list.Name())
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// defaultRunCmd runs a command and returns its combined output; compileall
+// reports failures on stdout.
+func defaultRunCmd(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
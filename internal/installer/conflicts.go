@@ -0,0 +1,148 @@
+package installer
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileOwners maps the path of every file listed in an installed package's
+// RECORD to its owner.
+type fileOwners map[string]fileOwner
+
+// fileOwner is the package that lists a file in its RECORD.
+type fileOwner struct {
+	name string // normalized, as returned by distName
+	hash string // the file's RECORD hash, e.g., "sha256=<digest>"; may be empty
+}
+
+// installedOwners indexes the RECORD files of the packages installed in the
+// environment's purelib and platlib directories. Packages without a
+// readable RECORD own nothing.
+func (s *Service) installedOwners() fileOwners {
+	owners := make(fileOwners)
+
+	dirs := []string{s.libDir(true)}
+	if platlib := s.libDir(false); platlib != dirs[0] {
+		dirs = append(dirs, platlib)
+	}
+
+	for _, siteDir := range dirs {
+		distInfos, err := filepath.Glob(filepath.Join(siteDir, "*.dist-info"))
+		if err != nil {
+			continue
+		}
+
+		for _, distInfo := range distInfos {
+			records, err := ReadRecord(distInfo)
+			if err != nil {
+				continue
+			}
+
+			prefix, _, _ := strings.Cut(filepath.Base(distInfo), "-")
+			owners.add(distName(prefix), siteDir, records)
+		}
+	}
+
+	return owners
+}
+
+// add records name as the owner of the files in records, which are relative
+// to siteDir.
+func (o fileOwners) add(name, siteDir string, records []RecordEntry) {
+	for _, e := range records {
+		o[recordPath(siteDir, e.Path)] = fileOwner{name: name, hash: e.Hash}
+	}
+}
+
+// checkConflicts logs a warning for each existing file that extracting the
+// wheel r for the package name would overwrite and that another installed
+// package lists in its RECORD; with WithStrictConflicts it returns an error
+// instead. A file whose RECORD hash matches the incoming file is not a
+// conflict: legacy pkgutil namespace packages, such as the backports.*
+// family, all ship the same __init__.py.
+func (s *Service) checkConflicts(r *zip.Reader, siteDir, name string, owners fileOwners) error {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	self := distName(name)
+
+	var conflicts []string
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		destPath, _ := s.resolveDestination(f.Name, siteDir, ".data/")
+		if destPath == "" {
+			continue
+		}
+
+		owner, ok := owners[filepath.Clean(destPath)]
+		if !ok || owner.name == self {
+			continue
+		}
+
+		if _, err := os.Lstat(destPath); err != nil {
+			continue
+		}
+
+		if owner.hash != "" && sameContent(f, owner.hash) {
+			continue
+		}
+
+		if !s.strictConflicts {
+			s.logger.Warn("overwriting file owned by another package",
+				slog.String("file", destPath), slog.String("owner", owner.name), slog.String("package", name))
+
+			continue
+		}
+
+		conflicts = append(conflicts, fmt.Sprintf("%s (owned by %s)", f.Name, owner.name))
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("would overwrite files of other installed packages: %s", strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+// sameContent reports whether the wheel entry f has the RECORD hash recorded,
+// which may be written as hex, as pipg does, or as PEP 376 urlsafe base64.
+func sameContent(f *zip.File, recorded string) bool {
+	encoded, ok := strings.CutPrefix(recorded, "sha256=")
+	if !ok {
+		return false
+	}
+
+	want, err := hex.DecodeString(encoded)
+	if err != nil || len(want) != sha256.Size {
+		if want, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "=")); err != nil {
+			return false
+		}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return false
+	}
+	defer func() { _ = rc.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return false
+	}
+
+	return bytes.Equal(h.Sum(nil), want)
+}
@@ -0,0 +1,53 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DirectURL is the PEP 610 record of where a package installed from a direct
+// URL came from, stored as direct_url.json in its dist-info directory.
+type DirectURL struct {
+	URL          string       `json:"url"`
+	VCSInfo      *VCSInfo     `json:"vcs_info,omitempty"`
+	ArchiveInfo  *ArchiveInfo `json:"archive_info,omitempty"`
+	Subdirectory string       `json:"subdirectory,omitempty"`
+}
+
+// VCSInfo describes a package installed from a version control repository.
+type VCSInfo struct {
+	VCS               string `json:"vcs"`                          // e.g., "git"
+	CommitID          string `json:"commit_id"`                    // exact commit that was installed
+	RequestedRevision string `json:"requested_revision,omitempty"` // branch, tag, or ref the user asked for
+}
+
+// ArchiveInfo describes a package installed from a wheel or sdist archive.
+type ArchiveInfo struct {
+	Hash string `json:"hash,omitempty"` // e.g., "sha256=<hex digest>"
+}
+
+// WriteDirectURL writes direct_url.json to the dist-info directory.
+// VCS installs must record the resolved commit, not just the requested ref.
+func WriteDirectURL(distInfoDir string, d DirectURL) error {
+	if d.URL == "" {
+		return errors.New("direct URL is empty")
+	}
+
+	if d.VCSInfo != nil && d.ArchiveInfo != nil {
+		return fmt.Errorf("direct URL %s cannot have both vcs_info and archive_info", d.URL)
+	}
+
+	if d.VCSInfo != nil && (d.VCSInfo.VCS == "" || d.VCSInfo.CommitID == "") {
+		return fmt.Errorf("vcs_info for %s needs both vcs and commit_id", d.URL)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("encoding direct_url.json: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(distInfoDir, "direct_url.json"), append(data, '\n'), 0o644)
+}
@@ -0,0 +1,59 @@
+package installer_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+func TestWriteDirectURLVCS(t *testing.T) {
+	distInfo := t.TempDir()
+
+	err := installer.WriteDirectURL(distInfo, installer.DirectURL{
+		URL: "https://github.com/pallets/flask.git",
+		VCSInfo: &installer.VCSInfo{
+			VCS:               "git",
+			CommitID:          "7f4b8e1c2d3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c",
+			RequestedRevision: "3.0.0",
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteDirectURL() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(distInfo, "direct_url.json"))
+	if err != nil {
+		t.Fatalf("reading direct_url.json: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("direct_url.json is not valid JSON: %v\n%s", err, data)
+	}
+
+	if got["url"] != "https://github.com/pallets/flask.git" {
+		t.Errorf("url = %v", got["url"])
+	}
+
+	vcs, ok := got["vcs_info"].(map[string]any)
+	if !ok {
+		t.Fatalf("missing vcs_info: %s", data)
+	}
+
+	if vcs["vcs"] != "git" || vcs["commit_id"] != "7f4b8e1c2d3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c" || vcs["requested_revision"] != "3.0.0" {
+		t.Errorf("unexpected vcs_info: %v", vcs)
+	}
+}
+
+func TestWriteDirectURLVCSRequiresCommit(t *testing.T) {
+	err := installer.WriteDirectURL(t.TempDir(), installer.DirectURL{
+		URL:     "https://github.com/pallets/flask.git",
+		VCSInfo: &installer.VCSInfo{VCS: "git", RequestedRevision: "main"},
+	})
+	if err == nil {
+		t.Fatal("expected error for vcs_info without commit_id, got nil")
+	}
+}
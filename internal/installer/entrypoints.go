@@ -6,16 +6,20 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/bilusteknoloji/pipg/internal/python"
 )
 
-// ConsoleScript represents a parsed console_scripts entry point.
+// ConsoleScript represents a parsed console_scripts or gui_scripts entry point.
 type ConsoleScript struct {
 	Name   string // script name, e.g., "ipython"
 	Module string // module path, e.g., "IPython"
 	Attr   string // callable attribute, e.g., "start_ipython"
+	GUI    bool   // declared in gui_scripts; runs without a console window on Windows
 }
 
-// ParseEntryPoints reads an entry_points.txt file and returns the console_scripts.
+// ParseEntryPoints reads an entry_points.txt file and returns the
+// console_scripts and gui_scripts, in file order.
 func ParseEntryPoints(path string) ([]ConsoleScript, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -29,7 +33,7 @@ func ParseEntryPoints(path string) ([]ConsoleScript, error) {
 
 	var scripts []ConsoleScript
 
-	inConsoleScripts := false
+	var section string
 
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
@@ -42,12 +46,12 @@ func ParseEntryPoints(path string) ([]ConsoleScript, error) {
 
 		// Section header.
 		if strings.HasPrefix(line, "[") {
-			inConsoleScripts = line == "[console_scripts]"
+			section = line
 
 			continue
 		}
 
-		if !inConsoleScripts {
+		if section != "[console_scripts]" && section != "[gui_scripts]" {
 			continue
 		}
 
@@ -57,6 +61,7 @@ func ParseEntryPoints(path string) ([]ConsoleScript, error) {
 			continue
 		}
 
+		cs.GUI = section == "[gui_scripts]"
 		scripts = append(scripts, cs)
 	}
 
@@ -67,6 +72,57 @@ func ParseEntryPoints(path string) ([]ConsoleScript, error) {
 	return scripts, nil
 }
 
+// BinDir returns the directory that scripts are installed to for env: the
+// interpreter's sysconfig scripts path when known, otherwise prefix/Scripts on
+// Windows and prefix/bin elsewhere.
+func BinDir(env *python.Environment) string {
+	if env.ScriptsDir != "" {
+		return env.ScriptsDir
+	}
+
+	if env.IsWindows() {
+		return filepath.Join(env.Prefix, "Scripts")
+	}
+
+	return filepath.Join(env.Prefix, "bin")
+}
+
+// ScriptFilename returns the file name of the wrapper for an entry point
+// script. Windows cannot execute extensionless scripts, so the wrapper gets a
+// .py extension there and is run through the py launcher, which honours the
+// shebang line; GUI scripts get .pyw so that they start without a console
+// window.
+func ScriptFilename(cs ConsoleScript, windows bool) string {
+	switch {
+	case windows && cs.GUI:
+		return cs.Name + ".pyw"
+	case windows:
+		return cs.Name + ".py"
+	default:
+		return cs.Name
+	}
+}
+
+// InstalledScripts returns the names of the console_scripts and gui_scripts
+// entry points declared by the installed package name in libDirs. Scripts a
+// wheel ships in its .data/scripts directory are not included.
+func InstalledScripts(libDirs []string, name string) []string {
+	var names []string
+
+	for _, dir := range installedDistInfo(libDirs, name) {
+		scripts, err := ParseEntryPoints(filepath.Join(dir, "entry_points.txt"))
+		if err != nil {
+			continue
+		}
+
+		for _, cs := range scripts {
+			names = append(names, cs.Name)
+		}
+	}
+
+	return names
+}
+
 // parseScriptEntry parses a single console_scripts entry.
 // Format: "name = module:attr" or "name = module:attr [extras]"
 func parseScriptEntry(line string) (ConsoleScript, error) {
@@ -109,9 +165,47 @@ if __name__ == '__main__':
 	return []byte(script)
 }
 
-// InstallConsoleScripts reads entry_points.txt, generates wrapper scripts,
-// and installs them to the bin directory. Returns RECORD entries for the scripts.
-func InstallConsoleScripts(distInfoDir, binDir, pythonPath string) ([]RecordEntry, error) {
+// GeneratePkgResourcesScript creates a wrapper script in the form of
+// setuptools' easy_install launchers: it declares __requires__ for the
+// distribution requirement (e.g., "flask==3.0.3") and loads the entry point
+// through pkg_resources, which activates that exact distribution when several
+// versions are on sys.path.
+func GeneratePkgResourcesScript(pythonPath, requirement string, cs ConsoleScript) []byte {
+	group := "console_scripts"
+	if cs.GUI {
+		group = "gui_scripts"
+	}
+
+	script := fmt.Sprintf(`#!%s
+# EASY-INSTALL-ENTRY-SCRIPT: '%s','%s','%s'
+__requires__ = '%s'
+import re
+import sys
+from pkg_resources import load_entry_point
+
+if __name__ == '__main__':
+    sys.argv[0] = re.sub(r'(-script\.pyw?|\.exe)?$', '', sys.argv[0])
+    sys.exit(
+        load_entry_point('%s', '%s', '%s')()
+    )
+`, pythonPath, requirement, group, cs.Name, requirement, requirement, group, cs.Name)
+
+	return []byte(script)
+}
+
+// InstallEntryPointScripts reads entry_points.txt, generates wrapper scripts
+// for its console_scripts and gui_scripts, and installs them to the bin
+// directory. Returns RECORD entries for the scripts.
+func InstallEntryPointScripts(distInfoDir, binDir, pythonPath string) ([]RecordEntry, error) {
+	generate := func(cs ConsoleScript) []byte { return GenerateScript(pythonPath, cs) }
+
+	return installEntryPointScripts(distInfoDir, binDir, generate, false, nil)
+}
+
+// installEntryPointScripts is InstallEntryPointScripts with the wrappers made
+// by generate and the Windows script naming applied when windows is set,
+// journaling the scripts it writes in tx.
+func installEntryPointScripts(distInfoDir, binDir string, generate func(ConsoleScript) []byte, windows bool, tx *transaction) ([]RecordEntry, error) {
 	epPath := filepath.Join(distInfoDir, "entry_points.txt")
 
 	scripts, err := ParseEntryPoints(epPath)
@@ -123,15 +217,20 @@ func InstallConsoleScripts(distInfoDir, binDir, pythonPath string) ([]RecordEntr
 		return nil, nil
 	}
 
-	if err := os.MkdirAll(binDir, 0o755); err != nil {
+	if err := tx.mkdirAll(binDir); err != nil {
 		return nil, fmt.Errorf("creating bin directory: %w", err)
 	}
 
 	var records []RecordEntry
 
 	for _, cs := range scripts {
-		scriptPath := filepath.Join(binDir, cs.Name)
-		content := GenerateScript(pythonPath, cs)
+		filename := ScriptFilename(cs, windows)
+		scriptPath := filepath.Join(binDir, filename)
+		content := generate(cs)
+
+		if err := tx.track(scriptPath); err != nil {
+			return nil, fmt.Errorf("writing script %s: %w", cs.Name, err)
+		}
 
 		if err := os.WriteFile(scriptPath, content, 0o755); err != nil {
 			return nil, fmt.Errorf("writing script %s: %w", cs.Name, err)
@@ -142,11 +241,8 @@ func InstallConsoleScripts(distInfoDir, binDir, pythonPath string) ([]RecordEntr
 			return nil, fmt.Errorf("hashing script %s: %w", cs.Name, err)
 		}
 
-		// Record path relative to site-packages uses ../../../bin/name format,
-		// but pip uses the absolute path in some cases. We'll use the relative
-		// path from the dist-info's perspective.
 		records = append(records, RecordEntry{
-			Path: filepath.Join("..", "..", "..", "bin", cs.Name),
+			Path: scriptRecordPath(distInfoDir, scriptPath, filename, windows),
 			Hash: hash,
 			Size: size,
 		})
@@ -154,3 +250,20 @@ func InstallConsoleScripts(distInfoDir, binDir, pythonPath string) ([]RecordEntr
 
 	return records, nil
 }
+
+// scriptRecordPath returns the RECORD path of an installed script, relative
+// to site-packages. The path is derived rather than assumed because the
+// scripts directory may come from the interpreter's install scheme, and
+// Windows site-packages (prefix/Lib/site-packages) is one level shallower
+// than on POSIX.
+func scriptRecordPath(distInfoDir, scriptPath, filename string, windows bool) string {
+	if rel, err := filepath.Rel(filepath.Dir(distInfoDir), scriptPath); err == nil {
+		return rel
+	}
+
+	if windows {
+		return filepath.Join("..", "..", "Scripts", filename)
+	}
+
+	return filepath.Join("..", "..", "..", "bin", filename)
+}
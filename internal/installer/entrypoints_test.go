@@ -3,6 +3,7 @@ package installer_test
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -29,8 +30,8 @@ some_gui = mymod:main
 		t.Fatalf("ParseEntryPoints() error: %v", err)
 	}
 
-	if len(scripts) != 2 {
-		t.Fatalf("expected 2 console scripts, got %d", len(scripts))
+	if len(scripts) != 3 {
+		t.Fatalf("expected 3 scripts, got %d", len(scripts))
 	}
 
 	if scripts[0].Name != "ipython" {
@@ -48,6 +49,14 @@ some_gui = mymod:main
 	if scripts[1].Name != "ipython3" {
 		t.Errorf("scripts[1].Name = %q, want %q", scripts[1].Name, "ipython3")
 	}
+
+	if scripts[0].GUI || scripts[1].GUI {
+		t.Error("console_scripts entries should not be marked GUI")
+	}
+
+	if scripts[2].Name != "some_gui" || !scripts[2].GUI {
+		t.Errorf("scripts[2] = %+v, want GUI script some_gui", scripts[2])
+	}
 }
 
 func TestParseEntryPointsWithExtras(t *testing.T) {
@@ -106,8 +115,32 @@ myapp = mymod:main
 		t.Fatalf("ParseEntryPoints() error: %v", err)
 	}
 
-	if len(scripts) != 0 {
-		t.Errorf("expected 0 console scripts, got %d", len(scripts))
+	want := []installer.ConsoleScript{{Name: "myapp", Module: "mymod", Attr: "main", GUI: true}}
+	if !slices.Equal(scripts, want) {
+		t.Errorf("scripts = %+v, want %+v", scripts, want)
+	}
+}
+
+func TestScriptFilename(t *testing.T) {
+	tests := []struct {
+		name    string
+		gui     bool
+		windows bool
+		want    string
+	}{
+		{"console posix", false, false, "app"},
+		{"gui posix", true, false, "app"},
+		{"console windows", false, true, "app.py"},
+		{"gui windows", true, true, "app.pyw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := installer.ConsoleScript{Name: "app", Module: "app", Attr: "main", GUI: tt.gui}
+			if got := installer.ScriptFilename(cs, tt.windows); got != tt.want {
+				t.Errorf("ScriptFilename() = %q, want %q", got, tt.want)
+			}
+		})
 	}
 }
 
@@ -153,9 +186,9 @@ mycli = mypackage.cli:main
 		t.Fatal(err)
 	}
 
-	records, err := installer.InstallConsoleScripts(distInfo, binDir, "/usr/bin/python3")
+	records, err := installer.InstallEntryPointScripts(distInfo, binDir, "/usr/bin/python3")
 	if err != nil {
-		t.Fatalf("InstallConsoleScripts() error: %v", err)
+		t.Fatalf("InstallEntryPointScripts() error: %v", err)
 	}
 
 	if len(records) != 1 {
@@ -195,9 +228,9 @@ func TestInstallConsoleScriptsNoEntryPoints(t *testing.T) {
 	}
 
 	// No entry_points.txt file.
-	records, err := installer.InstallConsoleScripts(distInfo, binDir, "/usr/bin/python3")
+	records, err := installer.InstallEntryPointScripts(distInfo, binDir, "/usr/bin/python3")
 	if err != nil {
-		t.Fatalf("InstallConsoleScripts() error: %v", err)
+		t.Fatalf("InstallEntryPointScripts() error: %v", err)
 	}
 
 	if len(records) != 0 {
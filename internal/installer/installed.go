@@ -0,0 +1,89 @@
+package installer
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+// Installed describes a package found in site-packages.
+type Installed struct {
+	Name      string // as declared in METADATA, e.g., "Flask"
+	Version   string
+	Installer string // contents of INSTALLER, e.g., "pipg"; empty if absent
+	DistInfo  string // path to the .dist-info directory
+}
+
+// Installed scans site-packages, and platlib when it is separate, for
+// .dist-info directories and reads the name and version from each METADATA
+// file. Entries with missing or malformed METADATA are skipped and logged at
+// debug level.
+func (s *Service) Installed() ([]Installed, error) {
+	dirs, err := distInfoDirs(s.env.LibDirs())
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Installed
+
+	for _, dir := range dirs {
+		info, err := readMetadata(dir)
+		if err != nil {
+			s.logger.Debug("skipping dist-info", slog.String("dist_info", dir), slog.String("error", err.Error()))
+
+			continue
+		}
+
+		installer, _ := os.ReadFile(filepath.Join(dir, "INSTALLER"))
+
+		pkgs = append(pkgs, Installed{
+			Name:      info.Name,
+			Version:   info.Version,
+			Installer: strings.TrimSpace(string(installer)),
+			DistInfo:  dir,
+		})
+	}
+
+	return pkgs, nil
+}
+
+// distInfoDirs returns the .dist-info directories in libDirs.
+func distInfoDirs(libDirs []string) ([]string, error) {
+	var dirs []string
+
+	for _, siteDir := range libDirs {
+		matches, err := filepath.Glob(filepath.Join(siteDir, "*.dist-info"))
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", siteDir, err)
+		}
+
+		dirs = append(dirs, matches...)
+	}
+
+	return dirs, nil
+}
+
+// readMetadata parses the METADATA file of a .dist-info directory and
+// requires it to declare both a name and a version.
+func readMetadata(distInfoDir string) (pypi.Info, error) {
+	f, err := os.Open(filepath.Join(distInfoDir, "METADATA"))
+	if err != nil {
+		return pypi.Info{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := pypi.ParseMetadata(f)
+	if err != nil {
+		return pypi.Info{}, err
+	}
+
+	if info.Name == "" || info.Version == "" {
+		return pypi.Info{}, fmt.Errorf("METADATA lacks Name or Version")
+	}
+
+	return info, nil
+}
@@ -0,0 +1,65 @@
+package installer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+func TestInstalled(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "Flask-3.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"flask/__init__.py":              "# flask\n",
+		"flask-3.0.0.dist-info/METADATA": "Metadata-Version: 2.1\nName: Flask\nVersion: 3.0.0\n",
+	})
+
+	svc := installer.New(env)
+	if err := svc.Install(context.Background(), []downloader.Result{{Name: "flask", Version: "3.0.0", FilePath: wheelPath}}); err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	// Installed by another tool, and a broken entry without METADATA.
+	for dir, metadata := range map[string]string{
+		"six-1.16.0.dist-info": "Name: six\nVersion: 1.16.0\n",
+		"broken-1.0.dist-info": "",
+	} {
+		path := filepath.Join(env.SitePackages, dir)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if metadata != "" {
+			if err := os.WriteFile(filepath.Join(path, "METADATA"), []byte(metadata), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	pkgs, err := svc.Installed()
+	if err != nil {
+		t.Fatalf("Installed() error: %v", err)
+	}
+
+	got := make(map[string]installer.Installed, len(pkgs))
+	for _, p := range pkgs {
+		got[p.Name] = p
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Installed() = %+v, want Flask and six only", pkgs)
+	}
+
+	if p := got["Flask"]; p.Version != "3.0.0" || p.Installer != "pipg" {
+		t.Errorf("Flask = %+v, want version 3.0.0 installed by pipg", p)
+	}
+
+	if p := got["six"]; p.Version != "1.16.0" || p.Installer != "" {
+		t.Errorf("six = %+v, want version 1.16.0 with no installer", p)
+	}
+}
@@ -3,12 +3,15 @@ package installer
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/bilusteknoloji/pipg/internal/downloader"
 	"github.com/bilusteknoloji/pipg/internal/python"
@@ -19,6 +22,20 @@ type Installer interface {
 	Install(ctx context.Context, downloads []downloader.Result) error
 }
 
+// PartialError reports a failed Install along with the packages that were
+// fully installed before the failure.
+type PartialError struct {
+	Installed []string
+	Err       error
+}
+
+func (e *PartialError) Error() string { return e.Err.Error() }
+func (e *PartialError) Unwrap() error { return e.Err }
+
+// LevelTrace is the log level for per-file install details (category and mode),
+// one step more verbose than slog.LevelDebug.
+const LevelTrace = slog.LevelDebug - 4
+
 // Option configures a Service.
 type Option func(*Service)
 
@@ -31,10 +48,136 @@ func WithLogger(l *slog.Logger) Option {
 	}
 }
 
+// WithWriteRecord controls whether RECORD and INSTALLER are written to the
+// dist-info directory (default true). Disabling it also skips hashing every
+// extracted file, which speeds up throwaway installs such as container layers
+// that are frozen right after installation. Without RECORD, pip and pipg
+// cannot uninstall or upgrade the package cleanly.
+func WithWriteRecord(write bool) Option {
+	return func(s *Service) {
+		s.writeRecord = write
+	}
+}
+
+// WithFlat switches to a flat layout for zipapps: only importable package
+// code, including .data/purelib and .data/platlib, is extracted into
+// site-packages. Dist-info directories, scripts, data and headers are skipped,
+// and no RECORD, INSTALLER or console scripts are written, so the result
+// cannot be listed, upgraded or uninstalled by pip or pipg.
+func WithFlat(flat bool) Option {
+	return func(s *Service) {
+		s.flat = flat
+	}
+}
+
+// WithCompile byte-compiles each installed package's .py files into
+// __pycache__ with the environment's interpreter, like pip, so first imports
+// are fast. The .pyc files are added to RECORD. Files that fail to compile
+// are logged as a warning and do not fail the install. Off by default.
+func WithCompile(compile bool) Option {
+	return func(s *Service) {
+		s.compile = compile
+	}
+}
+
+// WithCommandRunner sets the function used to run the interpreter for
+// WithCompile. Defaults to running the command and collecting its output.
+func WithCommandRunner(fn python.CommandRunner) Option {
+	return func(s *Service) {
+		if fn != nil {
+			s.runCmd = fn
+		}
+	}
+}
+
+// WithAtomic makes Install all-or-nothing: when a package fails, the packages
+// installed before it in the same call are rolled back too, restoring any
+// files they overwrote or removed. Without it, only the failing package is
+// rolled back.
+func WithAtomic(atomic bool) Option {
+	return func(s *Service) {
+		s.atomic = atomic
+	}
+}
+
+// WithStrictConflicts fails a wheel that would overwrite a file another
+// installed package lists in its RECORD with different content. By default
+// each such file is overwritten and logged as a warning.
+func WithStrictConflicts(strict bool) Option {
+	return func(s *Service) {
+		s.strictConflicts = strict
+	}
+}
+
+// WithExtractRetries retries a file extraction up to n more times when it
+// fails with a transient error (EBUSY, ETXTBSY, EAGAIN, or a sharing or lock
+// violation on Windows), as happens when antivirus software or an NFS mount
+// briefly holds the file. Other errors,
+// such as ENOSPC or EACCES, fail immediately. Negative values are ignored;
+// the default is 0.
+func WithExtractRetries(n int) Option {
+	return func(s *Service) {
+		if n >= 0 {
+			s.extractRetries = n
+		}
+	}
+}
+
+// WithDirectURLs names the packages installed from direct URL requirements
+// ("name @ url"). Only they get a PEP 610 direct_url.json recording the URL of
+// the downloaded wheel; the PEP forbids it for packages from an index or
+// --find-links, which tools like pip freeze would then report as URL pins.
+func WithDirectURLs(names []string) Option {
+	return func(s *Service) {
+		s.directURLs = make(map[string]bool, len(names))
+		for _, name := range names {
+			s.directURLs[distName(name)] = true
+		}
+	}
+}
+
+// ScriptStyle selects the form of generated entry point wrapper scripts.
+type ScriptStyle int
+
+const (
+	// ScriptPlain imports the entry point and calls it, like pip. It is the
+	// default.
+	ScriptPlain ScriptStyle = iota
+	// ScriptPkgResources pins the installing distribution with __requires__
+	// and loads the entry point through pkg_resources; see
+	// GeneratePkgResourcesScript. It needs setuptools at run time.
+	ScriptPkgResources
+)
+
+// WithScriptStyle sets the form of generated console and GUI scripts.
+// Unknown styles are ignored.
+func WithScriptStyle(style ScriptStyle) Option {
+	return func(s *Service) {
+		if style == ScriptPlain || style == ScriptPkgResources {
+			s.scriptStyle = style
+		}
+	}
+}
+
+// extractRetryDelay is the wait before the first extraction retry; each
+// further retry waits one delay longer.
+const extractRetryDelay = 50 * time.Millisecond
+
 // Service handles extracting wheel files into site-packages.
 type Service struct {
-	env    *python.Environment
-	logger *slog.Logger
+	env             *python.Environment
+	writeRecord     bool
+	flat            bool
+	compile         bool
+	atomic          bool
+	strictConflicts bool
+	scriptStyle     ScriptStyle
+	extractRetries  int
+	directURLs      map[string]bool // distName of packages from direct URLs
+	retryDelay      time.Duration
+	createFile      func(path string) (io.WriteCloser, error)
+	runCmd          python.CommandRunner
+	logger          *slog.Logger
 }
 
 // compile-time proof that Service implements Installer.
@@ -43,8 +186,12 @@ var _ Installer = (*Service)(nil)
 // New creates a new wheel installer targeting the given Python environment.
 func New(env *python.Environment, opts ...Option) *Service {
 	s := &Service{
-		env:    env,
-		logger: slog.Default(),
+		env:         env,
+		writeRecord: true,
+		retryDelay:  extractRetryDelay,
+		createFile:  func(path string) (io.WriteCloser, error) { return os.Create(path) },
+		runCmd:      defaultRunCmd,
+		logger:      slog.Default(),
 	}
 
 	for _, opt := range opts {
@@ -54,36 +201,149 @@ func New(env *python.Environment, opts ...Option) *Service {
 	return s
 }
 
-// Install extracts all downloaded wheel files into site-packages.
-// It handles .data directories, writes RECORD and INSTALLER files,
-// and sets executable permissions on scripts.
+// Install extracts all downloaded wheel files into site-packages. It handles
+// .data directories, writes RECORD and INSTALLER files, sets executable
+// permissions on scripts, and removes files that a previously installed
+// version listed in its RECORD but the new version no longer ships.
+//
+// Errors are returned as a *PartialError listing the packages installed
+// before the failure. A package that fails is rolled back, and with
+// WithAtomic so are the ones before it. A wheel that would overwrite another
+// package's files with different content logs a warning, or fails with
+// WithStrictConflicts. Downloads naming the same package twice are rejected
+// up front.
 func (s *Service) Install(ctx context.Context, downloads []downloader.Result) error {
+	if err := checkDuplicates(downloads); err != nil {
+		return &PartialError{Err: err}
+	}
+
+	var installed []string
+	var pending []*transaction
+	var owners fileOwners
+
+	if !s.flat && len(downloads) > 0 {
+		owners = s.installedOwners()
+	}
+
 	for _, dl := range downloads {
 		if err := ctx.Err(); err != nil {
-			return fmt.Errorf("installation canceled: %w", err)
+			return &PartialError{Installed: s.rollbackAll(installed, pending), Err: fmt.Errorf("installation canceled: %w", err)}
+		}
+
+		tx := newTransaction(s.libDir(true))
+
+		if err := s.installWheel(ctx, dl, tx, owners); err != nil {
+			s.rollback(dl.Name, tx)
+
+			return &PartialError{Installed: s.rollbackAll(installed, pending), Err: fmt.Errorf("installing %s: %w", dl.Name, err)}
 		}
 
-		if err := s.installWheel(dl); err != nil {
-			return fmt.Errorf("installing %s: %w", dl.Name, err)
+		if s.atomic {
+			pending = append(pending, tx)
+		} else {
+			s.commit(tx)
 		}
 
+		installed = append(installed, dl.Name)
 		s.logger.Debug("installed", slog.String("package", dl.Name))
 	}
 
+	for _, tx := range pending {
+		s.commit(tx)
+	}
+
+	return nil
+}
+
+// checkDuplicates returns an error if two downloads are the same package by
+// normalized name; installing both would leave whichever came last with a
+// RECORD that does not match what is on disk.
+func checkDuplicates(downloads []downloader.Result) error {
+	seen := make(map[string]downloader.Result, len(downloads))
+
+	for _, dl := range downloads {
+		key := distName(dl.Name)
+
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("%s requested at both %s and %s", dl.Name, first.Version, dl.Version)
+		}
+
+		seen[key] = dl
+	}
+
+	return nil
+}
+
+// rollbackAll rolls back the pending transactions of an atomic install, last
+// first, and returns the packages that remain installed.
+func (s *Service) rollbackAll(installed []string, pending []*transaction) []string {
+	if !s.atomic {
+		return installed
+	}
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		s.rollback(installed[i], pending[i])
+	}
+
 	return nil
 }
 
-// installWheel extracts a single wheel file into site-packages.
-func (s *Service) installWheel(dl downloader.Result) error {
+func (s *Service) rollback(name string, tx *transaction) {
+	if err := tx.rollback(); err != nil {
+		s.logger.Warn("rollback incomplete", slog.String("package", name), slog.String("error", err.Error()))
+
+		return
+	}
+
+	s.logger.Debug("rolled back", slog.String("package", name))
+}
+
+func (s *Service) commit(tx *transaction) {
+	if err := tx.commit(); err != nil {
+		s.logger.Debug("removing rollback backups", slog.String("error", err.Error()))
+	}
+}
+
+// installWheel extracts a single wheel file into site-packages, journaling
+// every change in tx. owners indexes the files of installed packages and is
+// updated with the files this wheel installs.
+func (s *Service) installWheel(ctx context.Context, dl downloader.Result, tx *transaction, owners fileOwners) error {
 	r, err := zip.OpenReader(dl.FilePath)
 	if err != nil {
 		return fmt.Errorf("opening wheel %s: %w", dl.FilePath, err)
 	}
 	defer func() { _ = r.Close() }()
 
-	siteDir := s.env.SitePackages
+	info, err := readWheelInfo(&r.Reader)
+	if err != nil {
+		return fmt.Errorf("reading WHEEL of %s: %w", dl.FilePath, err)
+	}
+
+	newerMinor, err := info.checkVersion()
+	if err != nil {
+		return fmt.Errorf("installing %s: %w", dl.FilePath, err)
+	}
+
+	if newerMinor {
+		s.logger.Warn("wheel format is newer than supported, installing anyway",
+			slog.String("file", dl.FilePath), slog.String("wheel_version", info.Version))
+	}
+
+	siteDir := s.libDir(info.RootIsPurelib)
+
+	if s.flat {
+		_, _, err := s.extractWheelFiles(r, siteDir, tx)
+
+		return err
+	}
+
+	if err := s.checkConflicts(&r.Reader, siteDir, dl.Name, owners); err != nil {
+		return err
+	}
+
+	previous := s.previousFiles(dl.Name)
 
-	records, distInfoDir, err := s.extractWheelFiles(r, siteDir)
+	records, distInfoDir, err := s.extractWheelFiles(r, siteDir, tx)
 	if err != nil {
 		return err
 	}
@@ -92,11 +352,26 @@ func (s *Service) installWheel(dl downloader.Result) error {
 		return fmt.Errorf("no .dist-info directory found in %s", dl.FilePath)
 	}
 
-	return s.finalizeInstall(siteDir, distInfoDir, records)
+	if s.compile {
+		records = append(records, s.byteCompile(ctx, siteDir, records, tx)...)
+	}
+
+	written, err := s.finalizeInstall(siteDir, distInfoDir, records, dl, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.removeStale(siteDir, distInfoDir, previous, written, tx); err != nil {
+		return fmt.Errorf("removing files from previous install: %w", err)
+	}
+
+	owners.add(distName(dl.Name), siteDir, written)
+
+	return nil
 }
 
 // extractWheelFiles extracts all files from a wheel archive and returns records and dist-info dir.
-func (s *Service) extractWheelFiles(r *zip.ReadCloser, siteDir string) ([]RecordEntry, string, error) {
+func (s *Service) extractWheelFiles(r *zip.ReadCloser, siteDir string, tx *transaction) ([]RecordEntry, string, error) {
 	var records []RecordEntry
 	var distInfoDir string
 
@@ -105,7 +380,7 @@ func (s *Service) extractWheelFiles(r *zip.ReadCloser, siteDir string) ([]Record
 			continue
 		}
 
-		entry, dir, err := s.processWheelEntry(f, siteDir)
+		entry, dir, err := s.processWheelEntry(f, siteDir, tx)
 		if err != nil {
 			return nil, "", err
 		}
@@ -123,22 +398,35 @@ func (s *Service) extractWheelFiles(r *zip.ReadCloser, siteDir string) ([]Record
 }
 
 // processWheelEntry extracts a single file from the wheel and returns its record entry.
-func (s *Service) processWheelEntry(f *zip.File, siteDir string) (*RecordEntry, string, error) {
+func (s *Service) processWheelEntry(f *zip.File, siteDir string, tx *transaction) (*RecordEntry, string, error) {
 	destPath, category := s.resolveDestination(f.Name, siteDir, ".data/")
 	if destPath == "" {
 		return nil, "", nil
 	}
 
+	// The wheel's own RECORD would otherwise be left behind as if pipg had written it.
+	if !s.writeRecord && isDistInfoRecord(f.Name) {
+		return nil, "", nil
+	}
+
+	if s.flat && (!category.importable() || isDistInfoEntry(f.Name)) {
+		return nil, "", nil
+	}
+
 	base := s.baseForCategory(category, siteDir)
 	if !isInsideDir(destPath, base) {
 		return nil, "", fmt.Errorf("zip slip detected: %s resolves outside %s", f.Name, base)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+	if err := tx.mkdirAll(filepath.Dir(destPath)); err != nil {
 		return nil, "", fmt.Errorf("creating directory for %s: %w", f.Name, err)
 	}
 
-	if err := extractFile(f, destPath); err != nil {
+	if err := tx.track(destPath); err != nil {
+		return nil, "", fmt.Errorf("extracting %s: %w", f.Name, err)
+	}
+
+	if err := s.extractWithRetry(f, destPath); err != nil {
 		return nil, "", fmt.Errorf("extracting %s: %w", f.Name, err)
 	}
 
@@ -148,9 +436,14 @@ func (s *Service) processWheelEntry(f *zip.File, siteDir string) (*RecordEntry,
 		}
 	}
 
+	s.logExtracted(f.Name, destPath, category)
+
+	// Only the top-level .dist-info directory counts; packages may vendor
+	// others (e.g., "setuptools/_vendor/packaging-24.2.dist-info/").
+	// Local versions keep their "+", e.g., "torch-2.1.0+cpu.dist-info".
 	var distInfoDir string
-	if strings.Contains(f.Name, ".dist-info/") {
-		distInfoDir = filepath.Join(siteDir, strings.SplitN(f.Name, "/", 2)[0])
+	if top, _, ok := strings.Cut(f.Name, "/"); ok && strings.HasSuffix(top, ".dist-info") {
+		distInfoDir = filepath.Join(siteDir, top)
 	}
 
 	relPath, err := filepath.Rel(siteDir, destPath)
@@ -158,6 +451,10 @@ func (s *Service) processWheelEntry(f *zip.File, siteDir string) (*RecordEntry,
 		relPath = f.Name
 	}
 
+	if !s.writeRecord || s.flat {
+		return &RecordEntry{Path: relPath}, distInfoDir, nil
+	}
+
 	hash, size, err := HashFile(destPath)
 	if err != nil {
 		return nil, "", fmt.Errorf("hashing %s: %w", destPath, err)
@@ -166,36 +463,112 @@ func (s *Service) processWheelEntry(f *zip.File, siteDir string) (*RecordEntry,
 	return &RecordEntry{Path: relPath, Hash: hash, Size: size}, distInfoDir, nil
 }
 
-// finalizeInstall writes INSTALLER, console scripts, and RECORD files.
-func (s *Service) finalizeInstall(siteDir, distInfoDir string, records []RecordEntry) error {
-	if err := WriteInstaller(distInfoDir); err != nil {
-		return fmt.Errorf("writing INSTALLER: %w", err)
+// logExtracted logs where a wheel entry was written and, at LevelTrace,
+// its category and file mode.
+func (s *Service) logExtracted(source, destPath string, category fileCategory) {
+	s.logger.Debug("extracted file", slog.String("source", source), slog.String("dest", destPath))
+
+	ctx := context.Background()
+	if !s.logger.Enabled(ctx, LevelTrace) {
+		return
+	}
+
+	attrs := []slog.Attr{slog.String("dest", destPath), slog.String("category", category.String())}
+	if info, err := os.Stat(destPath); err == nil {
+		attrs = append(attrs, slog.String("mode", info.Mode().Perm().String()))
 	}
 
-	installerPath := filepath.Join(distInfoDir, "INSTALLER")
+	s.logger.LogAttrs(ctx, LevelTrace, "file details", attrs...)
+}
+
+// finalizeInstall writes INSTALLER, direct_url.json, entry point scripts, and
+// RECORD files, and returns the entries for every file the install wrote.
+// INSTALLER, direct_url.json and RECORD are skipped when record writing is
+// disabled; direct_url.json is only written for a package named in
+// WithDirectURLs.
+func (s *Service) finalizeInstall(siteDir, distInfoDir string, records []RecordEntry, dl downloader.Result, tx *transaction) ([]RecordEntry, error) {
+	binDir := BinDir(s.env)
 
-	hash, size, err := HashFile(installerPath)
+	if !s.writeRecord {
+		scriptRecords, err := installEntryPointScripts(distInfoDir, binDir, s.scriptGenerator(dl), s.env.IsWindows(), tx)
+		if err != nil {
+			return nil, fmt.Errorf("installing entry point scripts: %w", err)
+		}
+
+		return append(records, scriptRecords...), nil
+	}
+
+	for _, name := range []string{"INSTALLER", "direct_url.json", "RECORD"} {
+		if err := tx.track(filepath.Join(distInfoDir, name)); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	if err := WriteInstaller(distInfoDir); err != nil {
+		return nil, fmt.Errorf("writing INSTALLER: %w", err)
+	}
+
+	entry, err := fileRecord(siteDir, filepath.Join(distInfoDir, "INSTALLER"))
 	if err != nil {
-		return fmt.Errorf("hashing INSTALLER: %w", err)
+		return nil, fmt.Errorf("hashing INSTALLER: %w", err)
 	}
 
-	relInstaller, _ := filepath.Rel(siteDir, installerPath)
-	records = append(records, RecordEntry{Path: relInstaller, Hash: hash, Size: size})
+	records = append(records, entry)
+
+	if dl.URL != "" && s.directURLs[distName(dl.Name)] {
+		archive := &ArchiveInfo{}
+		if dl.SHA256 != "" {
+			archive.Hash = "sha256=" + dl.SHA256
+		}
+
+		if err := WriteDirectURL(distInfoDir, DirectURL{URL: dl.URL, ArchiveInfo: archive}); err != nil {
+			return nil, fmt.Errorf("writing direct_url.json: %w", err)
+		}
+
+		entry, err := fileRecord(siteDir, filepath.Join(distInfoDir, "direct_url.json"))
+		if err != nil {
+			return nil, fmt.Errorf("hashing direct_url.json: %w", err)
+		}
 
-	binDir := filepath.Join(s.env.Prefix, "bin")
+		records = append(records, entry)
+	}
 
-	scriptRecords, err := InstallConsoleScripts(distInfoDir, binDir, s.env.PythonPath)
+	scriptRecords, err := installEntryPointScripts(distInfoDir, binDir, s.scriptGenerator(dl), s.env.IsWindows(), tx)
 	if err != nil {
-		return fmt.Errorf("installing console scripts: %w", err)
+		return nil, fmt.Errorf("installing entry point scripts: %w", err)
 	}
 
 	records = append(records, scriptRecords...)
 
 	if err := WriteRecord(distInfoDir, records); err != nil {
-		return fmt.Errorf("writing RECORD: %w", err)
+		return nil, fmt.Errorf("writing RECORD: %w", err)
 	}
 
-	return nil
+	return records, nil
+}
+
+// scriptGenerator returns the wrapper generator for dl's entry points in the
+// configured script style.
+func (s *Service) scriptGenerator(dl downloader.Result) func(ConsoleScript) []byte {
+	if s.scriptStyle == ScriptPkgResources {
+		requirement := dl.Name + "==" + dl.Version
+
+		return func(cs ConsoleScript) []byte { return GeneratePkgResourcesScript(s.env.PythonPath, requirement, cs) }
+	}
+
+	return func(cs ConsoleScript) []byte { return GenerateScript(s.env.PythonPath, cs) }
+}
+
+// fileRecord returns the RECORD entry for a file pipg wrote under siteDir.
+func fileRecord(siteDir, path string) (RecordEntry, error) {
+	hash, size, err := HashFile(path)
+	if err != nil {
+		return RecordEntry{}, err
+	}
+
+	rel, _ := filepath.Rel(siteDir, path)
+
+	return RecordEntry{Path: rel, Hash: hash, Size: size}, nil
 }
 
 // fileCategory describes where a wheel entry should be extracted.
@@ -203,19 +576,60 @@ type fileCategory int
 
 const (
 	categorySitePackages fileCategory = iota
+	categoryPurelib
+	categoryPlatlib
 	categoryScripts
 	categoryData
+	categoryHeaders
 	categorySkip
 )
 
-// resolveDestination determines the target path for a wheel entry.
+func (c fileCategory) String() string {
+	switch c {
+	case categorySitePackages:
+		return "site-packages"
+	case categoryPurelib:
+		return "purelib"
+	case categoryPlatlib:
+		return "platlib"
+	case categoryScripts:
+		return "scripts"
+	case categoryData:
+		return "data"
+	case categoryHeaders:
+		return "headers"
+	default:
+		return "skip"
+	}
+}
+
+// importable reports whether entries of the category land on sys.path.
+func (c fileCategory) importable() bool {
+	return c == categorySitePackages || c == categoryPurelib || c == categoryPlatlib
+}
+
+// libDir returns the purelib or platlib directory. Both are site-packages
+// unless the environment has a separate platlib (e.g., lib64 on Fedora).
+func (s *Service) libDir(purelib bool) string {
+	if purelib || s.env.PlatLib == "" {
+		return s.env.SitePackages
+	}
+
+	return s.env.PlatLib
+}
+
+// resolveDestination determines the target path for a wheel entry. siteDir
+// is the wheel's root directory, purelib or platlib per Root-Is-Purelib.
 // Wheel entries can be:
-//   - Regular files → site-packages/
-//   - .data/purelib/* → site-packages/
-//   - .data/platlib/* → site-packages/
-//   - .data/scripts/* → prefix/bin/
-//   - .data/data/* → prefix/
-//   - .data/headers/* → prefix/include/
+//   - Regular files → siteDir/
+//   - .data/purelib/* → purelib/
+//   - .data/platlib/* → platlib/
+//   - .data/scripts/* → scripts/ (prefix/bin/)
+//   - .data/data/* → data/ (prefix/)
+//   - .data/headers/* → include/ (prefix/include/)
+//
+// The scripts, data and include directories come from the interpreter's
+// sysconfig install scheme, falling back to the prefix-based paths shown.
 func (s *Service) resolveDestination(name, siteDir, dataSuffix string) (string, fileCategory) {
 	// Check if this is a .data directory entry.
 	dataIdx := strings.Index(name, dataSuffix)
@@ -240,14 +654,16 @@ func (s *Service) resolveDestination(name, siteDir, dataSuffix string) (string,
 	}
 
 	switch subdir {
-	case "purelib", "platlib":
-		return filepath.Join(siteDir, rest), categorySitePackages
+	case "purelib":
+		return filepath.Join(s.libDir(true), rest), categoryPurelib
+	case "platlib":
+		return filepath.Join(s.libDir(false), rest), categoryPlatlib
 	case "scripts":
-		return filepath.Join(s.env.Prefix, "bin", rest), categoryScripts
+		return filepath.Join(BinDir(s.env), rest), categoryScripts
 	case "data":
-		return filepath.Join(s.env.Prefix, rest), categoryData
+		return filepath.Join(s.dataDir(), rest), categoryData
 	case "headers":
-		return filepath.Join(s.env.Prefix, "include", rest), categoryData
+		return filepath.Join(s.includeDir(), rest), categoryHeaders
 	default:
 		return "", categorySkip
 	}
@@ -258,22 +674,90 @@ func (s *Service) baseForCategory(cat fileCategory, siteDir string) string {
 	switch cat {
 	case categorySitePackages:
 		return siteDir
-	case categoryScripts, categoryData:
-		return s.env.Prefix
+	case categoryPurelib:
+		return s.libDir(true)
+	case categoryPlatlib:
+		return s.libDir(false)
+	case categoryScripts:
+		return BinDir(s.env)
+	case categoryData:
+		return s.dataDir()
+	case categoryHeaders:
+		return s.includeDir()
 	default:
 		return siteDir
 	}
 }
 
-// extractFile extracts a single file from the zip archive.
-func extractFile(f *zip.File, destPath string) error {
+// dataDir returns the install scheme's data directory, prefix by default.
+func (s *Service) dataDir() string {
+	if s.env.DataDir != "" {
+		return s.env.DataDir
+	}
+
+	return s.env.Prefix
+}
+
+// includeDir returns the install scheme's include directory, prefix/include
+// by default.
+func (s *Service) includeDir() string {
+	if s.env.IncludeDir != "" {
+		return s.env.IncludeDir
+	}
+
+	return filepath.Join(s.env.Prefix, "include")
+}
+
+// UncompressedSize returns the total size of the files in the wheel at
+// wheelPath, read from its zip central directory: roughly the disk space the
+// wheel takes once installed.
+func UncompressedSize(wheelPath string) (int64, error) {
+	r, err := zip.OpenReader(wheelPath)
+	if err != nil {
+		return 0, fmt.Errorf("opening wheel %s: %w", wheelPath, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	var total uint64
+	for _, f := range r.File {
+		total += f.UncompressedSize64
+	}
+
+	return int64(total), nil
+}
+
+// extractWithRetry extracts f to destPath, retrying transient filesystem
+// errors as configured by WithExtractRetries.
+func (s *Service) extractWithRetry(f *zip.File, destPath string) error {
+	for attempt := 1; ; attempt++ {
+		err := extractFile(f, destPath, s.createFile)
+		if err == nil || attempt > s.extractRetries || !isTransient(err) {
+			return err
+		}
+
+		s.logger.Debug("retrying extraction",
+			slog.String("dest", destPath), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+		time.Sleep(time.Duration(attempt) * s.retryDelay)
+	}
+}
+
+// isTransient reports whether err is a filesystem error worth retrying:
+// the file is briefly busy or locked rather than permanently unwritable.
+func isTransient(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.ETXTBSY) || errors.Is(err, syscall.EAGAIN) ||
+		isLockedByOtherProcess(err)
+}
+
+// extractFile extracts a single file from the zip archive, opening the
+// destination with create.
+func extractFile(f *zip.File, destPath string, create func(string) (io.WriteCloser, error)) error {
 	src, err := f.Open()
 	if err != nil {
 		return fmt.Errorf("opening zip entry: %w", err)
 	}
 	defer func() { _ = src.Close() }()
 
-	dst, err := os.Create(destPath)
+	dst, err := create(destPath)
 	if err != nil {
 		return fmt.Errorf("creating %s: %w", destPath, err)
 	}
@@ -287,6 +771,22 @@ func extractFile(f *zip.File, destPath string) error {
 	return dst.Close()
 }
 
+// isDistInfoRecord reports whether a wheel entry is the RECORD file of its
+// top-level .dist-info directory, e.g., "six-1.16.0.dist-info/RECORD".
+func isDistInfoRecord(name string) bool {
+	dir, file, ok := strings.Cut(name, "/")
+
+	return ok && file == "RECORD" && strings.HasSuffix(dir, ".dist-info")
+}
+
+// isDistInfoEntry reports whether a wheel entry belongs to the wheel's
+// top-level .dist-info directory.
+func isDistInfoEntry(name string) bool {
+	top, _, ok := strings.Cut(name, "/")
+
+	return ok && strings.HasSuffix(top, ".dist-info")
+}
+
 // isInsideDir checks that path is inside dir after resolving symlinks.
 func isInsideDir(path, dir string) bool {
 	absPath, err := filepath.Abs(path)
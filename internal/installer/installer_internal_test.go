@@ -0,0 +1,241 @@
+package installer
+
+import (
+	"archive/zip"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/python"
+)
+
+func TestExtractWheelFilesSkipsHashing(t *testing.T) {
+	dir := t.TempDir()
+	wheelPath := filepath.Join(dir, "six-1.16.0-py3-none-any.whl")
+
+	f, err := os.Create(wheelPath)
+	if err != nil {
+		t.Fatalf("creating wheel file: %v", err)
+	}
+
+	w := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"six.py":                        "# six\n",
+		"six-1.16.0.dist-info/METADATA": "Name: six\n",
+	} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("creating zip entry %s: %v", name, err)
+		}
+
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatalf("writing zip entry %s: %v", name, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing zip writer: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("closing wheel file: %v", err)
+	}
+
+	for _, write := range []bool{true, false} {
+		siteDir := filepath.Join(t.TempDir(), "site-packages")
+		s := New(&python.Environment{Prefix: filepath.Dir(siteDir), SitePackages: siteDir}, WithWriteRecord(write))
+
+		r, err := zip.OpenReader(wheelPath)
+		if err != nil {
+			t.Fatalf("opening wheel: %v", err)
+		}
+
+		records, _, err := s.extractWheelFiles(r, siteDir, nil)
+		_ = r.Close()
+
+		if err != nil {
+			t.Fatalf("extractWheelFiles() error: %v", err)
+		}
+
+		for _, rec := range records {
+			if hashed := rec.Hash != ""; hashed != write {
+				t.Errorf("writeRecord=%v: %s hashed=%v", write, rec.Path, hashed)
+			}
+		}
+	}
+}
+
+// flakyWriter fails its first write with err, like a file briefly locked by
+// antivirus software, and writes normally afterwards.
+type flakyWriter struct {
+	file *os.File
+	fail *int
+	err  error
+}
+
+func (w flakyWriter) Write(p []byte) (int, error) {
+	if *w.fail > 0 {
+		*w.fail--
+
+		return 0, &os.PathError{Op: "write", Path: w.file.Name(), Err: w.err}
+	}
+
+	return w.file.Write(p)
+}
+
+func (w flakyWriter) Close() error { return w.file.Close() }
+
+func TestExtractRetriesTransientErrors(t *testing.T) {
+	dir := t.TempDir()
+	wheelPath := filepath.Join(dir, "six-1.16.0-py3-none-any.whl")
+
+	f, err := os.Create(wheelPath)
+	if err != nil {
+		t.Fatalf("creating wheel file: %v", err)
+	}
+
+	w := zip.NewWriter(f)
+
+	fw, err := w.Create("six.py")
+	if err != nil {
+		t.Fatalf("creating zip entry: %v", err)
+	}
+
+	if _, err := fw.Write([]byte("# six\n")); err != nil {
+		t.Fatalf("writing zip entry: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing zip writer: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("closing wheel file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		retries int
+		wantErr bool
+		wantOps int
+	}{
+		{"transient error retried", syscall.EBUSY, 2, false, 2},
+		{"transient error without retries", syscall.EBUSY, 0, true, 1},
+		{"permanent error not retried", syscall.ENOSPC, 2, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteDir := t.TempDir()
+			s := New(&python.Environment{Prefix: siteDir, SitePackages: siteDir},
+				WithExtractRetries(tt.retries), WithLogger(slog.New(slog.DiscardHandler)))
+			s.retryDelay = 0
+
+			fail := 1
+			opens := 0
+			s.createFile = func(path string) (io.WriteCloser, error) {
+				opens++
+
+				file, err := os.Create(path)
+				if err != nil {
+					return nil, err
+				}
+
+				return flakyWriter{file: file, fail: &fail, err: tt.err}, nil
+			}
+
+			r, err := zip.OpenReader(wheelPath)
+			if err != nil {
+				t.Fatalf("opening wheel: %v", err)
+			}
+			defer func() { _ = r.Close() }()
+
+			err = s.extractWithRetry(r.File[0], filepath.Join(siteDir, "six.py"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if opens != tt.wantOps {
+				t.Errorf("attempts = %d, want %d", opens, tt.wantOps)
+			}
+
+			if !tt.wantErr {
+				if data, _ := os.ReadFile(filepath.Join(siteDir, "six.py")); string(data) != "# six\n" {
+					t.Errorf("six.py = %q after retry", data)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactionRollbackRestoresFilesSymlinksAndDirs(t *testing.T) {
+	root := t.TempDir()
+
+	file := filepath.Join(root, "pkg", "mod.py")
+	link := filepath.Join(root, "pkg", "current")
+	pruned := filepath.Join(root, "old", "sub")
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(pruned, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("mod.py", link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tx := newTransaction(root)
+
+	for _, path := range []string{file, link} {
+		if err := tx.track(path); err != nil {
+			t.Fatalf("track(%s) error: %v", path, err)
+		}
+
+		if err := os.WriteFile(path, []byte("new\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The backups were moved, not copied, into a directory under root.
+	matches, _ := filepath.Glob(filepath.Join(root, ".pipg-rollback-*"))
+	if len(matches) != 1 {
+		t.Errorf("backup directories under root = %v, want one", matches)
+	}
+
+	removeEmptyParents(pruned, root, tx)
+
+	if _, err := os.Stat(filepath.Join(root, "old")); !os.IsNotExist(err) {
+		t.Fatalf("empty directories not pruned: %v", err)
+	}
+
+	if err := tx.rollback(); err != nil {
+		t.Fatalf("rollback() error: %v", err)
+	}
+
+	if data, err := os.ReadFile(file); err != nil || string(data) != "old\n" {
+		t.Errorf("mod.py = %q, %v; want the old content", data, err)
+	}
+
+	if target, err := os.Readlink(link); err != nil || target != "mod.py" {
+		t.Errorf("symlink target = %q, %v; want mod.py", target, err)
+	}
+
+	if info, err := os.Stat(pruned); err != nil || !info.IsDir() {
+		t.Errorf("pruned directory not restored: %v", err)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(root, ".pipg-rollback-*")); len(matches) != 0 {
+		t.Errorf("backup directories left behind: %v", matches)
+	}
+}
@@ -2,11 +2,17 @@ package installer_test
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bilusteknoloji/pipg/internal/downloader"
 	"github.com/bilusteknoloji/pipg/internal/installer"
@@ -45,6 +51,38 @@ func createWheel(t *testing.T, path string, entries map[string]string) {
 	}
 }
 
+// createOrderedWheel is createWheel with a fixed entry order, for tests that
+// depend on which entries are extracted before a failing one.
+func createOrderedWheel(t *testing.T, path string, entries [][2]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creating wheel file: %v", err)
+	}
+
+	w := zip.NewWriter(f)
+
+	for _, e := range entries {
+		fw, err := w.Create(e[0])
+		if err != nil {
+			t.Fatalf("creating zip entry %s: %v", e[0], err)
+		}
+
+		if _, err := fw.Write([]byte(e[1])); err != nil {
+			t.Fatalf("writing zip entry %s: %v", e[0], err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing zip writer: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("closing wheel file: %v", err)
+	}
+}
+
 func testEnv(t *testing.T) *python.Environment {
 	t.Helper()
 
@@ -227,6 +265,54 @@ func TestInstallWithDataDirectory(t *testing.T) {
 	}
 }
 
+func TestInstallLogsExtractedFiles(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "mypkg-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"mypkg/__init__.py":                      "# mypkg\n",
+		"mypkg-1.0.0.dist-info/METADATA":         "Name: mypkg\nVersion: 1.0.0\n",
+		"mypkg-1.0.0.data/scripts/mypkg-cli":     "#!/usr/bin/env python3\n",
+		"mypkg-1.0.0.data/headers/mypkg/mypkg.h": "#include <stdio.h>\n",
+	})
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: installer.LevelTrace}))
+	svc := installer.New(env, installer.WithLogger(logger))
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "mypkg", Version: "1.0.0", FilePath: wheelPath},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	out := buf.String()
+
+	for _, dest := range []string{
+		filepath.Join(env.SitePackages, "mypkg", "__init__.py"),
+		filepath.Join(env.SitePackages, "mypkg-1.0.0.dist-info", "METADATA"),
+		filepath.Join(env.Prefix, "bin", "mypkg-cli"),
+		filepath.Join(env.Prefix, "include", "mypkg", "mypkg.h"),
+	} {
+		if !strings.Contains(out, "dest="+dest) {
+			t.Errorf("expected destination %s in log output:\n%s", dest, out)
+		}
+	}
+
+	for _, want := range []string{
+		"source=mypkg-1.0.0.data/scripts/mypkg-cli",
+		"category=scripts mode=-rwxr-xr-x",
+		"category=headers",
+		"category=site-packages",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log output:\n%s", want, out)
+		}
+	}
+}
+
 func TestInstallMultiplePackages(t *testing.T) {
 	env := testEnv(t)
 	wheelDir := t.TempDir()
@@ -311,6 +397,270 @@ func TestInstallInvalidWheelFile(t *testing.T) {
 	}
 }
 
+func TestInstallPartialError(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+
+	goodPath := filepath.Join(wheelDir, "good-1.0.0-py3-none-any.whl")
+	createWheel(t, goodPath, map[string]string{
+		"good/__init__.py":              "# good\n",
+		"good-1.0.0.dist-info/METADATA": "Name: good\nVersion: 1.0.0\n",
+	})
+
+	badPath := filepath.Join(wheelDir, "bad-1.0.0-py3-none-any.whl")
+	if err := os.WriteFile(badPath, []byte("not a zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "good", Version: "1.0.0", FilePath: goodPath},
+		{Name: "bad", Version: "1.0.0", FilePath: badPath},
+	})
+
+	var pe *installer.PartialError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected *installer.PartialError, got %T: %v", err, err)
+	}
+
+	if len(pe.Installed) != 1 || pe.Installed[0] != "good" {
+		t.Errorf("Installed = %v, want [good]", pe.Installed)
+	}
+}
+
+func TestInstallRollsBackFailedWheel(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "broken-1.0.0-py3-none-any.whl")
+
+	// The first entry extracts fine; the second escapes site-packages.
+	createOrderedWheel(t, wheelPath, [][2]string{
+		{"broken/__init__.py", "# broken\n"},
+		{"broken/../../escape.py", "# escape\n"},
+		{"broken-1.0.0.dist-info/METADATA", "Name: broken\nVersion: 1.0.0\n"},
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "broken", Version: "1.0.0", FilePath: wheelPath},
+	})
+	if err == nil {
+		t.Fatal("expected an error for the failing wheel")
+	}
+
+	entries, err := os.ReadDir(env.SitePackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("site-packages not clean after rollback: %v", entries)
+	}
+}
+
+func TestInstallRollbackRestoresPreviousVersion(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+
+	v1 := filepath.Join(wheelDir, "mod-1.0.0-py3-none-any.whl")
+	createWheel(t, v1, map[string]string{
+		"mod/__init__.py":              "# v1\n",
+		"mod-1.0.0.dist-info/METADATA": "Name: mod\nVersion: 1.0.0\n",
+	})
+
+	v2 := filepath.Join(wheelDir, "mod-2.0.0-py3-none-any.whl")
+	createOrderedWheel(t, v2, [][2]string{
+		{"mod/__init__.py", "# v2\n"},
+		{"mod/../../escape.py", "# escape\n"},
+		{"mod-2.0.0.dist-info/METADATA", "Name: mod\nVersion: 2.0.0\n"},
+	})
+
+	svc := installer.New(env)
+
+	if err := svc.Install(context.Background(), []downloader.Result{{Name: "mod", Version: "1.0.0", FilePath: v1}}); err != nil {
+		t.Fatalf("Install(1.0.0) error: %v", err)
+	}
+
+	if err := svc.Install(context.Background(), []downloader.Result{{Name: "mod", Version: "2.0.0", FilePath: v2}}); err == nil {
+		t.Fatal("expected an error for the failing upgrade")
+	}
+
+	data, err := os.ReadFile(filepath.Join(env.SitePackages, "mod", "__init__.py"))
+	if err != nil || string(data) != "# v1\n" {
+		t.Errorf("mod/__init__.py = %q, %v; want the 1.0.0 content", data, err)
+	}
+
+	if got := installer.InstalledVersion(env.LibDirs(), "mod"); got != "1.0.0" {
+		t.Errorf("InstalledVersion() = %q, want 1.0.0", got)
+	}
+}
+
+func TestInstallAtomicRollsBackEarlierPackages(t *testing.T) {
+	wheelDir := t.TempDir()
+
+	goodPath := filepath.Join(wheelDir, "good-1.0.0-py3-none-any.whl")
+	createWheel(t, goodPath, map[string]string{
+		"good/__init__.py":              "# good\n",
+		"good-1.0.0.dist-info/METADATA": "Name: good\nVersion: 1.0.0\n",
+	})
+
+	badPath := filepath.Join(wheelDir, "bad-1.0.0-py3-none-any.whl")
+	if err := os.WriteFile(badPath, []byte("not a zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	downloads := []downloader.Result{
+		{Name: "good", Version: "1.0.0", FilePath: goodPath},
+		{Name: "bad", Version: "1.0.0", FilePath: badPath},
+	}
+
+	tests := []struct {
+		name     string
+		atomic   bool
+		wantGood bool
+	}{
+		{"default keeps earlier packages", false, true},
+		{"atomic rolls back earlier packages", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testEnv(t)
+			svc := installer.New(env, installer.WithAtomic(tt.atomic))
+
+			err := svc.Install(context.Background(), downloads)
+
+			var pe *installer.PartialError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected *installer.PartialError, got %T: %v", err, err)
+			}
+
+			if got := len(pe.Installed) == 1; got != tt.wantGood {
+				t.Errorf("Installed = %v, want good installed: %v", pe.Installed, tt.wantGood)
+			}
+
+			_, statErr := os.Stat(filepath.Join(env.SitePackages, "good", "__init__.py"))
+			if got := statErr == nil; got != tt.wantGood {
+				t.Errorf("good/__init__.py exists = %v, want %v", got, tt.wantGood)
+			}
+
+			if tt.atomic {
+				entries, _ := os.ReadDir(env.SitePackages)
+				if len(entries) != 0 {
+					t.Errorf("site-packages not clean after atomic rollback: %v", entries)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallConflictWithOtherPackage(t *testing.T) {
+	wheelDir := t.TempDir()
+
+	first := filepath.Join(wheelDir, "first-1.0.0-py3-none-any.whl")
+	createWheel(t, first, map[string]string{
+		"shared.py":                      "# first\n",
+		"backports/__init__.py":          "# namespace\n",
+		"first-1.0.0.dist-info/METADATA": "Name: first\nVersion: 1.0.0\n",
+	})
+
+	second := filepath.Join(wheelDir, "second-1.0.0-py3-none-any.whl")
+	createWheel(t, second, map[string]string{
+		"shared.py":                       "# second\n",
+		"backports/__init__.py":           "# namespace\n",
+		"second-1.0.0.dist-info/METADATA": "Name: second\nVersion: 1.0.0\n",
+	})
+
+	tests := []struct {
+		name        string
+		strict      bool
+		wantErr     bool
+		wantContent string
+	}{
+		{"warns and overwrites by default", false, false, "# second\n"},
+		{"strict refuses", true, true, "# first\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testEnv(t)
+
+			if err := installer.New(env).Install(context.Background(), []downloader.Result{
+				{Name: "first", Version: "1.0.0", FilePath: first},
+			}); err != nil {
+				t.Fatalf("Install(first) error: %v", err)
+			}
+
+			var logs bytes.Buffer
+
+			svc := installer.New(env, installer.WithStrictConflicts(tt.strict),
+				installer.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+			err := svc.Install(context.Background(), []downloader.Result{
+				{Name: "second", Version: "1.0.0", FilePath: second},
+			})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "shared.py (owned by first)") {
+					t.Errorf("Install(second) error = %v, want a conflict with first", err)
+				}
+
+				if err != nil && strings.Contains(err.Error(), "backports") {
+					t.Errorf("Install(second) error = %v, want the identical backports/__init__.py allowed", err)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Install(second) error: %v", err)
+				}
+
+				if !strings.Contains(logs.String(), "shared.py") {
+					t.Errorf("logs = %q, want a warning about shared.py", logs.String())
+				}
+			}
+
+			if strings.Contains(logs.String(), "backports") {
+				t.Errorf("logs = %q, want no warning about the identical backports/__init__.py", logs.String())
+			}
+
+			data, err := os.ReadFile(filepath.Join(env.SitePackages, "shared.py"))
+			if err != nil || string(data) != tt.wantContent {
+				t.Errorf("shared.py = %q, %v; want %q", data, err, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestInstallRejectsDuplicatePackages(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+
+	oldPath := filepath.Join(wheelDir, "flask-2.3.0-py3-none-any.whl")
+	createWheel(t, oldPath, map[string]string{
+		"flask/__init__.py":              "# 2.3.0\n",
+		"flask-2.3.0.dist-info/METADATA": "Name: flask\nVersion: 2.3.0\n",
+	})
+
+	newPath := filepath.Join(wheelDir, "Flask-3.0.0-py3-none-any.whl")
+	createWheel(t, newPath, map[string]string{
+		"flask/__init__.py":              "# 3.0.0\n",
+		"flask-3.0.0.dist-info/METADATA": "Name: Flask\nVersion: 3.0.0\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "flask", Version: "2.3.0", FilePath: oldPath},
+		{Name: "Flask", Version: "3.0.0", FilePath: newPath},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Flask requested at both 2.3.0 and 3.0.0") {
+		t.Fatalf("Install() error = %v, want a duplicate package error", err)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(env.SitePackages, "flask")); !os.IsNotExist(statErr) {
+		t.Errorf("nothing should be installed, stat error: %v", statErr)
+	}
+}
+
 func TestInstallNoDistInfo(t *testing.T) {
 	env := testEnv(t)
 	wheelDir := t.TempDir()
@@ -390,117 +740,869 @@ func TestInstallWithConsoleScripts(t *testing.T) {
 	}
 }
 
-func TestInstallEmptyDownloads(t *testing.T) {
+func TestInstallWithPkgResourcesScripts(t *testing.T) {
 	env := testEnv(t)
-	svc := installer.New(env)
+	wheelPath := filepath.Join(t.TempDir(), "mycli-1.0.0-py3-none-any.whl")
 
-	err := svc.Install(context.Background(), nil)
+	createWheel(t, wheelPath, map[string]string{
+		"mycli/cli.py":                           "def main(): pass\n",
+		"mycli-1.0.0.dist-info/METADATA":         "Name: mycli\nVersion: 1.0.0\n",
+		"mycli-1.0.0.dist-info/entry_points.txt": "[console_scripts]\nmycli = mycli.cli:main\n",
+	})
+
+	svc := installer.New(env, installer.WithScriptStyle(installer.ScriptPkgResources))
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "mycli", Version: "1.0.0", FilePath: wheelPath},
+	})
 	if err != nil {
-		t.Fatalf("Install(nil) error: %v", err)
+		t.Fatalf("Install() error: %v", err)
 	}
-}
 
-func TestWithLoggerIgnoresNil(t *testing.T) {
-	env := testEnv(t)
-	_ = installer.New(env, installer.WithLogger(nil))
+	content, err := os.ReadFile(filepath.Join(env.Prefix, "bin", "mycli"))
+	if err != nil {
+		t.Fatalf("console script not found: %v", err)
+	}
+
+	for _, want := range []string{
+		"__requires__ = 'mycli==1.0.0'",
+		"load_entry_point('mycli==1.0.0', 'console_scripts', 'mycli')()",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("script missing %q:\n%s", want, content)
+		}
+	}
+
+	if strings.Contains(string(content), "from mycli.cli import main") {
+		t.Errorf("pkg_resources script should not import the entry point directly:\n%s", content)
+	}
 }
 
-func TestInstallWithPlatlib(t *testing.T) {
+func TestInstallWithConsoleScriptsWindows(t *testing.T) {
 	env := testEnv(t)
-	wheelDir := t.TempDir()
-	wheelPath := filepath.Join(wheelDir, "ext-1.0.0-py3-none-any.whl")
+	env.PlatformTag = "win-amd64"
+	env.SitePackages = filepath.Join(env.Prefix, "Lib", "site-packages")
+
+	if err := os.MkdirAll(env.SitePackages, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	wheelPath := filepath.Join(t.TempDir(), "mycli-1.0.0-py3-none-any.whl")
 
 	createWheel(t, wheelPath, map[string]string{
-		"ext-1.0.0.dist-info/METADATA":       "Name: ext\nVersion: 1.0.0\n",
-		"ext-1.0.0.dist-info/WHEEL":          "Wheel-Version: 1.0\n",
-		"ext-1.0.0.dist-info/RECORD":         "",
-		"ext-1.0.0.data/platlib/ext_native.py": "# native\n",
+		"mycli/__init__.py":                      "",
+		"mycli-1.0.0.dist-info/METADATA":         "Name: mycli\nVersion: 1.0.0\n",
+		"mycli-1.0.0.dist-info/entry_points.txt": "[console_scripts]\nmycli = mycli.cli:main\n",
 	})
 
 	svc := installer.New(env)
 
 	err := svc.Install(context.Background(), []downloader.Result{
-		{Name: "ext", Version: "1.0.0", FilePath: wheelPath, Size: 100},
+		{Name: "mycli", Version: "1.0.0", FilePath: wheelPath},
 	})
 	if err != nil {
 		t.Fatalf("Install() error: %v", err)
 	}
 
-	// platlib → site-packages/
-	platPath := filepath.Join(env.SitePackages, "ext_native.py")
-	if _, statErr := os.Stat(platPath); statErr != nil {
-		t.Errorf("platlib file not found: %v", statErr)
+	scriptPath := filepath.Join(env.Prefix, "Scripts", "mycli.py")
+	if _, statErr := os.Stat(scriptPath); statErr != nil {
+		t.Fatalf("script not installed to Scripts: %v", statErr)
+	}
+
+	record, err := os.ReadFile(filepath.Join(env.SitePackages, "mycli-1.0.0.dist-info", "RECORD"))
+	if err != nil {
+		t.Fatalf("reading RECORD: %v", err)
+	}
+
+	if want := filepath.Join("..", "..", "Scripts", "mycli.py"); !strings.Contains(string(record), want) {
+		t.Errorf("RECORD does not contain %q:\n%s", want, record)
 	}
 }
 
-func TestInstallDataSkipsUnknownSubdir(t *testing.T) {
+func TestInstallFlat(t *testing.T) {
 	env := testEnv(t)
+	env.SitePackages = filepath.Join(t.TempDir(), "app")
 	wheelDir := t.TempDir()
-	wheelPath := filepath.Join(wheelDir, "pkg-1.0.0-py3-none-any.whl")
 
-	createWheel(t, wheelPath, map[string]string{
-		"pkg/__init__.py":                    "# pkg\n",
-		"pkg-1.0.0.dist-info/METADATA":      "Name: pkg\nVersion: 1.0.0\n",
-		"pkg-1.0.0.dist-info/WHEEL":         "Wheel-Version: 1.0\n",
-		"pkg-1.0.0.dist-info/RECORD":        "",
-		"pkg-1.0.0.data/unknown/somefile.py": "# should be skipped\n",
+	sixPath := filepath.Join(wheelDir, "six-1.16.0-py3-none-any.whl")
+	createWheel(t, sixPath, map[string]string{
+		"six.py":                        "# six\n",
+		"six-1.16.0.dist-info/METADATA": "Name: six\nVersion: 1.16.0\n",
+		"six-1.16.0.dist-info/RECORD":   "",
 	})
 
-	svc := installer.New(env)
+	cliPath := filepath.Join(wheelDir, "mycli-1.0.0-py3-none-any.whl")
+	createWheel(t, cliPath, map[string]string{
+		"mycli/__init__.py":                      "",
+		"mycli-1.0.0.data/purelib/mycli_ext.py":  "",
+		"mycli-1.0.0.data/scripts/mycli-run":     "#!/bin/sh\n",
+		"mycli-1.0.0.data/data/share/mycli.txt":  "",
+		"mycli-1.0.0.dist-info/METADATA":         "Name: mycli\nVersion: 1.0.0\n",
+		"mycli-1.0.0.dist-info/entry_points.txt": "[console_scripts]\nmycli = mycli.cli:main\n",
+	})
+
+	svc := installer.New(env, installer.WithFlat(true))
 
 	err := svc.Install(context.Background(), []downloader.Result{
-		{Name: "pkg", Version: "1.0.0", FilePath: wheelPath, Size: 100},
+		{Name: "six", Version: "1.16.0", FilePath: sixPath},
+		{Name: "mycli", Version: "1.0.0", FilePath: cliPath},
 	})
 	if err != nil {
 		t.Fatalf("Install() error: %v", err)
 	}
 
-	// The unknown subdir file should not exist anywhere.
-	entries := findFiles(t, env.Prefix, "somefile.py")
-	if len(entries) > 0 {
-		t.Errorf("unknown .data subdir file should be skipped, found: %v", entries)
+	var got []string
+
+	walkErr := filepath.WalkDir(env.SitePackages, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, _ := filepath.Rel(env.SitePackages, path)
+		got = append(got, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if walkErr != nil {
+		t.Fatal(walkErr)
+	}
+
+	want := []string{"mycli/__init__.py", "mycli_ext.py", "six.py"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("flat target files = %v, want %v", got, want)
+	}
+
+	for _, dir := range []string{"bin", "share"} {
+		if _, statErr := os.Stat(filepath.Join(env.Prefix, dir)); !os.IsNotExist(statErr) {
+			t.Errorf("%s should not be created in flat mode", dir)
+		}
 	}
 }
 
-func TestInstallDataSkipsEmptyRemainder(t *testing.T) {
+func TestInstallWritesDirectURL(t *testing.T) {
 	env := testEnv(t)
-	wheelDir := t.TempDir()
-	wheelPath := filepath.Join(wheelDir, "pkg-1.0.0-py3-none-any.whl")
+	wheelPath := filepath.Join(t.TempDir(), "six-1.16.0-py3-none-any.whl")
 
 	createWheel(t, wheelPath, map[string]string{
-		"pkg/__init__.py":               "# pkg\n",
-		"pkg-1.0.0.dist-info/METADATA":  "Name: pkg\nVersion: 1.0.0\n",
-		"pkg-1.0.0.dist-info/WHEEL":     "Wheel-Version: 1.0\n",
-		"pkg-1.0.0.dist-info/RECORD":    "",
+		"six.py":                        "# six\n",
+		"six-1.16.0.dist-info/METADATA": "Name: six\nVersion: 1.16.0\n",
 	})
 
-	svc := installer.New(env)
+	svc := installer.New(env, installer.WithDirectURLs([]string{"six"}))
 
-	err := svc.Install(context.Background(), []downloader.Result{
-		{Name: "pkg", Version: "1.0.0", FilePath: wheelPath, Size: 100},
-	})
+	err := svc.Install(context.Background(), []downloader.Result{{
+		Name:     "six",
+		Version:  "1.16.0",
+		FilePath: wheelPath,
+		URL:      "https://files.example/six-1.16.0-py3-none-any.whl",
+		SHA256:   "abc123",
+	}})
 	if err != nil {
 		t.Fatalf("Install() error: %v", err)
 	}
-}
 
-// findFiles recursively finds files matching the given name under root.
-func findFiles(t *testing.T, root, name string) []string {
-	t.Helper()
+	distInfo := filepath.Join(env.SitePackages, "six-1.16.0.dist-info")
 
-	var found []string
+	data, err := os.ReadFile(filepath.Join(distInfo, "direct_url.json"))
+	if err != nil {
+		t.Fatalf("reading direct_url.json: %v", err)
+	}
 
-	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
+	var got struct {
+		URL         string `json:"url"`
+		ArchiveInfo struct {
+			Hash string `json:"hash"`
+		} `json:"archive_info"`
+	}
 
-		if !info.IsDir() && info.Name() == name {
-			found = append(found, path)
-		}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("direct_url.json is not valid JSON: %v\n%s", err, data)
+	}
 
-		return nil
-	})
+	if got.URL != "https://files.example/six-1.16.0-py3-none-any.whl" || got.ArchiveInfo.Hash != "sha256=abc123" {
+		t.Errorf("direct_url.json = %s", data)
+	}
 
-	return found
+	record, err := os.ReadFile(filepath.Join(distInfo, "RECORD"))
+	if err != nil {
+		t.Fatalf("reading RECORD: %v", err)
+	}
+
+	if !strings.Contains(string(record), "six-1.16.0.dist-info/direct_url.json,sha256=") {
+		t.Errorf("RECORD does not list direct_url.json:\n%s", record)
+	}
+}
+
+func TestInstallIndexPackageHasNoDirectURL(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "six-1.16.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"six.py":                        "# six\n",
+		"six-1.16.0.dist-info/METADATA": "Name: six\nVersion: 1.16.0\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{{
+		Name:     "six",
+		Version:  "1.16.0",
+		FilePath: wheelPath,
+		URL:      "https://files.pythonhosted.org/packages/six-1.16.0-py3-none-any.whl",
+		SHA256:   "abc123",
+	}})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	path := filepath.Join(env.SitePackages, "six-1.16.0.dist-info", "direct_url.json")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("direct_url.json written for an index install: %v", err)
+	}
+}
+
+// fakeCompileall writes a .pyc for every listed source except those named
+// bad.py, and then fails like compileall does when a file has syntax errors.
+func fakeCompileall(t *testing.T) python.CommandRunner {
+	t.Helper()
+
+	return func(_ context.Context, name string, args ...string) ([]byte, error) {
+		if name != "python3" || strings.Join(args[:4], " ") != "-m compileall -q -i" {
+			t.Errorf("unexpected command %s %v", name, args)
+		}
+
+		list, err := os.ReadFile(args[4])
+		if err != nil {
+			t.Fatalf("reading compile list: %v", err)
+		}
+
+		failed := false
+
+		for _, src := range strings.Fields(string(list)) {
+			if filepath.Base(src) == "bad.py" {
+				failed = true
+
+				continue
+			}
+
+			pycDir := filepath.Join(filepath.Dir(src), "__pycache__")
+			pyc := filepath.Join(pycDir, strings.TrimSuffix(filepath.Base(src), ".py")+".cpython-312.pyc")
+
+			if err := os.MkdirAll(pycDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.WriteFile(pyc, []byte("pyc"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if failed {
+			return []byte("*** Error compiling 'bad.py'"), errors.New("exit status 1")
+		}
+
+		return nil, nil
+	}
+}
+
+func TestInstallByteCompiles(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "mypkg-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"mypkg/__init__.py":              "",
+		"mypkg/core.py":                  "x = 1\n",
+		"mypkg/_vendor/bad.py":           "print 'py2'\n",
+		"mypkg/data.txt":                 "",
+		"mypkg-1.0.0.dist-info/METADATA": "Name: mypkg\nVersion: 1.0.0\n",
+	})
+
+	var logs bytes.Buffer
+
+	svc := installer.New(env,
+		installer.WithCompile(true),
+		installer.WithCommandRunner(fakeCompileall(t)),
+		installer.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "mypkg", Version: "1.0.0", FilePath: wheelPath},
+	})
+	if err != nil {
+		t.Fatalf("Install() should not fail on compile errors: %v", err)
+	}
+
+	record, err := os.ReadFile(filepath.Join(env.SitePackages, "mypkg-1.0.0.dist-info", "RECORD"))
+	if err != nil {
+		t.Fatalf("reading RECORD: %v", err)
+	}
+
+	for _, want := range []string{
+		"mypkg/__pycache__/__init__.cpython-312.pyc,,",
+		"mypkg/__pycache__/core.cpython-312.pyc,,",
+	} {
+		if !strings.Contains(string(record), want) {
+			t.Errorf("RECORD missing %q:\n%s", want, record)
+		}
+	}
+
+	if strings.Contains(string(record), "bad.cpython") {
+		t.Errorf("RECORD lists a .pyc for a file that failed to compile:\n%s", record)
+	}
+
+	if !strings.Contains(logs.String(), "could not be byte-compiled") {
+		t.Errorf("expected a compile warning, got logs:\n%s", logs.String())
+	}
+}
+
+func TestInstallWithoutRecord(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "six-1.16.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"six.py":                        "# six\n",
+		"six-1.16.0.dist-info/METADATA": "Name: six\nVersion: 1.16.0\n",
+		"six-1.16.0.dist-info/RECORD":   "six.py,sha256=abc,6\n",
+	})
+
+	svc := installer.New(env, installer.WithWriteRecord(false))
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "six", Version: "1.16.0", FilePath: wheelPath},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(env.SitePackages, "six.py")); err != nil {
+		t.Errorf("six.py not extracted: %v", err)
+	}
+
+	distInfo := filepath.Join(env.SitePackages, "six-1.16.0.dist-info")
+
+	for _, name := range []string{"RECORD", "INSTALLER"} {
+		if _, err := os.Stat(filepath.Join(distInfo, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be written when record writing is disabled", name)
+		}
+	}
+}
+
+func TestInstallUpgradeRemovesStaleFiles(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+
+	v1 := filepath.Join(wheelDir, "mod-1.0.0-py3-none-any.whl")
+	createWheel(t, v1, map[string]string{
+		"mod/__init__.py":              "# v1\n",
+		"mod/old.py":                   "# removed in v2\n",
+		"mod/legacy/__init__.py":       "# removed in v2\n",
+		"mod-1.0.0.dist-info/METADATA": "Name: mod\nVersion: 1.0.0\n",
+	})
+
+	v2 := filepath.Join(wheelDir, "mod-2.0.0-py3-none-any.whl")
+	createWheel(t, v2, map[string]string{
+		"mod/__init__.py":              "# v2\n",
+		"mod/new.py":                   "# added in v2\n",
+		"mod-2.0.0.dist-info/METADATA": "Name: mod\nVersion: 2.0.0\n",
+	})
+
+	svc := installer.New(env)
+
+	for _, dl := range []downloader.Result{
+		{Name: "mod", Version: "1.0.0", FilePath: v1},
+		{Name: "mod", Version: "2.0.0", FilePath: v2},
+	} {
+		if err := svc.Install(context.Background(), []downloader.Result{dl}); err != nil {
+			t.Fatalf("Install(%s) error: %v", dl.Version, err)
+		}
+	}
+
+	for _, gone := range []string{"mod/old.py", "mod/legacy", "mod-1.0.0.dist-info"} {
+		if _, err := os.Stat(filepath.Join(env.SitePackages, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after upgrade, stat error: %v", gone, err)
+		}
+	}
+
+	for _, kept := range []string{"mod/__init__.py", "mod/new.py", "mod-2.0.0.dist-info/RECORD"} {
+		if _, err := os.Stat(filepath.Join(env.SitePackages, kept)); err != nil {
+			t.Errorf("%s should exist after upgrade: %v", kept, err)
+		}
+	}
+}
+
+func TestInstallReinstallKeepsRecord(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "mod-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"mod.py":                       "# mod\n",
+		"mod-1.0.0.dist-info/METADATA": "Name: mod\nVersion: 1.0.0\n",
+	})
+
+	svc := installer.New(env)
+	dl := []downloader.Result{{Name: "mod", Version: "1.0.0", FilePath: wheelPath}}
+
+	for range 2 {
+		if err := svc.Install(context.Background(), dl); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+	}
+
+	for _, name := range []string{"mod.py", "mod-1.0.0.dist-info/RECORD", "mod-1.0.0.dist-info/INSTALLER"} {
+		if _, err := os.Stat(filepath.Join(env.SitePackages, name)); err != nil {
+			t.Errorf("%s should survive a reinstall: %v", name, err)
+		}
+	}
+}
+
+func TestInstallReinstallRepairsIdenticalVersion(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "mycli-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"mycli/__init__.py":                      "# mycli\n",
+		"mycli/cli.py":                           "def main(): pass\n",
+		"mycli-1.0.0.dist-info/METADATA":         "Name: mycli\nVersion: 1.0.0\n",
+		"mycli-1.0.0.dist-info/entry_points.txt": "[console_scripts]\nmycli = mycli.cli:main\n",
+	})
+
+	svc := installer.New(env)
+	dl := []downloader.Result{{Name: "mycli", Version: "1.0.0", FilePath: wheelPath}}
+
+	if err := svc.Install(context.Background(), dl); err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	module := filepath.Join(env.SitePackages, "mycli", "cli.py")
+	script := filepath.Join(env.Prefix, "bin", "mycli")
+	recordPath := filepath.Join(env.SitePackages, "mycli-1.0.0.dist-info", "RECORD")
+	orphan := filepath.Join(env.SitePackages, "mycli", "orphan.py")
+
+	// Damage the install: a corrupted module, a deleted script, and a RECORD
+	// listing a file the wheel does not ship.
+	if err := os.WriteFile(module, []byte("corrupted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(script); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(orphan, []byte("# orphan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(recordPath, append(record, "mycli/orphan.py,,\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	for _, path := range []string{module, recordPath} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := svc.Install(context.Background(), dl); err != nil {
+		t.Fatalf("reinstall error: %v", err)
+	}
+
+	if content, err := os.ReadFile(module); err != nil || string(content) != "def main(): pass\n" {
+		t.Errorf("module content = %q, %v; want it re-extracted", content, err)
+	}
+
+	for _, path := range []string{module, recordPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !info.ModTime().After(old) {
+			t.Errorf("%s was not rewritten, mtime %v", path, info.ModTime())
+		}
+	}
+
+	if _, err := os.Stat(script); err != nil {
+		t.Errorf("console script should be regenerated: %v", err)
+	}
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("file listed only in the prior RECORD should be removed, stat error: %v", err)
+	}
+
+	if record, err := os.ReadFile(recordPath); err != nil || strings.Contains(string(record), "orphan.py") {
+		t.Errorf("RECORD should be rewritten without the orphan:\n%s", record)
+	}
+}
+
+func TestInstallLocalVersionDistInfo(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "torch-2.1.0+cpu-cp312-cp312-linux_x86_64.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"torch/__init__.py": "# torch\n",
+		"torch/_vendor/typing_extensions-4.8.0.dist-info/METADATA": "Name: typing_extensions\n",
+		"torch-2.1.0+cpu.dist-info/METADATA":                       "Name: torch\nVersion: 2.1.0+cpu\n",
+		"torch-2.1.0+cpu.dist-info/RECORD":                         "",
+		"torch-2.1.0+cpu.data/scripts/torchrun":                    "#!/usr/bin/env python3\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "torch", Version: "2.1.0+cpu", FilePath: wheelPath},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	distInfo := filepath.Join(env.SitePackages, "torch-2.1.0+cpu.dist-info")
+
+	if _, err := os.Stat(filepath.Join(distInfo, "INSTALLER")); err != nil {
+		t.Errorf("INSTALLER not written to local-version dist-info: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(env.SitePackages, "torch", "_vendor", "typing_extensions-4.8.0.dist-info", "RECORD")); !os.IsNotExist(err) {
+		t.Error("RECORD written to a vendored dist-info directory")
+	}
+
+	records, err := installer.ReadRecord(distInfo)
+	if err != nil {
+		t.Fatalf("ReadRecord() error: %v", err)
+	}
+
+	paths := make(map[string]bool, len(records))
+	for _, r := range records {
+		paths[r.Path] = true
+	}
+
+	for _, want := range []string{
+		"torch/__init__.py",
+		"torch-2.1.0+cpu.dist-info/METADATA",
+		"torch-2.1.0+cpu.dist-info/INSTALLER",
+		"torch-2.1.0+cpu.dist-info/RECORD",
+		filepath.Join("..", "..", "..", "bin", "torchrun"),
+	} {
+		if !paths[want] {
+			t.Errorf("RECORD missing %q; got %v", want, records)
+		}
+	}
+}
+
+func TestInstallEmptyDownloads(t *testing.T) {
+	env := testEnv(t)
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Install(nil) error: %v", err)
+	}
+}
+
+func TestWithLoggerIgnoresNil(t *testing.T) {
+	env := testEnv(t)
+	_ = installer.New(env, installer.WithLogger(nil))
+}
+
+func TestInstallWithPlatlib(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+	wheelPath := filepath.Join(wheelDir, "ext-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"ext-1.0.0.dist-info/METADATA":       "Name: ext\nVersion: 1.0.0\n",
+		"ext-1.0.0.dist-info/WHEEL":          "Wheel-Version: 1.0\n",
+		"ext-1.0.0.dist-info/RECORD":         "",
+		"ext-1.0.0.data/platlib/ext_native.py": "# native\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "ext", Version: "1.0.0", FilePath: wheelPath, Size: 100},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	// platlib → site-packages/
+	platPath := filepath.Join(env.SitePackages, "ext_native.py")
+	if _, statErr := os.Stat(platPath); statErr != nil {
+		t.Errorf("platlib file not found: %v", statErr)
+	}
+}
+
+func TestInstallRejectsUnsupportedWheelVersion(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "future-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"future/__init__.py":              "# future\n",
+		"future-1.0.0.dist-info/METADATA": "Name: future\nVersion: 1.0.0\n",
+		"future-1.0.0.dist-info/WHEEL":    "Wheel-Version: 2.0\nRoot-Is-Purelib: true\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "future", Version: "1.0.0", FilePath: wheelPath},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported Wheel-Version 2.0") {
+		t.Fatalf("Install() error = %v, want unsupported Wheel-Version 2.0", err)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(env.SitePackages, "future")); !os.IsNotExist(statErr) {
+		t.Errorf("rejected wheel was extracted: %v", statErr)
+	}
+}
+
+func TestInstallRootIsPlatlib(t *testing.T) {
+	env := testEnv(t)
+	env.PlatLib = filepath.Join(env.Prefix, "lib64", "site-packages")
+	wheelPath := filepath.Join(t.TempDir(), "ext-1.0.0-cp312-cp312-linux_x86_64.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"ext/__init__.py":                  "# ext\n",
+		"ext-1.0.0.dist-info/METADATA":     "Name: ext\nVersion: 1.0.0\n",
+		"ext-1.0.0.dist-info/WHEEL":        "Wheel-Version: 1.0\nRoot-Is-Purelib: false\n",
+		"ext-1.0.0.data/purelib/ext_py.py": "# pure\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "ext", Version: "1.0.0", FilePath: wheelPath},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(env.PlatLib, "ext", "__init__.py"),
+		filepath.Join(env.PlatLib, "ext-1.0.0.dist-info", "RECORD"),
+		filepath.Join(env.SitePackages, "ext_py.py"),
+	} {
+		if _, statErr := os.Stat(path); statErr != nil {
+			t.Errorf("expected %s: %v", path, statErr)
+		}
+	}
+
+	if _, statErr := os.Stat(filepath.Join(env.SitePackages, "ext")); !os.IsNotExist(statErr) {
+		t.Errorf("platlib root was extracted to purelib: %v", statErr)
+	}
+}
+
+func TestInstallUsesSchemePaths(t *testing.T) {
+	env := testEnv(t)
+	scheme := t.TempDir()
+	env.ScriptsDir = filepath.Join(scheme, "local", "bin")
+	env.DataDir = filepath.Join(scheme, "local")
+	env.IncludeDir = filepath.Join(scheme, "include", "python3.12")
+
+	wheelPath := filepath.Join(t.TempDir(), "tool-1.0.0-py3-none-any.whl")
+	createWheel(t, wheelPath, map[string]string{
+		"tool/__init__.py":                      "# tool\n",
+		"tool-1.0.0.dist-info/METADATA":         "Name: tool\nVersion: 1.0.0\n",
+		"tool-1.0.0.dist-info/entry_points.txt": "[console_scripts]\ntool = tool:main\n",
+		"tool-1.0.0.data/scripts/tool-sh":       "#!/bin/sh\n",
+		"tool-1.0.0.data/data/share/tool.txt":   "data\n",
+		"tool-1.0.0.data/headers/tool.h":        "#define TOOL\n",
+	})
+
+	svc := installer.New(env)
+
+	if err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "tool", Version: "1.0.0", FilePath: wheelPath},
+	}); err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(env.ScriptsDir, "tool-sh"),
+		filepath.Join(env.ScriptsDir, "tool"),
+		filepath.Join(env.DataDir, "share", "tool.txt"),
+		filepath.Join(env.IncludeDir, "tool.h"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+
+	for _, naive := range []string{"bin", "include", "share"} {
+		if _, err := os.Stat(filepath.Join(env.Prefix, naive)); !os.IsNotExist(err) {
+			t.Errorf("prefix/%s should not be created, stat error: %v", naive, err)
+		}
+	}
+
+	if err := svc.Uninstall(context.Background(), "tool"); err != nil {
+		t.Fatalf("Uninstall() error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(env.ScriptsDir, "tool")); !os.IsNotExist(err) {
+		t.Errorf("console script should be removed by uninstall, stat error: %v", err)
+	}
+}
+
+func TestInstallDataSkipsUnknownSubdir(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+	wheelPath := filepath.Join(wheelDir, "pkg-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"pkg/__init__.py":                    "# pkg\n",
+		"pkg-1.0.0.dist-info/METADATA":      "Name: pkg\nVersion: 1.0.0\n",
+		"pkg-1.0.0.dist-info/WHEEL":         "Wheel-Version: 1.0\n",
+		"pkg-1.0.0.dist-info/RECORD":        "",
+		"pkg-1.0.0.data/unknown/somefile.py": "# should be skipped\n",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "pkg", Version: "1.0.0", FilePath: wheelPath, Size: 100},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	// The unknown subdir file should not exist anywhere.
+	entries := findFiles(t, env.Prefix, "somefile.py")
+	if len(entries) > 0 {
+		t.Errorf("unknown .data subdir file should be skipped, found: %v", entries)
+	}
+}
+
+func TestInstallDataSkipsEmptyRemainder(t *testing.T) {
+	env := testEnv(t)
+	wheelDir := t.TempDir()
+	wheelPath := filepath.Join(wheelDir, "pkg-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"pkg/__init__.py":               "# pkg\n",
+		"pkg-1.0.0.dist-info/METADATA":  "Name: pkg\nVersion: 1.0.0\n",
+		"pkg-1.0.0.dist-info/WHEEL":     "Wheel-Version: 1.0\n",
+		"pkg-1.0.0.dist-info/RECORD":    "",
+	})
+
+	svc := installer.New(env)
+
+	err := svc.Install(context.Background(), []downloader.Result{
+		{Name: "pkg", Version: "1.0.0", FilePath: wheelPath, Size: 100},
+	})
+	if err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+}
+
+// findFiles recursively finds files matching the given name under root.
+func findFiles(t *testing.T, root, name string) []string {
+	t.Helper()
+
+	var found []string
+
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !info.IsDir() && info.Name() == name {
+			found = append(found, path)
+		}
+
+		return nil
+	})
+
+	return found
+}
+
+func TestInstallRecordIsReproducible(t *testing.T) {
+	entries := [][2]string{
+		{"mycli/__init__.py", "# mycli\n"},
+		{"mycli/cli.py", "def main(): pass\n"},
+		{"mycli/data/config.json", "{}\n"},
+		{"mycli-1.0.0.dist-info/METADATA", "Name: mycli\nVersion: 1.0.0\n"},
+		{"mycli-1.0.0.dist-info/entry_points.txt", "[console_scripts]\nmycli = mycli.cli:main\n"},
+	}
+
+	reversed := slices.Clone(entries)
+	slices.Reverse(reversed)
+
+	dir := t.TempDir()
+	forward := filepath.Join(dir, "forward", "mycli-1.0.0-py3-none-any.whl")
+	backward := filepath.Join(dir, "backward", "mycli-1.0.0-py3-none-any.whl")
+
+	for _, path := range []string{forward, backward} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createOrderedWheel(t, forward, entries)
+	createOrderedWheel(t, backward, reversed)
+
+	// Like compileall, stamp each .pyc with the time it was written.
+	compileall := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		list, err := os.ReadFile(args[len(args)-1])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, src := range strings.Fields(string(list)) {
+			pycDir := filepath.Join(filepath.Dir(src), "__pycache__")
+			pyc := filepath.Join(pycDir, strings.TrimSuffix(filepath.Base(src), ".py")+".cpython-312.pyc")
+
+			if err := os.MkdirAll(pycDir, 0o755); err != nil {
+				return nil, err
+			}
+
+			if err := os.WriteFile(pyc, []byte(time.Now().Format(time.RFC3339Nano)), 0o644); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	install := func(env *python.Environment, wheelPath string) []byte {
+		t.Helper()
+
+		svc := installer.New(env, installer.WithCompile(true), installer.WithCommandRunner(compileall))
+
+		dl := []downloader.Result{{Name: "mycli", Version: "1.0.0", FilePath: wheelPath}}
+		if err := svc.Install(context.Background(), dl); err != nil {
+			t.Fatalf("Install() error: %v", err)
+		}
+
+		record, err := os.ReadFile(filepath.Join(env.SitePackages, "mycli-1.0.0.dist-info", "RECORD"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return record
+	}
+
+	env := testEnv(t)
+	first := install(env, forward)
+
+	if again := install(env, forward); !bytes.Equal(again, first) {
+		t.Errorf("reinstalling changed RECORD:\n%s\nwant:\n%s", again, first)
+	}
+
+	if other := install(testEnv(t), backward); !bytes.Equal(other, first) {
+		t.Errorf("archive order changed RECORD:\n%s\nwant:\n%s", other, first)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(first)), "\n")
+	if last := lines[len(lines)-1]; last != "mycli-1.0.0.dist-info/RECORD,," {
+		t.Errorf("last RECORD line = %q, want the self-entry", last)
+	}
+
+	if !slices.Contains(lines, "mycli/__pycache__/cli.cpython-312.pyc,,") {
+		t.Errorf("RECORD = %q, want the .pyc listed without hash or size", lines)
+	}
 }
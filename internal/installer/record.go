@@ -8,6 +8,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 // RecordEntry represents a single line in a RECORD file.
@@ -19,7 +22,14 @@ type RecordEntry struct {
 
 // WriteRecord writes a RECORD file to the dist-info directory.
 // The RECORD file itself is listed with empty hash and size per PEP 376.
+// Entries are sorted by path, with the self-entry last, so installing the
+// same wheel always writes the same bytes whatever the order of its archive.
+// An entry without a hash is written without a size too.
 func WriteRecord(distInfoDir string, entries []RecordEntry) error {
+	entries = slices.SortedFunc(slices.Values(entries), func(a, b RecordEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
 	recordPath := filepath.Join(distInfoDir, "RECORD")
 
 	f, err := os.Create(recordPath)
@@ -31,7 +41,12 @@ func WriteRecord(distInfoDir string, entries []RecordEntry) error {
 	w := csv.NewWriter(f)
 
 	for _, e := range entries {
-		if err := w.Write([]string{e.Path, e.Hash, fmt.Sprintf("%d", e.Size)}); err != nil {
+		size := ""
+		if e.Hash != "" {
+			size = fmt.Sprintf("%d", e.Size)
+		}
+
+		if err := w.Write([]string{e.Path, e.Hash, size}); err != nil {
 			return fmt.Errorf("writing RECORD entry: %w", err)
 		}
 	}
@@ -51,6 +66,44 @@ func WriteRecord(distInfoDir string, entries []RecordEntry) error {
 	return f.Close()
 }
 
+// ReadRecord reads the RECORD file from a dist-info directory.
+func ReadRecord(distInfoDir string) ([]RecordEntry, error) {
+	f, err := os.Open(filepath.Join(distInfoDir, "RECORD"))
+	if err != nil {
+		return nil, fmt.Errorf("opening RECORD: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing RECORD: %w", err)
+	}
+
+	entries := make([]RecordEntry, 0, len(rows))
+
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+
+		e := RecordEntry{Path: row[0]}
+		if len(row) > 1 {
+			e.Hash = row[1]
+		}
+
+		if len(row) > 2 && row[2] != "" {
+			e.Size, _ = strconv.ParseInt(row[2], 10, 64)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
 // WriteInstaller writes the INSTALLER file with "pipg" as the content.
 func WriteInstaller(distInfoDir string) error {
 	path := filepath.Join(distInfoDir, "INSTALLER")
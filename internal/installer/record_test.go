@@ -46,17 +46,21 @@ func TestWriteRecord(t *testing.T) {
 		t.Fatalf("expected 4 RECORD lines, got %d", len(records))
 	}
 
-	// Verify first entry.
-	if records[0][0] != "pkg/__init__.py" {
-		t.Errorf("record[0] path = %q, want %q", records[0][0], "pkg/__init__.py")
+	// Entries are sorted by path: the dist-info directory sorts before "pkg/".
+	if records[0][0] != "pkg-1.0.0.dist-info/METADATA" {
+		t.Errorf("record[0] path = %q, want %q", records[0][0], "pkg-1.0.0.dist-info/METADATA")
 	}
 
-	if records[0][1] != "sha256=abc123" {
-		t.Errorf("record[0] hash = %q, want %q", records[0][1], "sha256=abc123")
+	if records[1][0] != "pkg/__init__.py" {
+		t.Errorf("record[1] path = %q, want %q", records[1][0], "pkg/__init__.py")
 	}
 
-	if records[0][2] != "42" {
-		t.Errorf("record[0] size = %q, want %q", records[0][2], "42")
+	if records[1][1] != "sha256=abc123" {
+		t.Errorf("record[1] hash = %q, want %q", records[1][1], "sha256=abc123")
+	}
+
+	if records[1][2] != "42" {
+		t.Errorf("record[1] size = %q, want %q", records[1][2], "42")
 	}
 
 	// Verify self-entry (last line).
@@ -70,6 +74,44 @@ func TestWriteRecord(t *testing.T) {
 	}
 }
 
+func TestReadRecord(t *testing.T) {
+	distInfo := filepath.Join(t.TempDir(), "six-1.16.0.dist-info")
+	if err := os.MkdirAll(distInfo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []installer.RecordEntry{
+		{Path: "../../../bin/six-cli", Hash: "sha256=def", Size: 7},
+		{Path: "six.py", Hash: "sha256=abc", Size: 42},
+	}
+
+	if err := installer.WriteRecord(distInfo, entries); err != nil {
+		t.Fatalf("WriteRecord() error: %v", err)
+	}
+
+	got, err := installer.ReadRecord(distInfo)
+	if err != nil {
+		t.Fatalf("ReadRecord() error: %v", err)
+	}
+
+	want := append(entries, installer.RecordEntry{Path: filepath.Join("six-1.16.0.dist-info", "RECORD")})
+	if len(got) != len(want) {
+		t.Fatalf("ReadRecord() returned %d entries, want %d: %v", len(got), len(want), got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadRecordNotFound(t *testing.T) {
+	if _, err := installer.ReadRecord(t.TempDir()); err == nil {
+		t.Fatal("expected error for missing RECORD, got nil")
+	}
+}
+
 func TestWriteInstaller(t *testing.T) {
 	dir := t.TempDir()
 	distInfo := filepath.Join(dir, "pkg-1.0.0.dist-info")
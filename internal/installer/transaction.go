@@ -0,0 +1,287 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// change is one journaled filesystem change. A change without a backup
+// created path; one with a backup overwrote or removed an existing file or
+// symlink; a pruned one removed an empty directory.
+type change struct {
+	path   string
+	backup string
+	mode   os.FileMode
+	pruned bool
+}
+
+// transaction journals the files and directories an install creates,
+// overwrites or removes, so that a failed install can be rolled back and
+// leave the environment as it was. Only the first change to a path is
+// journaled: that is the state rollback restores. A nil *transaction
+// journals nothing, for callers outside an install such as Uninstall.
+//
+// Files are backed up by renaming them into a directory under root, which
+// costs no copy when root is on the same filesystem; they are copied only
+// when the rename fails.
+type transaction struct {
+	changes   []change
+	seen      map[string]bool
+	root      string
+	backupDir string
+}
+
+func newTransaction(root string) *transaction {
+	return &transaction{seen: make(map[string]bool), root: root}
+}
+
+// mkdirAll creates dir and any missing parents, journaling each one created.
+func (t *transaction) mkdirAll(dir string) error {
+	if t == nil {
+		return os.MkdirAll(dir, 0o755)
+	}
+
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+
+		missing = append(missing, d)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	slices.Reverse(missing)
+
+	for _, d := range missing {
+		t.created(d)
+	}
+
+	return nil
+}
+
+// track journals path before it is written: an existing file or symlink is
+// moved to the backup directory, leaving path free for the new file, and
+// otherwise the path is recorded as created.
+func (t *transaction) track(path string) error {
+	if t == nil || t.seen[path] {
+		return nil
+	}
+
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.created(path)
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return t.backup(path, info)
+}
+
+// created journals a path that did not exist before the install, such as a
+// file written by a subprocess.
+func (t *transaction) created(path string) {
+	if t == nil || t.seen[path] {
+		return
+	}
+
+	t.seen[path] = true
+	t.changes = append(t.changes, change{path: path})
+}
+
+// remove deletes path, backing it up first so rollback can restore it.
+func (t *transaction) remove(path string) error {
+	if t == nil {
+		return os.Remove(path)
+	}
+
+	if err := t.track(path); err != nil {
+		return err
+	}
+
+	// A tracked file has already been moved to the backup directory.
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// removeDir removes the empty directory dir, journaling it so rollback
+// recreates it.
+func (t *transaction) removeDir(dir string) error {
+	if t == nil {
+		return os.Remove(dir)
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(dir); err != nil {
+		return err
+	}
+
+	t.changes = append(t.changes, change{path: dir, mode: info.Mode().Perm(), pruned: true})
+
+	return nil
+}
+
+// backup moves the file or symlink at path into the transaction's backup
+// directory.
+func (t *transaction) backup(path string, info os.FileInfo) error {
+	if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("cannot back up %s: not a file or symlink", path)
+	}
+
+	if t.backupDir == "" {
+		dir, err := os.MkdirTemp(t.root, ".pipg-rollback-*")
+		if err != nil {
+			dir, err = os.MkdirTemp("", "pipg-rollback-*")
+		}
+
+		if err != nil {
+			return fmt.Errorf("creating rollback directory: %w", err)
+		}
+
+		t.backupDir = dir
+	}
+
+	backup := filepath.Join(t.backupDir, fmt.Sprintf("%d", len(t.changes)))
+	if err := moveFile(path, backup, info); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+
+	t.seen[path] = true
+	t.changes = append(t.changes, change{path: path, backup: backup, mode: info.Mode()})
+
+	return nil
+}
+
+// rollback undoes the journaled changes in reverse order: created files and
+// directories are removed and backed-up files are restored. It keeps going
+// past failures and returns them joined.
+func (t *transaction) rollback() error {
+	if t == nil {
+		return nil
+	}
+
+	var errs []error
+
+	for _, c := range slices.Backward(t.changes) {
+		if c.pruned {
+			if err := os.MkdirAll(c.path, c.mode); err != nil {
+				errs = append(errs, fmt.Errorf("restoring %s: %w", c.path, err))
+			}
+
+			continue
+		}
+
+		if c.backup == "" {
+			if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("restoring %s: %w", c.path, err))
+
+			continue
+		}
+
+		info, err := os.Lstat(c.backup)
+		if err == nil {
+			err = moveFile(c.backup, c.path, info)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("restoring %s: %w", c.path, err))
+		}
+	}
+
+	t.changes = nil
+
+	return errors.Join(append(errs, t.commit())...)
+}
+
+// commit keeps the changes and discards the backups.
+func (t *transaction) commit() error {
+	if t == nil || t.backupDir == "" {
+		return nil
+	}
+
+	err := os.RemoveAll(t.backupDir)
+	t.backupDir = ""
+
+	return err
+}
+
+// moveFile moves the file or symlink src, described by info, to dst. It
+// renames when it can and otherwise copies src and removes it, e.g., when dst
+// is on another filesystem.
+func moveFile(src, dst string, info os.FileInfo) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+	} else if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// copyFile copies src to dst, replacing dst, with the given permissions.
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chmod(dst, perm)
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package installer
+
+// isLockedByOtherProcess reports whether err means another process holds the
+// file. Only Windows has such errors; elsewhere EBUSY covers it.
+func isLockedByOtherProcess(error) bool {
+	return false
+}
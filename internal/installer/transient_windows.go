@@ -0,0 +1,18 @@
+package installer
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows errors raised while another process, typically antivirus software
+// scanning a freshly written file, holds it open or locked.
+const (
+	errorSharingViolation syscall.Errno = 32 // ERROR_SHARING_VIOLATION
+	errorLockViolation    syscall.Errno = 33 // ERROR_LOCK_VIOLATION
+)
+
+// isLockedByOtherProcess reports whether err is a sharing or lock violation.
+func isLockedByOtherProcess(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
@@ -0,0 +1,67 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotInstalled is returned when uninstalling a package that has no
+// .dist-info directory in site-packages.
+var ErrNotInstalled = errors.New("package is not installed")
+
+// Uninstaller defines the interface for removing installed packages.
+type Uninstaller interface {
+	Uninstall(ctx context.Context, name string) error
+}
+
+var _ Uninstaller = (*Service)(nil)
+
+// Uninstall removes the named package from site-packages. Every file listed
+// in its RECORD is deleted, directories left empty are pruned, and the
+// .dist-info directory is removed. Files outside site-packages and the
+// environment prefix are never deleted.
+func (s *Service) Uninstall(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("uninstall canceled: %w", err)
+	}
+
+	dirs := installedDistInfo(s.env.LibDirs(), name)
+	if len(dirs) == 0 {
+		return fmt.Errorf("uninstalling %s: %w", name, ErrNotInstalled)
+	}
+
+	for _, distInfoDir := range dirs {
+		records, err := ReadRecord(distInfoDir)
+		if err != nil {
+			return fmt.Errorf("uninstalling %s: no usable RECORD: %w", name, err)
+		}
+
+		if err := s.removeInstall(filepath.Dir(distInfoDir), distInfoDir, records); err != nil {
+			return fmt.Errorf("uninstalling %s: %w", name, err)
+		}
+	}
+
+	s.logger.Debug("uninstalled", slog.String("package", name))
+
+	return nil
+}
+
+// removeInstall deletes the files listed in records and then the .dist-info
+// directory itself.
+func (s *Service) removeInstall(siteDir, distInfoDir string, records []RecordEntry) error {
+	for _, e := range records {
+		if err := s.removeRecorded(siteDir, recordPath(siteDir, e.Path), nil); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(distInfoDir); err != nil {
+		return fmt.Errorf("removing %s: %w", distInfoDir, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package installer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/downloader"
+	"github.com/bilusteknoloji/pipg/internal/installer"
+)
+
+func TestUninstall(t *testing.T) {
+	env := testEnv(t)
+	wheelPath := filepath.Join(t.TempDir(), "mod-1.0.0-py3-none-any.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"mod/__init__.py":              "# mod\n",
+		"mod/sub/helpers.py":           "# helpers\n",
+		"mod-1.0.0.dist-info/METADATA": "Name: mod\nVersion: 1.0.0\n",
+	})
+
+	svc := installer.New(env)
+	dl := []downloader.Result{{Name: "mod", Version: "1.0.0", FilePath: wheelPath}}
+
+	if err := svc.Install(context.Background(), dl); err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	if err := svc.Uninstall(context.Background(), "Mod"); err != nil {
+		t.Fatalf("Uninstall() error: %v", err)
+	}
+
+	for _, gone := range []string{"mod", "mod-1.0.0.dist-info"} {
+		if _, err := os.Stat(filepath.Join(env.SitePackages, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, stat error: %v", gone, err)
+		}
+	}
+
+	if _, err := os.Stat(env.SitePackages); err != nil {
+		t.Errorf("site-packages should be kept: %v", err)
+	}
+}
+
+func TestUninstallFromPlatlib(t *testing.T) {
+	env := testEnv(t)
+	env.PlatLib = filepath.Join(env.Prefix, "lib64", "site-packages")
+	wheelPath := filepath.Join(t.TempDir(), "ext-1.0.0-cp312-cp312-linux_x86_64.whl")
+
+	createWheel(t, wheelPath, map[string]string{
+		"ext/__init__.py":                  "# ext\n",
+		"ext-1.0.0.dist-info/METADATA":     "Name: ext\nVersion: 1.0.0\n",
+		"ext-1.0.0.dist-info/WHEEL":        "Wheel-Version: 1.0\nRoot-Is-Purelib: false\n",
+		"ext-1.0.0.data/purelib/ext_py.py": "# pure\n",
+	})
+
+	svc := installer.New(env)
+	dl := []downloader.Result{{Name: "ext", Version: "1.0.0", FilePath: wheelPath}}
+
+	if err := svc.Install(context.Background(), dl); err != nil {
+		t.Fatalf("Install() error: %v", err)
+	}
+
+	if got := installer.InstalledVersion(env.LibDirs(), "ext"); got != "1.0.0" {
+		t.Errorf("InstalledVersion() = %q, want 1.0.0", got)
+	}
+
+	installed, err := svc.Installed()
+	if err != nil || len(installed) != 1 || installed[0].Name != "ext" {
+		t.Errorf("Installed() = %+v, %v; want ext", installed, err)
+	}
+
+	if err := svc.Uninstall(context.Background(), "ext"); err != nil {
+		t.Fatalf("Uninstall() error: %v", err)
+	}
+
+	for _, gone := range []string{
+		filepath.Join(env.PlatLib, "ext"),
+		filepath.Join(env.PlatLib, "ext-1.0.0.dist-info"),
+		filepath.Join(env.SitePackages, "ext_py.py"),
+	} {
+		if _, err := os.Stat(gone); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, stat error: %v", gone, err)
+		}
+	}
+}
+
+func TestUninstallNotInstalled(t *testing.T) {
+	svc := installer.New(testEnv(t))
+
+	err := svc.Uninstall(context.Background(), "missing")
+	if !errors.Is(err, installer.ErrNotInstalled) {
+		t.Fatalf("Uninstall() error = %v, want ErrNotInstalled", err)
+	}
+}
+
+func TestUninstallSkipsPathsOutsideEnvironment(t *testing.T) {
+	env := testEnv(t)
+
+	outside := filepath.Join(t.TempDir(), "keep.txt")
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	distInfo := filepath.Join(env.SitePackages, "evil-1.0.dist-info")
+	if err := os.MkdirAll(distInfo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	rel, err := filepath.Rel(env.SitePackages, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := filepath.ToSlash(rel) + ",,\nevil-1.0.dist-info/RECORD,,\n"
+	if err := os.WriteFile(filepath.Join(distInfo, "RECORD"), []byte(record), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := installer.New(env).Uninstall(context.Background(), "evil"); err != nil {
+		t.Fatalf("Uninstall() error: %v", err)
+	}
+
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the environment should be kept: %v", err)
+	}
+
+	if _, err := os.Stat(distInfo); !os.IsNotExist(err) {
+		t.Errorf("dist-info should be removed, stat error: %v", err)
+	}
+}
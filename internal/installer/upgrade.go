@@ -0,0 +1,161 @@
+package installer
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var nameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// distName normalizes a distribution name for comparing against .dist-info
+// directory names, e.g., "Typing.Extensions" → "typing_extensions".
+func distName(name string) string {
+	return nameSeparators.ReplaceAllString(strings.ToLower(name), "_")
+}
+
+// InstalledVersion returns the version of the named package installed in any
+// of libDirs, read from its .dist-info directory name, or "" if it is not
+// installed.
+func InstalledVersion(libDirs []string, name string) string {
+	dirs := installedDistInfo(libDirs, name)
+	if len(dirs) == 0 {
+		return ""
+	}
+
+	_, version, _ := strings.Cut(strings.TrimSuffix(filepath.Base(dirs[0]), ".dist-info"), "-")
+
+	return version
+}
+
+// installedDistInfo returns the .dist-info directories in libDirs that belong
+// to the named package.
+func installedDistInfo(libDirs []string, name string) []string {
+	want := distName(name)
+
+	var matches []string
+
+	for _, siteDir := range libDirs {
+		dirs, err := filepath.Glob(filepath.Join(siteDir, "*.dist-info"))
+		if err != nil {
+			continue
+		}
+
+		for _, dir := range dirs {
+			prefix, _, _ := strings.Cut(filepath.Base(dir), "-")
+			if distName(prefix) == want {
+				matches = append(matches, dir)
+			}
+		}
+	}
+
+	return matches
+}
+
+// previousFiles returns the paths listed in the RECORD of any existing
+// installation of the named package, in either library directory, so a
+// package that moves between purelib and platlib is cleaned up too.
+// Installations without a readable RECORD contribute nothing.
+func (s *Service) previousFiles(name string) []string {
+	var paths []string
+
+	for _, dir := range installedDistInfo(s.env.LibDirs(), name) {
+		records, err := ReadRecord(dir)
+		if err != nil {
+			s.logger.Debug("ignoring existing install without RECORD", slog.String("dist_info", dir))
+
+			continue
+		}
+
+		for _, e := range records {
+			paths = append(paths, recordPath(filepath.Dir(dir), e.Path))
+		}
+	}
+
+	return paths
+}
+
+// removeStale deletes files listed in a previous install's RECORD that the new
+// install did not write, so modules dropped between versions do not linger.
+// Directories left empty are removed as well. Paths resolving outside the
+// environment are never touched. Removals are journaled in tx.
+func (s *Service) removeStale(siteDir, distInfoDir string, previous []string, written []RecordEntry, tx *transaction) error {
+	if len(previous) == 0 {
+		return nil
+	}
+
+	keep := make(map[string]bool, len(written)+1)
+	if s.writeRecord {
+		keep[filepath.Join(distInfoDir, "RECORD")] = true
+	}
+
+	for _, e := range written {
+		keep[recordPath(siteDir, e.Path)] = true
+	}
+
+	for _, path := range previous {
+		if keep[path] {
+			continue
+		}
+
+		if err := s.removeRecorded(siteDir, path, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeRecorded deletes a file listed in a RECORD and prunes the directories
+// it leaves empty. Paths outside site-packages, the environment prefix and the
+// install scheme directories are never touched. The removal is journaled in
+// tx, which may be nil.
+func (s *Service) removeRecorded(siteDir, path string, tx *transaction) error {
+	var base string
+
+	for _, dir := range []string{siteDir, s.libDir(true), s.libDir(false), s.env.Prefix, BinDir(s.env), s.dataDir(), s.includeDir()} {
+		if isInsideDir(path, dir) {
+			base = dir
+
+			break
+		}
+	}
+
+	if base == "" {
+		s.logger.Debug("not removing file outside the environment", slog.String("path", path))
+
+		return nil
+	}
+
+	if err := tx.remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	s.logger.Debug("removed file", slog.String("path", path))
+	removeEmptyParents(filepath.Dir(path), base, tx)
+
+	return nil
+}
+
+// recordPath resolves a RECORD path, which is relative to site-packages.
+func recordPath(siteDir, p string) string {
+	return filepath.Clean(filepath.Join(siteDir, filepath.FromSlash(p)))
+}
+
+// removeEmptyParents removes dir and its ancestors up to (but excluding) stop,
+// halting at the first directory that is not empty. Each removal is journaled
+// in tx, which may be nil.
+func removeEmptyParents(dir, stop string, tx *transaction) {
+	stop = filepath.Clean(stop)
+
+	for dir != stop && isInsideDir(dir, stop) {
+		if err := tx.removeDir(dir); err != nil {
+			return
+		}
+
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,96 @@
+package installer
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// supportedWheelMajor is the only major Wheel-Version pipg installs. PEP 427
+// requires installers to refuse a newer major version and to warn about a
+// newer minor version.
+const (
+	supportedWheelMajor = 1
+	supportedWheelMinor = 0
+)
+
+// wheelInfo holds the fields pipg uses from a wheel's .dist-info/WHEEL file.
+type wheelInfo struct {
+	Version       string // Wheel-Version, e.g., "1.0"
+	RootIsPurelib bool   // Root-Is-Purelib: root files go to purelib, else platlib
+}
+
+// readWheelInfo parses the top-level .dist-info/WHEEL file of a wheel. A
+// missing file or field falls back to Wheel-Version 1.0 with a purelib root,
+// which is how pipg has always installed such wheels.
+func readWheelInfo(r *zip.Reader) (wheelInfo, error) {
+	info := wheelInfo{Version: "1.0", RootIsPurelib: true}
+
+	for _, f := range r.File {
+		top, rest, ok := strings.Cut(f.Name, "/")
+		if !ok || rest != "WHEEL" || !strings.HasSuffix(top, ".dist-info") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return info, fmt.Errorf("opening %s: %w", f.Name, err)
+		}
+		defer func() { _ = rc.Close() }()
+
+		return parseWheelInfo(rc, info)
+	}
+
+	return info, nil
+}
+
+// parseWheelInfo reads the "Key: value" lines of a WHEEL file over defaults.
+func parseWheelInfo(r io.Reader, info wheelInfo) (wheelInfo, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "wheel-version":
+			info.Version = value
+		case "root-is-purelib":
+			info.RootIsPurelib = strings.EqualFold(value, "true")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return info, fmt.Errorf("reading WHEEL: %w", err)
+	}
+
+	return info, nil
+}
+
+// checkVersion returns an error if the wheel format is newer than pipg
+// understands, and reports whether only the minor version is newer.
+func (w wheelInfo) checkVersion() (newerMinor bool, err error) {
+	majorStr, minorStr, _ := strings.Cut(w.Version, ".")
+
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid Wheel-Version %q", w.Version)
+	}
+
+	if major != supportedWheelMajor {
+		return false, fmt.Errorf("unsupported Wheel-Version %s: pipg supports %d.x", w.Version, supportedWheelMajor)
+	}
+
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid Wheel-Version %q", w.Version)
+	}
+
+	return minor > supportedWheelMinor, nil
+}
@@ -0,0 +1,79 @@
+package pypi
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Credentials authenticates requests to private package indexes. Each index
+// host gets HTTP basic auth from the user info of its URL or, failing that,
+// the bearer token. Credentials are matched by host, so files the index
+// serves from its own host are authenticated as well while files on other
+// hosts (e.g., a CDN) are not. A nil *Credentials authenticates nothing.
+type Credentials struct {
+	token string
+
+	mu    sync.RWMutex
+	hosts map[string]*url.Userinfo // nil value: use the token
+}
+
+// NewCredentials creates Credentials with a bearer token, which may be empty.
+func NewCredentials(token string) *Credentials {
+	return &Credentials{token: token, hosts: make(map[string]*url.Userinfo)}
+}
+
+// Add registers the host of indexURL and returns the URL without its user
+// info, so the secret never reaches logs, errors or recorded URLs. Empty and
+// unparsable URLs are returned unchanged.
+func (c *Credentials) Add(indexURL string) string {
+	u, err := url.Parse(indexURL)
+	if err != nil || u.Host == "" {
+		return indexURL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if u.User != nil || c.hosts[u.Host] == nil {
+		c.hosts[u.Host] = u.User
+	}
+
+	u.User = nil
+
+	return u.String()
+}
+
+// Apply sets the Authorization header of req if its host is a registered
+// index and the request does not already carry one.
+func (c *Credentials) Apply(req *http.Request) {
+	if c == nil || req.Header.Get("Authorization") != "" {
+		return
+	}
+
+	c.mu.RLock()
+	user, ok := c.hosts[req.URL.Host]
+	c.mu.RUnlock()
+
+	switch {
+	case !ok:
+	case user != nil:
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// RedactURL masks the user info of a URL, for logging; a username can be a
+// token too. URLs without user info are returned unchanged.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+
+	u.User = url.User("***")
+
+	return u.String()
+}
@@ -9,7 +9,11 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"golang.org/x/sync/semaphore"
 )
 
 const (
@@ -18,7 +22,8 @@ const (
 	clientTimeout  = 30 * time.Second
 )
 
-// Client defines the interface for communicating with the PyPI JSON API.
+// Client defines the interface for communicating with a package index, either
+// the PyPI JSON API (Service) or a PEP 503 simple index (SimpleService).
 type Client interface {
 	GetPackage(ctx context.Context, name string) (*PackageInfo, error)
 	GetPackageVersion(ctx context.Context, name, version string) (*PackageInfo, error)
@@ -45,6 +50,104 @@ func WithBaseURL(url string) Option {
 	}
 }
 
+// WithExtraBaseURLs adds fallback indexes that are queried, in order, when a
+// package is not found on the base URL. Empty entries are ignored.
+func WithExtraBaseURLs(urls []string) Option {
+	return func(s *Service) {
+		for _, u := range urls {
+			if u != "" {
+				s.extraBaseURLs = append(s.extraBaseURLs, u)
+			}
+		}
+	}
+}
+
+// WithCredentials authenticates index requests with c.
+func WithCredentials(c *Credentials) Option {
+	return func(s *Service) {
+		if c != nil {
+			s.credentials = c
+		}
+	}
+}
+
+// NormalizeIndexURL validates a package index URL and converts it to the base
+// URL of its JSON API. Both the simple ("https://mirror/simple") and the JSON
+// ("https://mirror/pypi") shapes are accepted, as is a bare host, e.g.,
+// "https://mirror" → "https://mirror/pypi".
+func NormalizeIndexURL(raw string) (string, error) {
+	u, err := parseIndexURL(raw)
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	path = strings.TrimSuffix(path, "/simple")
+	path = strings.TrimSuffix(path, "/+simple") // devpi
+
+	if !strings.HasSuffix(path, "/pypi") {
+		path += "/pypi"
+	}
+
+	u.Path = path
+
+	return u.String(), nil
+}
+
+// NormalizeSimpleIndexURL validates a package index URL and converts it to
+// the base URL of its PEP 503 simple API, the counterpart of
+// NormalizeIndexURL, e.g., "https://mirror/pypi" → "https://mirror/simple".
+func NormalizeSimpleIndexURL(raw string) (string, error) {
+	u, err := parseIndexURL(raw)
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+
+	switch {
+	case strings.HasSuffix(path, "/simple"), strings.HasSuffix(path, "/+simple"):
+	case strings.HasSuffix(path, "/pypi"):
+		path = strings.TrimSuffix(path, "/pypi") + "/simple"
+	default:
+		path += "/simple"
+	}
+
+	u.Path = path
+
+	return u.String(), nil
+}
+
+// parseIndexURL parses an index URL, requiring an http(s) scheme and a host,
+// and drops any query and fragment.
+func parseIndexURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid index URL %q: %w", raw, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid index URL %q: expected an http or https URL with a host", raw)
+	}
+
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return u, nil
+}
+
+// WithSemaphore bounds concurrent HTTP requests with a semaphore, which may be
+// shared with other components (e.g., the downloader) to cap total in-flight
+// requests. Each request holds one unit until its response body is read.
+func WithSemaphore(sem *semaphore.Weighted) Option {
+	return func(s *Service) {
+		if sem != nil {
+			s.sem = sem
+		}
+	}
+}
+
 // WithLogger sets the structured logger.
 func WithLogger(l *slog.Logger) Option {
 	return func(s *Service) {
@@ -59,6 +162,10 @@ type Service struct {
 	httpClient *http.Client
 	baseURL    string
 	logger     *slog.Logger
+	sem        *semaphore.Weighted
+
+	extraBaseURLs []string
+	credentials   *Credentials
 }
 
 // compile-time proof that Service implements Client.
@@ -82,62 +189,154 @@ func New(opts ...Option) *Service {
 // GetPackage fetches metadata for a package from PyPI.
 // Endpoint: GET {baseURL}/{package_name}/json
 func (s *Service) GetPackage(ctx context.Context, name string) (*PackageInfo, error) {
-	url := fmt.Sprintf("%s/%s/json", s.baseURL, name)
-
-	return s.fetch(ctx, url, name)
+	return s.fetchFirst(ctx, name, name+"/json", s.fetch)
 }
 
 // GetPackageVersion fetches metadata for a specific version of a package.
 // Endpoint: GET {baseURL}/{package_name}/{version}/json
 func (s *Service) GetPackageVersion(ctx context.Context, name, version string) (*PackageInfo, error) {
-	url := fmt.Sprintf("%s/%s/%s/json", s.baseURL, name, version)
+	return s.fetchFirst(ctx, name, name+"/"+version+"/json", s.fetch)
+}
+
+// fetchFirst fetches path from the base URL and then each extra index in
+// order with fetch, returning the first success. An index is skipped when it
+// does not have the package or is still failing after retries; any other
+// error is returned immediately.
+func (s *Service) fetchFirst(ctx context.Context, name, path string, fetch fetchFunc) (*PackageInfo, error) {
+	indexes := append([]string{s.baseURL}, s.extraBaseURLs...)
+
+	var lastErr error
+
+	for _, index := range indexes {
+		info, err := fetch(ctx, index+"/"+path, name)
+		if err == nil {
+			s.logger.Debug("package served by index", slog.String("package", name), slog.String("index", RedactURL(index)))
+
+			return info, nil
+		}
+
+		var re *retryableError
+		if !errors.Is(err, errNotFound) && !errors.As(err, &re) {
+			return nil, err
+		}
+
+		lastErr = err
+	}
+
+	if len(indexes) > 1 {
+		return nil, fmt.Errorf("%s not available from any of %d indexes: %w", name, len(indexes), lastErr)
+	}
 
-	return s.fetch(ctx, url, name)
+	return nil, lastErr
 }
 
-// fetch performs an HTTP GET with retry and exponential backoff, then decodes the response.
-// Only transient errors (5xx, network errors) are retried; permanent errors (404, bad JSON)
-// are returned immediately.
+// fetchFunc downloads and decodes the package document at url.
+type fetchFunc func(ctx context.Context, url, name string) (*PackageInfo, error)
+
+// fetch downloads the JSON document at url and decodes it into a PackageInfo.
 func (s *Service) fetch(ctx context.Context, url, name string) (*PackageInfo, error) {
+	body, err := s.get(ctx, url, name, "application/json")
+	if err != nil {
+		return nil, err
+	}
+
+	var info PackageInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("fetching %s: decoding response from %s: %w", name, url, err)
+	}
+
+	if err := checkShape(body, &info); err != nil {
+		return nil, fmt.Errorf("fetching %s: malformed metadata response from %s: %w", name, url, err)
+	}
+
+	return &info, nil
+}
+
+// checkShape rejects responses that decode without error but are not package
+// metadata, such as an error object served with status 200 by a misconfigured
+// mirror. A valid response names the project and carries "urls" or "releases".
+func checkShape(body []byte, info *PackageInfo) error {
+	if info.Info.Name == "" {
+		return errors.New(`missing "info.name"`)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return err
+	}
+
+	_, hasURLs := fields["urls"]
+	_, hasReleases := fields["releases"]
+
+	if !hasURLs && !hasReleases {
+		return errors.New(`missing both "urls" and "releases"`)
+	}
+
+	return nil
+}
+
+// get performs an HTTP GET with retry and exponential backoff and returns the body.
+// Only transient errors (5xx, network errors) are retried; permanent errors (404)
+// are returned immediately.
+func (s *Service) get(ctx context.Context, url, name, accept string) ([]byte, error) {
+	var body []byte
+
+	err := s.retry(ctx, name, func() error {
+		var err error
+		body, err = s.doRequest(ctx, url, accept)
+
+		return err
+	})
+
+	return body, err
+}
+
+// retry calls attempt until it succeeds, fails with an error that is not a
+// retryableError, or has been tried maxRetries times, backing off
+// exponentially between attempts.
+func (s *Service) retry(ctx context.Context, name string, attempt func() error) error {
 	var lastErr error
 
-	for attempt := range maxRetries {
-		if attempt > 0 {
-			backoff := time.Duration(math.Pow(2, float64(attempt))) * 500 * time.Millisecond
+	for i := range maxRetries {
+		if i > 0 {
+			backoff := max(time.Duration(math.Pow(2, float64(i)))*500*time.Millisecond, RetryAfter(lastErr))
 			s.logger.Debug("retrying PyPI request",
 				slog.String("package", name),
-				slog.Int("attempt", attempt+1),
+				slog.Int("attempt", i+1),
 				slog.Duration("backoff", backoff),
 			)
 
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("fetching %s: %w", name, ctx.Err())
+				return fmt.Errorf("fetching %s: %w", name, ctx.Err())
 			case <-time.After(backoff):
 			}
 		}
 
-		info, err := s.doRequest(ctx, url)
+		err := attempt()
 		if err == nil {
-			return info, nil
+			return nil
 		}
 
 		var re *retryableError
 		if !errors.As(err, &re) {
-			return nil, fmt.Errorf("fetching %s: %w", name, err)
+			return fmt.Errorf("fetching %s: %w", name, err)
 		}
 
 		lastErr = err
 		s.logger.Debug("PyPI request failed",
 			slog.String("package", name),
-			slog.Int("attempt", attempt+1),
+			slog.Int("attempt", i+1),
 			slog.String("error", err.Error()),
 		)
 	}
 
-	return nil, fmt.Errorf("fetching %s after %d attempts: %w", name, maxRetries, lastErr)
+	return fmt.Errorf("fetching %s after %d attempts: %w", name, maxRetries, lastErr)
 }
 
+// errNotFound reports a 404 from an index.
+var errNotFound = errors.New("package not found")
+
 // retryableError indicates a transient error that should be retried.
 type retryableError struct {
 	err error
@@ -146,43 +345,74 @@ type retryableError struct {
 func (e *retryableError) Error() string { return e.err.Error() }
 func (e *retryableError) Unwrap() error { return e.err }
 
-// doRequest performs a single HTTP GET and decodes the JSON response.
+// doRequest performs a single HTTP GET and returns the response body.
 // Returns a retryableError for transient failures (5xx, network errors).
-func (s *Service) doRequest(ctx context.Context, url string) (*PackageInfo, error) {
+func (s *Service) doRequest(ctx context.Context, url, accept string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request for %s: %w", url, err)
 	}
 
-	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept", accept)
+
+	var body []byte
+
+	err = s.send(s.httpClient, req, func(resp *http.Response) error {
+		var err error
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return &retryableError{err: fmt.Errorf("reading response from %s: %w", url, err)}
+		}
+
+		return nil
+	})
 
-	resp, err := s.httpClient.Do(req)
+	return body, err
+}
+
+// send performs a single request with client and hands a successful response
+// to read, which runs while the request still holds its slot. A 206 counts as
+// success for requests with a Range header.
+// Returns a retryableError for transient failures (5xx, network errors).
+func (s *Service) send(client *http.Client, req *http.Request, read func(*http.Response) error) error {
+	url := req.URL.String()
+	s.credentials.Apply(req)
+
+	if s.sem != nil {
+		if err := s.sem.Acquire(req.Context(), 1); err != nil {
+			return fmt.Errorf("waiting for a request slot: %w", err)
+		}
+		defer s.sem.Release(1)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, &retryableError{err: fmt.Errorf("requesting %s: %w", url, err)}
+		return &retryableError{err: fmt.Errorf("requesting %s: %w", url, err)}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("package not found at %s", url)
+		return fmt.Errorf("%w at %s", errNotFound, url)
 	}
 
 	if resp.StatusCode >= http.StatusInternalServerError {
-		return nil, &retryableError{err: fmt.Errorf("server error %d from %s", resp.StatusCode, url)}
+		return &retryableError{err: fmt.Errorf("server error %d from %s", resp.StatusCode, url)}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
-	}
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		err := DeniedError(resp, url)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, &retryableError{err: fmt.Errorf("reading response from %s: %w", url, err)}
+		var rl *RateLimitError
+		if errors.As(err, &rl) {
+			return &retryableError{err: err}
+		}
+
+		return err
 	}
 
-	var info PackageInfo
-	if err := json.Unmarshal(body, &info); err != nil {
-		return nil, fmt.Errorf("decoding response from %s: %w", url, err)
+	partial := resp.StatusCode == http.StatusPartialContent && req.Header.Get("Range") != ""
+	if resp.StatusCode != http.StatusOK && !partial {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
 	}
 
-	return &info, nil
+	return read(resp)
 }
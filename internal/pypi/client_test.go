@@ -3,8 +3,11 @@ package pypi_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -219,6 +222,124 @@ func TestGetPackageRetriesExhausted(t *testing.T) {
 	}
 }
 
+func TestGetPackageForbiddenRateLimitRetried(t *testing.T) {
+	attempts := 0
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+
+			return
+		}
+
+		encodeJSON(t, w, newTestPackageInfo())
+	})
+
+	if _, err := client.GetPackage(context.Background(), "six"); err != nil {
+		t.Fatalf("GetPackage() error after rate-limit 403: %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestGetPackageForbiddenRateLimitBody(t *testing.T) {
+	attempts := 0
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "Rate limit exceeded, slow down", http.StatusForbidden)
+
+			return
+		}
+
+		encodeJSON(t, w, newTestPackageInfo())
+	})
+
+	if _, err := client.GetPackage(context.Background(), "six"); err != nil {
+		t.Fatalf("GetPackage() error after rate-limit 403: %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestDeniedError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		authorization string
+		wantRetry     time.Duration
+		wantRateLimit bool
+		wantHint      string
+	}{
+		{name: "429 seconds", status: http.StatusTooManyRequests, retryAfter: "7", wantRetry: 7 * time.Second, wantRateLimit: true},
+		{name: "429 capped", status: http.StatusTooManyRequests, retryAfter: "86400", wantRetry: time.Minute, wantRateLimit: true},
+		{name: "429 date", status: http.StatusTooManyRequests, retryAfter: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), wantRetry: time.Minute, wantRateLimit: true},
+		{name: "429 without header", status: http.StatusTooManyRequests, wantRateLimit: true},
+		{name: "403 without credentials", status: http.StatusForbidden, wantHint: "no credentials were sent"},
+		{name: "403 with credentials", status: http.StatusForbidden, authorization: "Bearer secret", wantHint: "credentials sent were rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://pypi.example.com/simple/six/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: http.NoBody, Request: req}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			err := pypi.DeniedError(resp, req.URL.String())
+
+			var rl *pypi.RateLimitError
+			if got := errors.As(err, &rl); got != tt.wantRateLimit {
+				t.Fatalf("DeniedError() = %v, rate limited %v, want %v", err, got, tt.wantRateLimit)
+			}
+
+			if got := pypi.RetryAfter(err); got != tt.wantRetry {
+				t.Errorf("RetryAfter() = %v, want %v", got, tt.wantRetry)
+			}
+
+			if tt.wantHint != "" && !strings.Contains(err.Error(), tt.wantHint) {
+				t.Errorf("DeniedError() = %q, want containing %q", err, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestGetPackageForbiddenAuth(t *testing.T) {
+	attempts := 0
+
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		http.Error(w, "Invalid or missing credentials", http.StatusForbidden)
+	})
+
+	_, err := client.GetPackage(context.Background(), "private-pkg")
+	if err == nil {
+		t.Fatal("expected error for auth 403, got nil")
+	}
+
+	if attempts != 1 {
+		t.Errorf("expected auth 403 to fail without retrying, got %d attempts", attempts)
+	}
+
+	if !strings.Contains(err.Error(), "authentication required or failed") ||
+		!strings.Contains(err.Error(), "credentials") {
+		t.Errorf("expected authentication hint in error, got: %v", err)
+	}
+}
+
 func TestGetPackageRequiresDist(t *testing.T) {
 	pkg := pypi.PackageInfo{
 		Info: pypi.Info{
@@ -251,3 +372,130 @@ func TestGetPackageRequiresDist(t *testing.T) {
 		t.Errorf("expected first dep %q, got %q", "blinker>=1.9.0", info.Info.RequiresDist[0])
 	}
 }
+
+func TestNormalizeIndexURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://mirror.example.com/simple", "https://mirror.example.com/pypi"},
+		{"https://mirror.example.com/simple/", "https://mirror.example.com/pypi"},
+		{"https://mirror.example.com/pypi", "https://mirror.example.com/pypi"},
+		{"https://mirror.example.com", "https://mirror.example.com/pypi"},
+		{"http://localhost:3141/root/pypi/+simple/", "http://localhost:3141/root/pypi"},
+	}
+
+	for _, tt := range tests {
+		got, err := pypi.NormalizeIndexURL(tt.in)
+		if err != nil {
+			t.Errorf("NormalizeIndexURL(%q) error: %v", tt.in, err)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("NormalizeIndexURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeIndexURLInvalid(t *testing.T) {
+	for _, in := range []string{"mirror.example.com/simple", "ftp://mirror.example.com", "https://", "://bad"} {
+		if _, err := pypi.NormalizeIndexURL(in); err == nil {
+			t.Errorf("NormalizeIndexURL(%q) expected error, got nil", in)
+		}
+	}
+}
+
+func TestGetPackageExtraIndexFallback(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	t.Cleanup(primary.Close)
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		encodeJSON(t, w, newTestPackageInfo())
+	}))
+	t.Cleanup(fallback.Close)
+
+	client := pypi.New(
+		pypi.WithBaseURL(primary.URL+"/pypi"),
+		pypi.WithExtraBaseURLs([]string{"", fallback.URL + "/pypi"}),
+	)
+
+	info, err := client.GetPackage(context.Background(), "six")
+	if err != nil {
+		t.Fatalf("GetPackage() error: %v", err)
+	}
+
+	if info.Info.Version != "1.17.0" {
+		t.Errorf("expected version %q, got %q", "1.17.0", info.Info.Version)
+	}
+}
+
+func TestGetPackageExtraIndexAfterRetries(t *testing.T) {
+	var primaryHits atomic.Int32
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		primaryHits.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(primary.Close)
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		encodeJSON(t, w, newTestPackageInfo())
+	}))
+	t.Cleanup(fallback.Close)
+
+	client := pypi.New(
+		pypi.WithBaseURL(primary.URL+"/pypi"),
+		pypi.WithExtraBaseURLs([]string{fallback.URL + "/pypi"}),
+	)
+
+	if _, err := client.GetPackageVersion(context.Background(), "six", "1.17.0"); err != nil {
+		t.Fatalf("GetPackageVersion() error: %v", err)
+	}
+
+	if got := primaryHits.Load(); got != 3 {
+		t.Errorf("expected the primary index to be retried 3 times, got %d", got)
+	}
+}
+
+func TestGetPackageNotFoundOnAnyIndex(t *testing.T) {
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	primary := httptest.NewServer(notFound)
+	t.Cleanup(primary.Close)
+
+	extra := httptest.NewServer(notFound)
+	t.Cleanup(extra.Close)
+
+	client := pypi.New(
+		pypi.WithBaseURL(primary.URL+"/pypi"),
+		pypi.WithExtraBaseURLs([]string{extra.URL + "/pypi"}),
+	)
+
+	_, err := client.GetPackage(context.Background(), "missing")
+	if err == nil || !strings.Contains(err.Error(), "any of 2 indexes") {
+		t.Fatalf("expected not-found error across 2 indexes, got %v", err)
+	}
+}
+
+func TestGetPackageMalformedResponse(t *testing.T) {
+	for _, body := range []string{
+		`{"message": "index not configured"}`,
+		`{"info": {"name": "six", "version": "1.17.0"}}`,
+	} {
+		client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		})
+
+		_, err := client.GetPackage(context.Background(), "six")
+		if err == nil || !strings.Contains(err.Error(), "malformed metadata response") {
+			t.Errorf("body %s: expected malformed metadata error, got %v", body, err)
+		}
+	}
+}
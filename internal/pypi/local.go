@@ -0,0 +1,271 @@
+package pypi
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// LocalService serves packages from directories of wheels, like pip's
+// --find-links. Wheels are grouped by the project name and version in their
+// filenames and listed with file:// URLs; the dependencies of a version are
+// read from the METADATA of one of its wheels. The releases of the fallback
+// client, if there is one, are merged in, with the local wheels taking the
+// place of the index files of any version the directories hold.
+type LocalService struct {
+	dirs     []string
+	fallback Client
+	logger   *slog.Logger
+
+	scan     sync.Once
+	scanErr  error
+	projects map[string]*PackageInfo
+
+	mu       sync.Mutex
+	versions map[string]*PackageInfo
+}
+
+// compile-time proof that LocalService implements Client.
+var _ Client = (*LocalService)(nil)
+
+// wheelMetadataReader is implemented by the clients that can read the
+// dependencies of a wheel given only its URL.
+type wheelMetadataReader interface {
+	GetWheelMetadata(ctx context.Context, file URL) (*Info, error)
+}
+
+// NewLocal creates a client for the wheels in dirs. fallback serves the
+// packages the directories do not hold; nil keeps every lookup local, like
+// pip's --no-index. Of the options, only WithLogger applies.
+func NewLocal(dirs []string, fallback Client, opts ...Option) *LocalService {
+	return &LocalService{
+		dirs:     dirs,
+		fallback: fallback,
+		logger:   New(opts...).logger,
+		projects: make(map[string]*PackageInfo),
+		versions: make(map[string]*PackageInfo),
+	}
+}
+
+// GetPackage lists the local wheels of a project, grouped by version into
+// Releases, merged with the project's releases on the fallback index. Without
+// a fallback Info.Version is left empty, as for a simple index.
+func (s *LocalService) GetPackage(ctx context.Context, name string) (*PackageInfo, error) {
+	local, err := s.project(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.fallback == nil {
+		if local == nil {
+			return nil, fmt.Errorf("fetching %s: %w in %s", name, errNotFound, strings.Join(s.dirs, ", "))
+		}
+
+		return local, nil
+	}
+
+	remote, err := s.fallback.GetPackage(ctx, name)
+	if err != nil {
+		if local != nil && errors.Is(err, errNotFound) {
+			return local, nil
+		}
+
+		return nil, err
+	}
+
+	if local == nil {
+		return remote, nil
+	}
+
+	merged := *remote
+	merged.Releases = make(map[string][]URL, len(remote.Releases)+len(local.Releases))
+
+	for version, files := range remote.Releases {
+		merged.Releases[version] = files
+	}
+
+	for version, files := range local.Releases {
+		merged.Releases[version] = files
+	}
+
+	if files, ok := local.Releases[remote.Info.Version]; ok {
+		merged.URLs = files
+	}
+
+	return &merged, nil
+}
+
+// GetPackageVersion returns the local wheels of one version, with Info filled
+// from the METADATA of the first of them. A version the directories do not
+// hold is looked up on the fallback index.
+func (s *LocalService) GetPackageVersion(ctx context.Context, name, version string) (*PackageInfo, error) {
+	project, err := s.project(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if project == nil || project.Releases[version] == nil {
+		switch {
+		case s.fallback != nil:
+			return s.fallback.GetPackageVersion(ctx, name, version)
+		case project == nil:
+			return nil, fmt.Errorf("fetching %s: %w in %s", name, errNotFound, strings.Join(s.dirs, ", "))
+		default:
+			return nil, fmt.Errorf("fetching %s: %w: version %s is not in %s", name, errNotFound, version, strings.Join(s.dirs, ", "))
+		}
+	}
+
+	key := projectName(name) + "==" + version
+
+	s.mu.Lock()
+	cached, ok := s.versions[key]
+	s.mu.Unlock()
+
+	if ok {
+		return cached, nil
+	}
+
+	files := project.Releases[version]
+
+	meta, err := localWheelMetadata(files[0])
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PackageInfo{
+		Info: Info{
+			Name:           project.Info.Name,
+			Version:        version,
+			Summary:        meta.Summary,
+			RequiresDist:   meta.RequiresDist,
+			RequiresPython: meta.RequiresPython,
+			Classifiers:    meta.Classifiers,
+		},
+		URLs:     files,
+		Releases: map[string][]URL{version: files},
+	}
+
+	s.mu.Lock()
+	s.versions[key] = info
+	s.mu.Unlock()
+
+	return info, nil
+}
+
+// GetWheelMetadata reads the METADATA of a wheel, from disk for a file://
+// URL and otherwise through the fallback client.
+func (s *LocalService) GetWheelMetadata(ctx context.Context, file URL) (*Info, error) {
+	if _, ok := LocalPath(file.URL); ok {
+		return localWheelMetadata(file)
+	}
+
+	if r, ok := s.fallback.(wheelMetadataReader); ok {
+		return r.GetWheelMetadata(ctx, file)
+	}
+
+	return nil, fmt.Errorf("cannot read the metadata of %s without a package index", file.Filename)
+}
+
+// project returns the local wheels of name, or nil if the directories hold
+// none. The directories are read on first use.
+func (s *LocalService) project(name string) (*PackageInfo, error) {
+	s.scan.Do(func() { s.scanErr = s.scanDirs() })
+
+	if s.scanErr != nil {
+		return nil, s.scanErr
+	}
+
+	return s.projects[projectName(name)], nil
+}
+
+// scanDirs lists the wheels in every directory. Other files are ignored.
+func (s *LocalService) scanDirs() error {
+	for _, dir := range s.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("reading find-links directory: %w", err)
+		}
+
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+
+			file := URL{Filename: e.Name()}
+
+			version, ok := classifyFile(&file)
+			if !ok || file.PackageType != "bdist_wheel" {
+				continue
+			}
+
+			abs, err := filepath.Abs(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return fmt.Errorf("reading find-links directory: %w", err)
+			}
+
+			file.URL = fileURL(abs)
+
+			name, _, _ := strings.Cut(e.Name(), "-")
+			key := projectName(name)
+
+			info, ok := s.projects[key]
+			if !ok {
+				info = &PackageInfo{Info: Info{Name: name}, Releases: make(map[string][]URL)}
+				s.projects[key] = info
+			}
+
+			info.Releases[version] = append(info.Releases[version], file)
+		}
+
+		s.logger.Debug("scanned find-links directory", slog.String("dir", dir), slog.Int("projects", len(s.projects)))
+	}
+
+	return nil
+}
+
+// localWheelMetadata parses the METADATA of a local wheel.
+func localWheelMetadata(file URL) (*Info, error) {
+	path, _ := LocalPath(file.URL)
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", file.Filename, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	return readWheelMetadata(&r.Reader, file.Filename)
+}
+
+// fileURL returns the file:// URL of an absolute path.
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // a Windows drive path, e.g., C:/wheels
+	}
+
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// LocalPath returns the filesystem path of a file:// URL, such as those of
+// LocalService files. ok is false for any other URL.
+func LocalPath(rawURL string) (path string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+
+	path = u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+
+	return filepath.FromSlash(path), true
+}
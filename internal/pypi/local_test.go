@@ -0,0 +1,168 @@
+package pypi_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+// writeLocalWheels writes a wheel for each filename into a new directory,
+// each declaring requires for its dependencies.
+func writeLocalWheels(t *testing.T, wheels map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for filename, requires := range wheels {
+		metadata := "Metadata-Version: 2.1\nName: demo\nVersion: 1.0\n" + requires + "\n"
+		if err := os.WriteFile(filepath.Join(dir, filename), buildWheel(t, metadata), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLocalGetPackage(t *testing.T) {
+	dir := writeLocalWheels(t, map[string]string{
+		"Flask-3.0.0-py3-none-any.whl":    "Requires-Dist: werkzeug>=3.0\n",
+		"flask-3.0.3-py3-none-any.whl":    "Requires-Dist: werkzeug>=3.0.1\nRequires-Python: >=3.8\n",
+		"six-1.17.0-py2.py3-none-any.whl": "",
+	})
+
+	if err := os.WriteFile(filepath.Join(dir, "flask-3.1.0.tar.gz"), []byte("sdist"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := pypi.NewLocal([]string{dir}, nil)
+	ctx := context.Background()
+
+	info, err := client.GetPackage(ctx, "flask")
+	if err != nil {
+		t.Fatalf("GetPackage() error: %v", err)
+	}
+
+	if len(info.Releases) != 2 || info.Releases["3.0.3"] == nil || info.Releases["3.0.0"] == nil {
+		t.Fatalf("Releases = %v, want the two wheel versions", info.Releases)
+	}
+
+	file := info.Releases["3.0.3"][0]
+	if path, ok := pypi.LocalPath(file.URL); !ok || path != filepath.Join(dir, "flask-3.0.3-py3-none-any.whl") {
+		t.Errorf("URL = %q (path %q), want a file URL of the wheel", file.URL, path)
+	}
+
+	version, err := client.GetPackageVersion(ctx, "Flask", "3.0.3")
+	if err != nil {
+		t.Fatalf("GetPackageVersion() error: %v", err)
+	}
+
+	if len(version.Info.RequiresDist) != 1 || version.Info.RequiresDist[0] != "werkzeug>=3.0.1" || version.Info.RequiresPython != ">=3.8" {
+		t.Errorf("Info = %+v, want the wheel's METADATA", version.Info)
+	}
+
+	if len(version.URLs) != 1 || version.URLs[0].Filename != "flask-3.0.3-py3-none-any.whl" {
+		t.Errorf("URLs = %v, want the 3.0.3 wheel", version.URLs)
+	}
+
+	if _, err := client.GetPackage(ctx, "requests"); err == nil || !strings.Contains(err.Error(), dir) {
+		t.Errorf("GetPackage(requests) error = %v, want not found in %s", err, dir)
+	}
+}
+
+func TestLocalFallback(t *testing.T) {
+	dir := writeLocalWheels(t, map[string]string{"six-1.16.0-py2.py3-none-any.whl": "Requires-Dist: local\n"})
+
+	index := &countingClient{versions: []string{"1.16.0", "1.17.0"}}
+	client := pypi.NewLocal([]string{dir}, index)
+	ctx := context.Background()
+
+	info, err := client.GetPackage(ctx, "six")
+	if err != nil {
+		t.Fatalf("GetPackage(six) error: %v", err)
+	}
+
+	if len(info.Releases) != 2 || info.Releases["1.17.0"] == nil {
+		t.Fatalf("Releases = %v, want the local and index versions merged", info.Releases)
+	}
+
+	if _, ok := pypi.LocalPath(info.Releases["1.16.0"][0].URL); !ok {
+		t.Errorf("1.16.0 files = %v, want the local wheel in place of the index files", info.Releases["1.16.0"])
+	}
+
+	version, err := client.GetPackageVersion(ctx, "six", "1.16.0")
+	if err != nil {
+		t.Fatalf("GetPackageVersion(six, 1.16.0) error: %v", err)
+	}
+
+	if len(version.Info.RequiresDist) != 1 || version.Info.RequiresDist[0] != "local" {
+		t.Errorf("RequiresDist = %v, want the local wheel's", version.Info.RequiresDist)
+	}
+
+	if index.calls != 1 {
+		t.Errorf("index calls = %d, want 1 for the project listing only", index.calls)
+	}
+
+	if _, err := client.GetPackageVersion(ctx, "six", "1.17.0"); err != nil {
+		t.Fatalf("GetPackageVersion(six, 1.17.0) error: %v", err)
+	}
+
+	if _, err := client.GetPackage(ctx, "requests"); err != nil {
+		t.Fatalf("GetPackage(requests) error: %v", err)
+	}
+
+	if index.calls != 3 {
+		t.Errorf("index calls = %d, want 3", index.calls)
+	}
+}
+
+func TestLocalGetWheelMetadata(t *testing.T) {
+	dir := writeLocalWheels(t, map[string]string{"six-1.17.0-py2.py3-none-any.whl": "Requires-Dist: local\n"})
+
+	client := pypi.NewLocal([]string{dir}, nil)
+
+	info, err := client.GetPackageVersion(context.Background(), "six", "1.17.0")
+	if err != nil {
+		t.Fatalf("GetPackageVersion() error: %v", err)
+	}
+
+	meta, err := client.GetWheelMetadata(context.Background(), info.URLs[0])
+	if err != nil {
+		t.Fatalf("GetWheelMetadata() error: %v", err)
+	}
+
+	if len(meta.RequiresDist) != 1 || meta.RequiresDist[0] != "local" {
+		t.Errorf("RequiresDist = %v, want the wheel's", meta.RequiresDist)
+	}
+
+	remote := pypi.URL{Filename: "six-1.17.0-py2.py3-none-any.whl", URL: "https://files.example.com/six-1.17.0-py2.py3-none-any.whl"}
+	if _, err := client.GetWheelMetadata(context.Background(), remote); err == nil {
+		t.Error("expected an error reading a remote wheel without an index")
+	}
+}
+
+// countingClient is an index that has every package, each with versions,
+// and counts lookups.
+type countingClient struct {
+	versions []string
+	calls    int
+}
+
+func (c *countingClient) GetPackage(_ context.Context, name string) (*pypi.PackageInfo, error) {
+	c.calls++
+
+	info := &pypi.PackageInfo{Info: pypi.Info{Name: name}, Releases: make(map[string][]pypi.URL)}
+	for _, v := range c.versions {
+		info.Releases[v] = []pypi.URL{{Filename: name + "-" + v + "-py3-none-any.whl", PackageType: "bdist_wheel"}}
+	}
+
+	return info, nil
+}
+
+func (c *countingClient) GetPackageVersion(_ context.Context, name, version string) (*pypi.PackageInfo, error) {
+	c.calls++
+
+	return &pypi.PackageInfo{Info: pypi.Info{Name: name, Version: version}}, nil
+}
@@ -0,0 +1,128 @@
+package pypi
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ParseMetadata parses a core metadata file (METADATA / PKG-INFO) into an Info.
+// Only the header section is read; the long description body is ignored.
+func ParseMetadata(r io.Reader) (Info, error) {
+	var info Info
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line ends the headers; the body follows.
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+
+		// Continuation lines belong to multi-line fields we do not use.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(key) {
+		case "name":
+			info.Name = value
+		case "version":
+			info.Version = value
+		case "summary":
+			info.Summary = value
+		case "requires-python":
+			info.RequiresPython = value
+		case "requires-dist":
+			info.RequiresDist = append(info.RequiresDist, value)
+		case "classifier":
+			info.Classifiers = append(info.Classifiers, value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Info{}, fmt.Errorf("reading metadata: %w", err)
+	}
+
+	return info, nil
+}
+
+// GetCoreMetadata fetches the PEP 658 metadata file published next to a
+// distribution file ({file}.metadata) and parses it. This avoids downloading
+// the whole wheel just to read its Requires-Dist.
+func (s *Service) GetCoreMetadata(ctx context.Context, file URL) (*Info, error) {
+	if !file.HasCoreMetadata() {
+		return nil, fmt.Errorf("no core metadata advertised for %s", file.Filename)
+	}
+
+	fileURL, _, _ := strings.Cut(file.URL, "#")
+
+	body, err := s.get(ctx, fileURL+".metadata", file.Filename, "*/*")
+	if err != nil {
+		return nil, err
+	}
+
+	expected := file.CoreMetadata.SHA256
+	if expected == "" {
+		expected = file.DistInfoMetadata.SHA256
+	}
+
+	if expected != "" {
+		sum := sha256.Sum256(body)
+		if got := hex.EncodeToString(sum[:]); got != expected {
+			return nil, fmt.Errorf("sha256 mismatch for %s.metadata: expected %s, got %s",
+				file.Filename, expected, got)
+		}
+	}
+
+	info, err := ParseMetadata(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parsing metadata for %s: %w", file.Filename, err)
+	}
+
+	return &info, nil
+}
+
+// readWheelMetadata parses the METADATA file in the .dist-info directory of
+// an opened wheel.
+func readWheelMetadata(r *zip.Reader, filename string) (*Info, error) {
+	for _, f := range r.File {
+		dir, base := path.Split(f.Name)
+		if base != "METADATA" || strings.Count(dir, "/") != 1 || !strings.HasSuffix(dir, ".dist-info/") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("reading METADATA from %s: %w", filename, err)
+		}
+
+		info, err := ParseMetadata(rc)
+		_ = rc.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("parsing metadata for %s: %w", filename, err)
+		}
+
+		return &info, nil
+	}
+
+	return nil, fmt.Errorf("%s has no .dist-info/METADATA", filename)
+}
@@ -0,0 +1,267 @@
+package pypi_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+const testMetadata = `Metadata-Version: 2.1
+Name: Flask
+Version: 3.0.0
+Summary: A simple framework for building complex web applications.
+Requires-Python: >=3.8
+Classifier: Development Status :: 7 - Inactive
+Classifier: Framework :: Flask
+Requires-Dist: Werkzeug>=3.0.0
+Requires-Dist: Jinja2>=3.1.2
+Requires-Dist: asgiref>=3.2 ; extra == "async"
+Description-Content-Type: text/markdown
+
+# Flask
+
+Requires-Dist: not-a-header
+`
+
+func TestParseMetadata(t *testing.T) {
+	info, err := pypi.ParseMetadata(strings.NewReader(testMetadata))
+	if err != nil {
+		t.Fatalf("ParseMetadata() error: %v", err)
+	}
+
+	if info.Name != "Flask" {
+		t.Errorf("Name = %q, want %q", info.Name, "Flask")
+	}
+
+	if info.Version != "3.0.0" {
+		t.Errorf("Version = %q, want %q", info.Version, "3.0.0")
+	}
+
+	if info.RequiresPython != ">=3.8" {
+		t.Errorf("RequiresPython = %q, want %q", info.RequiresPython, ">=3.8")
+	}
+
+	if !info.Inactive() || len(info.Classifiers) != 2 {
+		t.Errorf("Classifiers = %v, want the inactive status and one framework classifier", info.Classifiers)
+	}
+
+	want := []string{"Werkzeug>=3.0.0", "Jinja2>=3.1.2", `asgiref>=3.2 ; extra == "async"`}
+	if len(info.RequiresDist) != len(want) {
+		t.Fatalf("RequiresDist = %v, want %v", info.RequiresDist, want)
+	}
+
+	for i, dep := range want {
+		if info.RequiresDist[i] != dep {
+			t.Errorf("RequiresDist[%d] = %q, want %q", i, info.RequiresDist[i], dep)
+		}
+	}
+}
+
+func TestCoreMetadataUnmarshal(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantAvail  bool
+		wantSHA256 string
+	}{
+		{`{"core-metadata": true}`, true, ""},
+		{`{"core-metadata": false}`, false, ""},
+		{`{"core-metadata": {"sha256": "abc"}}`, true, "abc"},
+		{`{"data-dist-info-metadata": {"sha256": "def"}}`, true, ""},
+		{`{}`, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var u pypi.URL
+			if err := json.Unmarshal([]byte(tt.input), &u); err != nil {
+				t.Fatalf("Unmarshal() error: %v", err)
+			}
+
+			if got := u.HasCoreMetadata(); got != tt.wantAvail {
+				t.Errorf("HasCoreMetadata() = %v, want %v", got, tt.wantAvail)
+			}
+
+			if u.CoreMetadata.SHA256 != tt.wantSHA256 {
+				t.Errorf("CoreMetadata.SHA256 = %q, want %q", u.CoreMetadata.SHA256, tt.wantSHA256)
+			}
+		})
+	}
+}
+
+func TestGetCoreMetadata(t *testing.T) {
+	sum := sha256.Sum256([]byte(testMetadata))
+	wheelRequested := false
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/flask-3.0.0-py3-none-any.whl.metadata":
+			_, _ = w.Write([]byte(testMetadata))
+		case "/files/flask-3.0.0-py3-none-any.whl":
+			wheelRequested = true
+			http.Error(w, "wheel should not be downloaded", http.StatusTeapot)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := pypi.New(pypi.WithHTTPClient(srv.Client()))
+
+	info, err := client.GetCoreMetadata(context.Background(), pypi.URL{
+		Filename:     "flask-3.0.0-py3-none-any.whl",
+		URL:          srv.URL + "/files/flask-3.0.0-py3-none-any.whl#sha256=deadbeef",
+		CoreMetadata: pypi.CoreMetadata{Available: true, SHA256: hex.EncodeToString(sum[:])},
+	})
+	if err != nil {
+		t.Fatalf("GetCoreMetadata() error: %v", err)
+	}
+
+	if wheelRequested {
+		t.Error("wheel file was requested; expected only the .metadata file")
+	}
+
+	if info.Version != "3.0.0" || len(info.RequiresDist) != 3 {
+		t.Errorf("unexpected metadata: version=%q requires_dist=%v", info.Version, info.RequiresDist)
+	}
+}
+
+func TestGetCoreMetadataHashMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testMetadata))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := pypi.New(pypi.WithHTTPClient(srv.Client()))
+
+	_, err := client.GetCoreMetadata(context.Background(), pypi.URL{
+		Filename:     "flask-3.0.0-py3-none-any.whl",
+		URL:          srv.URL + "/flask-3.0.0-py3-none-any.whl",
+		CoreMetadata: pypi.CoreMetadata{Available: true, SHA256: "0000"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected sha256 mismatch error, got %v", err)
+	}
+}
+
+func TestGetCoreMetadataNotAdvertised(t *testing.T) {
+	client := pypi.New()
+
+	_, err := client.GetCoreMetadata(context.Background(), pypi.URL{
+		Filename: "flask-3.0.0-py3-none-any.whl",
+		URL:      "https://files.example/flask-3.0.0-py3-none-any.whl",
+	})
+	if err == nil {
+		t.Fatal("expected error when core metadata is not advertised, got nil")
+	}
+}
+
+func TestGetWheelMetadataRange(t *testing.T) {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	// A large stored payload ahead of METADATA, which a full download would read.
+	payload, err := zw.CreateHeader(&zip.FileHeader{Name: "demo/data.bin", Method: zip.Store})
+	if err != nil {
+		t.Fatalf("creating payload: %v", err)
+	}
+
+	if _, err := payload.Write(bytes.Repeat([]byte{0xff}, 1<<20)); err != nil {
+		t.Fatalf("writing payload: %v", err)
+	}
+
+	w, err := zw.Create("demo-1.0.dist-info/METADATA")
+	if err != nil {
+		t.Fatalf("creating METADATA: %v", err)
+	}
+
+	if _, err := w.Write([]byte(testMetadata)); err != nil {
+		t.Fatalf("writing METADATA: %v", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing wheel: %v", err)
+	}
+
+	wheel := buf.Bytes()
+
+	var (
+		served   atomic.Int64
+		fullGets atomic.Int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.Header.Get("Range") == "" {
+			fullGets.Add(1)
+		}
+
+		cw := &countingWriter{ResponseWriter: w, n: &served}
+		http.ServeContent(cw, r, "demo-1.0-py3-none-any.whl", time.Time{}, bytes.NewReader(wheel))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := pypi.New(pypi.WithHTTPClient(srv.Client()))
+
+	info, err := client.GetWheelMetadata(context.Background(), pypi.URL{
+		Filename: "demo-1.0-py3-none-any.whl",
+		URL:      srv.URL + "/demo-1.0-py3-none-any.whl",
+	})
+	if err != nil {
+		t.Fatalf("GetWheelMetadata() error: %v", err)
+	}
+
+	if info.Version != "3.0.0" || len(info.RequiresDist) != 3 {
+		t.Errorf("unexpected metadata: version=%q requires_dist=%v", info.Version, info.RequiresDist)
+	}
+
+	if n := fullGets.Load(); n != 0 {
+		t.Errorf("wheel downloaded in full %d times, want only range requests", n)
+	}
+
+	if n := served.Load(); n >= int64(len(wheel))/4 {
+		t.Errorf("served %d of %d bytes, want only the end of the wheel", n, len(wheel))
+	}
+}
+
+func TestGetWheelMetadataHashMismatch(t *testing.T) {
+	wheel := buildWheel(t, testMetadata)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(wheel)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := pypi.New(pypi.WithHTTPClient(srv.Client()))
+
+	_, err := client.GetWheelMetadata(context.Background(), pypi.URL{
+		Filename: "demo-1.0-py3-none-any.whl",
+		URL:      srv.URL + "/demo-1.0-py3-none-any.whl",
+		Digests:  pypi.Digests{SHA256: "0000"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected sha256 mismatch error, got %v", err)
+	}
+}
+
+// countingWriter counts the body bytes written to a response.
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n.Add(int64(len(p)))
+
+	return w.ResponseWriter.Write(p)
+}
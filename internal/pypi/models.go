@@ -1,5 +1,11 @@
 package pypi
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // PackageInfo represents the top-level response from the PyPI JSON API.
 // Endpoint: GET https://pypi.org/pypi/{package_name}/json
 type PackageInfo struct {
@@ -15,6 +21,7 @@ type Info struct {
 	Summary        string            `json:"summary"`
 	RequiresDist   []string          `json:"requires_dist"`
 	RequiresPython string            `json:"requires_python"`
+	Classifiers    []string          `json:"classifiers"`
 	PackageURL     string            `json:"package_url"`
 	ProjectURL     string            `json:"project_url"`
 	ProjectURLs    map[string]string `json:"project_urls"`
@@ -22,6 +29,20 @@ type Info struct {
 	YankedReason   string            `json:"yanked_reason"`
 }
 
+// inactiveClassifier marks a project its maintainers no longer develop.
+const inactiveClassifier = "Development Status :: 7 - Inactive"
+
+// Inactive reports whether the project is classified as inactive.
+func (i Info) Inactive() bool {
+	for _, c := range i.Classifiers {
+		if strings.TrimSpace(c) == inactiveClassifier {
+			return true
+		}
+	}
+
+	return false
+}
+
 // URL represents a downloadable file (wheel or sdist) from the PyPI API response.
 type URL struct {
 	Filename       string  `json:"filename"`
@@ -33,6 +54,50 @@ type URL struct {
 	Digests        Digests `json:"digests"`
 	Yanked         bool    `json:"yanked"`
 	YankedReason   string  `json:"yanked_reason"`
+
+	// PEP 658 / PEP 714: a standalone METADATA file is served at {url}.metadata.
+	CoreMetadata     CoreMetadata `json:"core-metadata"`
+	DistInfoMetadata CoreMetadata `json:"data-dist-info-metadata"`
+}
+
+// HasCoreMetadata reports whether the index advertises a PEP 658 metadata file for u.
+func (u URL) HasCoreMetadata() bool {
+	return u.CoreMetadata.Available || u.DistInfoMetadata.Available
+}
+
+// CoreMetadata describes a PEP 658 metadata file. Indexes advertise it either as
+// a boolean or as a hash mapping such as {"sha256": "..."}.
+type CoreMetadata struct {
+	Available bool
+	SHA256    string
+}
+
+// UnmarshalJSON accepts both the boolean and the hash mapping forms.
+func (m *CoreMetadata) UnmarshalJSON(data []byte) error {
+	var available bool
+	if err := json.Unmarshal(data, &available); err == nil {
+		*m = CoreMetadata{Available: available}
+
+		return nil
+	}
+
+	var hashes map[string]string
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return fmt.Errorf("decoding core-metadata: %w", err)
+	}
+
+	*m = CoreMetadata{Available: true, SHA256: hashes["sha256"]}
+
+	return nil
+}
+
+// MarshalJSON encodes the hash mapping form when a digest is known.
+func (m CoreMetadata) MarshalJSON() ([]byte, error) {
+	if m.SHA256 != "" {
+		return json.Marshal(map[string]string{"sha256": m.SHA256})
+	}
+
+	return json.Marshal(m.Available)
 }
 
 // Digests contains hash digests for verifying downloaded files.
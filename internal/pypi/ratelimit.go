@@ -0,0 +1,84 @@
+package pypi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps the wait a Retry-After header can ask for, so a server
+// asking for hours does not stall an install; the retry then fails sooner.
+const maxRetryAfter = time.Minute
+
+// RateLimitError reports a response the server marked as rate limited: a 429,
+// or a 403 with rate-limit signals, which some mirrors send instead. Callers
+// retry it after RetryAfter, the wait the server asked for (zero if none).
+type RateLimitError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited (status %d) by %s", e.StatusCode, e.URL)
+}
+
+// DeniedError classifies a 403 or 429 response to a request for url. A 429,
+// or a 403 with a Retry-After header, an exhausted X-RateLimit-Remaining
+// header or a body mentioning rate limits, is a *RateLimitError. Any other
+// 403 is an authentication failure, worded by whether the request carried
+// credentials.
+func DeniedError(resp *http.Response, url string) error {
+	if resp.StatusCode == http.StatusTooManyRequests || isRateLimited(resp) {
+		return &RateLimitError{URL: url, StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
+	if resp.Request != nil && resp.Request.Header.Get("Authorization") != "" {
+		return fmt.Errorf("authentication required or failed (status %d) for %s; "+
+			"the credentials sent were rejected, check the token or the user info of the index URL", resp.StatusCode, url)
+	}
+
+	return fmt.Errorf("authentication required or failed (status %d) for %s; "+
+		"no credentials were sent, configure a token or user info for this index (only index hosts receive them)", resp.StatusCode, url)
+}
+
+// RetryAfter returns the wait requested by a *RateLimitError in err's chain,
+// or zero.
+func RetryAfter(err error) time.Duration {
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return rl.RetryAfter
+	}
+
+	return 0
+}
+
+// isRateLimited reports whether a response carries rate-limit signals.
+func isRateLimited(resp *http.Response) bool {
+	if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	text := strings.ToLower(string(body))
+
+	return strings.Contains(text, "rate limit") || strings.Contains(text, "too many requests")
+}
+
+// retryAfter parses a Retry-After value, either delay seconds or an HTTP
+// date, capped at maxRetryAfter. Missing or invalid values yield zero.
+func retryAfter(value string, now time.Time) time.Duration {
+	var wait time.Duration
+
+	if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		wait = t.Sub(now)
+	}
+
+	return min(max(wait, 0), maxRetryAfter)
+}
@@ -0,0 +1,351 @@
+package pypi
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const defaultSimpleURL = "https://pypi.org/simple"
+
+// SimpleService reads packages from a PEP 503 simple repository, the HTML
+// index served by pip-compatible mirrors that lack the JSON API. The project
+// page only lists files, so the dependencies of a version are read from its
+// PEP 658 metadata file when the index advertises one, or else from the
+// METADATA of one of its wheels.
+type SimpleService struct {
+	svc *Service
+
+	mu       sync.Mutex
+	projects map[string]*PackageInfo
+	versions map[string]*PackageInfo
+}
+
+// compile-time proof that SimpleService implements Client.
+var _ Client = (*SimpleService)(nil)
+
+// NewSimple creates a client for a PEP 503 simple index. It accepts the same
+// options as New; the base URL defaults to https://pypi.org/simple.
+func NewSimple(opts ...Option) *SimpleService {
+	svc := New(opts...)
+	if svc.baseURL == defaultBaseURL {
+		svc.baseURL = defaultSimpleURL
+	}
+
+	return &SimpleService{
+		svc:      svc,
+		projects: make(map[string]*PackageInfo),
+		versions: make(map[string]*PackageInfo),
+	}
+}
+
+// GetPackage lists the files of a project, grouped by version into Releases.
+// Info.Version is left empty: the page does not say which release is latest.
+// Endpoint: GET {baseURL}/{normalized_name}/
+func (s *SimpleService) GetPackage(ctx context.Context, name string) (*PackageInfo, error) {
+	key := projectName(name)
+
+	s.mu.Lock()
+	info, ok := s.projects[key]
+	s.mu.Unlock()
+
+	if ok {
+		return info, nil
+	}
+
+	info, err := s.svc.fetchFirst(ctx, name, key+"/", s.fetchPage)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.projects[key] = info
+	s.mu.Unlock()
+
+	return info, nil
+}
+
+// GetPackageVersion returns the files of one version, with Info filled from
+// the version's core metadata.
+func (s *SimpleService) GetPackageVersion(ctx context.Context, name, version string) (*PackageInfo, error) {
+	key := projectName(name) + "==" + version
+
+	s.mu.Lock()
+	cached, ok := s.versions[key]
+	s.mu.Unlock()
+
+	if ok {
+		return cached, nil
+	}
+
+	project, err := s.GetPackage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	files, ok := project.Releases[version]
+	if !ok {
+		return nil, fmt.Errorf("fetching %s: %w: version %s is not listed on the index", name, errNotFound, version)
+	}
+
+	info := &PackageInfo{
+		Info:     Info{Name: project.Info.Name, Version: version},
+		URLs:     files,
+		Releases: map[string][]URL{version: files},
+	}
+
+	meta, err := s.versionMetadata(ctx, name, version, files)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta != nil {
+		info.Info.Summary = meta.Summary
+		info.Info.RequiresDist = meta.RequiresDist
+		info.Info.RequiresPython = meta.RequiresPython
+		info.Info.Classifiers = meta.Classifiers
+	}
+
+	if info.Info.RequiresPython == "" {
+		for _, f := range files {
+			if f.RequiresPython != "" {
+				info.Info.RequiresPython = f.RequiresPython
+
+				break
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.versions[key] = info
+	s.mu.Unlock()
+
+	return info, nil
+}
+
+// versionMetadata reads the core metadata of a version from one of its
+// wheels, preferring a wheel with a PEP 658 metadata file. It returns nil if
+// the version has no wheel; sdist metadata is not authoritative.
+func (s *SimpleService) versionMetadata(ctx context.Context, name, version string, files []URL) (*Info, error) {
+	var wheel *URL
+
+	for i, f := range files {
+		if f.PackageType != "bdist_wheel" {
+			continue
+		}
+
+		if f.HasCoreMetadata() {
+			return s.svc.GetCoreMetadata(ctx, f)
+		}
+
+		if wheel == nil {
+			wheel = &files[i]
+		}
+	}
+
+	if wheel == nil {
+		s.svc.logger.Debug("no wheel to read dependencies from",
+			slog.String("package", name), slog.String("version", version))
+
+		return nil, nil
+	}
+
+	return s.svc.GetWheelMetadata(ctx, *wheel)
+}
+
+// GetWheelMetadata downloads a wheel and parses the METADATA file in its
+// .dist-info directory.
+func (s *SimpleService) GetWheelMetadata(ctx context.Context, file URL) (*Info, error) {
+	return s.svc.GetWheelMetadata(ctx, file)
+}
+
+// fetchPage downloads a project page and parses its file listing.
+func (s *SimpleService) fetchPage(ctx context.Context, pageURL, name string) (*PackageInfo, error) {
+	body, err := s.svc.get(ctx, pageURL, name, "text/html")
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := ParseSimplePage(pageURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: parsing project page %s: %w", name, pageURL, err)
+	}
+
+	info.Info.Name = name
+
+	return info, nil
+}
+
+var (
+	anchorPattern    = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a\s*>`)
+	attributePattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	namePattern      = regexp.MustCompile(`[-_.]+`)
+)
+
+// ParseSimplePage parses a PEP 503 project page served at pageURL into a
+// PackageInfo whose Releases group the listed wheels and sdists by the
+// version in their filenames. Relative links are resolved against pageURL and
+// a "#sha256=" fragment becomes the file's digest. Files of other types are
+// ignored.
+func ParseSimplePage(pageURL string, body []byte) (*PackageInfo, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL %q: %w", pageURL, err)
+	}
+
+	info := &PackageInfo{Releases: make(map[string][]URL)}
+
+	for _, m := range anchorPattern.FindAllSubmatch(body, -1) {
+		attrs := parseAttributes(string(m[1]))
+
+		href, ok := attrs["href"]
+		if !ok {
+			continue
+		}
+
+		link, err := base.Parse(href)
+		if err != nil {
+			continue
+		}
+
+		file := URL{
+			Filename:       strings.TrimSpace(html.UnescapeString(string(m[2]))),
+			RequiresPython: attrs["data-requires-python"],
+		}
+
+		if algo, digest, ok := strings.Cut(link.Fragment, "="); ok && algo == "sha256" {
+			file.Digests.SHA256 = digest
+		}
+
+		link.Fragment = ""
+		file.URL = link.String()
+
+		if name := path.Base(link.Path); file.Filename == "" || strings.Contains(file.Filename, "<") {
+			file.Filename = name
+		}
+
+		if reason, ok := attrs["data-yanked"]; ok {
+			file.Yanked, file.YankedReason = true, reason
+		}
+
+		if value, ok := attrs["data-core-metadata"]; ok {
+			file.CoreMetadata = parseMetadataAttribute(value)
+		}
+
+		if value, ok := attrs["data-dist-info-metadata"]; ok {
+			file.DistInfoMetadata = parseMetadataAttribute(value)
+		}
+
+		version, ok := classifyFile(&file)
+		if !ok {
+			continue
+		}
+
+		info.Releases[version] = append(info.Releases[version], file)
+	}
+
+	return info, nil
+}
+
+// parseAttributes returns the attributes of a tag, lowercased and with
+// character references decoded. Valueless attributes map to "".
+func parseAttributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+
+	for _, m := range attributePattern.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+
+	return attrs
+}
+
+// parseMetadataAttribute decodes a data-core-metadata value: "true" or a
+// "<hashname>=<hashvalue>" pair. "false" means no metadata file.
+func parseMetadataAttribute(value string) CoreMetadata {
+	if algo, digest, ok := strings.Cut(value, "="); ok {
+		if algo == "sha256" {
+			return CoreMetadata{Available: true, SHA256: digest}
+		}
+
+		return CoreMetadata{Available: true}
+	}
+
+	return CoreMetadata{Available: value != "false"}
+}
+
+// sdistExtensions are the source distribution formats recognized on project
+// pages.
+var sdistExtensions = []string{".tar.gz", ".zip", ".tar.bz2", ".tar.xz", ".tgz"}
+
+// classifyFile sets the package type and Python tag of file from its
+// filename and returns the version the filename carries. Only wheels and
+// sdists are recognized.
+func classifyFile(file *URL) (string, bool) {
+	if strings.HasSuffix(file.Filename, ".whl") {
+		_, version, tag, err := ParseWheelFilename(file.Filename)
+		if err != nil {
+			return "", false
+		}
+
+		file.PackageType = "bdist_wheel"
+		file.PythonVersion = tag.Python
+
+		return version, true
+	}
+
+	for _, ext := range sdistExtensions {
+		if stem, ok := strings.CutSuffix(file.Filename, ext); ok {
+			// {name}-{version}.{ext}; names may contain dashes in older sdists.
+			i := strings.LastIndex(stem, "-")
+			if i <= 0 || i == len(stem)-1 {
+				return "", false
+			}
+
+			file.PackageType = "sdist"
+			file.PythonVersion = "source"
+
+			return stem[i+1:], true
+		}
+	}
+
+	return "", false
+}
+
+// WheelFromURL describes the wheel at a direct URL, such as the target of a
+// PEP 508 "name @ url" requirement, and returns the version its filename
+// carries. A "#sha256=" fragment becomes the file's digest.
+func WheelFromURL(rawURL string) (URL, string, error) {
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return URL{}, "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	var file URL
+
+	if algo, digest, ok := strings.Cut(link.Fragment, "="); ok && algo == "sha256" {
+		file.Digests.SHA256 = digest
+	}
+
+	link.Fragment = ""
+	file.URL = link.String()
+	file.Filename = path.Base(link.Path)
+
+	version, ok := classifyFile(&file)
+	if !ok || file.PackageType != "bdist_wheel" {
+		return URL{}, "", fmt.Errorf("%s is not a wheel; only wheel URLs can be installed", rawURL)
+	}
+
+	return file, version, nil
+}
+
+// projectName normalizes a project name for its simple index URL, per
+// PEP 503, e.g., "Typing.Extensions" → "typing-extensions".
+func projectName(name string) string {
+	return namePattern.ReplaceAllString(strings.ToLower(name), "-")
+}
@@ -0,0 +1,223 @@
+package pypi_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+const testSimplePage = `<!DOCTYPE html>
+<html><body>
+<h1>Links for requests</h1>
+<a href="../../files/requests-2.31.0-py3-none-any.whl#sha256=abc123" data-requires-python="&gt;=3.7">requests-2.31.0-py3-none-any.whl</a><br/>
+<a href="../../files/requests-2.31.0.tar.gz#sha256=def456" data-requires-python="&gt;=3.7">requests-2.31.0.tar.gz</a><br/>
+<a href="https://files.example.com/requests-2.30.0-py3-none-any.whl" data-yanked="broken release">requests-2.30.0-py3-none-any.whl</a><br/>
+<a href='/files/requests-2.29.0-py3-none-any.whl' data-core-metadata="sha256=fff">requests-2.29.0-py3-none-any.whl</a><br/>
+<a href="/files/requests-2.28.0.egg">requests-2.28.0.egg</a><br/>
+</body></html>`
+
+func TestParseSimplePage(t *testing.T) {
+	info, err := pypi.ParseSimplePage("https://mirror.example.com/simple/requests/", []byte(testSimplePage))
+	if err != nil {
+		t.Fatalf("ParseSimplePage() error: %v", err)
+	}
+
+	versions := make([]string, 0, len(info.Releases))
+	for v := range info.Releases {
+		versions = append(versions, v)
+	}
+
+	slices.Sort(versions)
+
+	if want := []string{"2.29.0", "2.30.0", "2.31.0"}; !slices.Equal(versions, want) {
+		t.Fatalf("versions = %v, want %v", versions, want)
+	}
+
+	files := info.Releases["2.31.0"]
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files for 2.31.0, got %d", len(files))
+	}
+
+	wheel := files[0]
+	if wheel.URL != "https://mirror.example.com/files/requests-2.31.0-py3-none-any.whl" {
+		t.Errorf("URL = %q, want the link resolved against the page without its fragment", wheel.URL)
+	}
+
+	if wheel.Digests.SHA256 != "abc123" || wheel.RequiresPython != ">=3.7" {
+		t.Errorf("wheel = %+v, want sha256 abc123 and requires_python >=3.7", wheel)
+	}
+
+	if wheel.PackageType != "bdist_wheel" || files[1].PackageType != "sdist" {
+		t.Errorf("package types = %q, %q; want bdist_wheel, sdist", wheel.PackageType, files[1].PackageType)
+	}
+
+	if yanked := info.Releases["2.30.0"][0]; !yanked.Yanked || yanked.YankedReason != "broken release" {
+		t.Errorf("2.30.0 = %+v, want yanked with reason", yanked)
+	}
+
+	if meta := info.Releases["2.29.0"][0]; !meta.HasCoreMetadata() || meta.CoreMetadata.SHA256 != "fff" {
+		t.Errorf("2.29.0 core metadata = %+v, want advertised with sha256 fff", meta.CoreMetadata)
+	}
+}
+
+// buildWheel returns a wheel holding only a METADATA file with the given body.
+func buildWheel(t *testing.T, metadata string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("demo-1.0.dist-info/METADATA")
+	if err != nil {
+		t.Fatalf("creating METADATA: %v", err)
+	}
+
+	if _, err := w.Write([]byte(metadata)); err != nil {
+		t.Fatalf("writing METADATA: %v", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing wheel: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSimpleGetPackageVersionReadsWheel(t *testing.T) {
+	wheel := buildWheel(t, "Metadata-Version: 2.1\nName: demo\nVersion: 1.0\nRequires-Dist: six>=1.16\n\n")
+	sum := sha256.Sum256(wheel)
+
+	var pageHits atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/simple/demo/", func(w http.ResponseWriter, _ *http.Request) {
+		pageHits.Add(1)
+		_, _ = fmt.Fprintf(w, `<a href="/files/demo-1.0-py3-none-any.whl#sha256=%s">demo-1.0-py3-none-any.whl</a>`,
+			hex.EncodeToString(sum[:]))
+	})
+	mux.HandleFunc("/files/demo-1.0-py3-none-any.whl", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(wheel)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := pypi.NewSimple(pypi.WithHTTPClient(srv.Client()), pypi.WithBaseURL(srv.URL+"/simple"))
+
+	info, err := client.GetPackageVersion(context.Background(), "Demo", "1.0")
+	if err != nil {
+		t.Fatalf("GetPackageVersion() error: %v", err)
+	}
+
+	if !slices.Equal(info.Info.RequiresDist, []string{"six>=1.16"}) {
+		t.Errorf("RequiresDist = %v, want [six>=1.16]", info.Info.RequiresDist)
+	}
+
+	if len(info.URLs) != 1 || info.URLs[0].Filename != "demo-1.0-py3-none-any.whl" {
+		t.Errorf("URLs = %+v, want the 1.0 wheel", info.URLs)
+	}
+
+	if _, err := client.GetPackage(context.Background(), "demo"); err != nil {
+		t.Fatalf("GetPackage() error: %v", err)
+	}
+
+	if n := pageHits.Load(); n != 1 {
+		t.Errorf("project page fetched %d times, want 1", n)
+	}
+}
+
+func TestSimpleGetPackageVersionCoreMetadata(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/simple/demo/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `<a href="/files/demo-1.0-py3-none-any.whl" data-core-metadata="true">demo-1.0-py3-none-any.whl</a>`)
+	})
+	mux.HandleFunc("/files/demo-1.0-py3-none-any.whl.metadata", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, "Name: demo\nVersion: 1.0\nRequires-Python: >=3.9\nRequires-Dist: idna\n")
+	})
+	mux.HandleFunc("/files/demo-1.0-py3-none-any.whl", func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("wheel downloaded although core metadata is advertised")
+		http.NotFound(w, nil)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := pypi.NewSimple(pypi.WithHTTPClient(srv.Client()), pypi.WithBaseURL(srv.URL+"/simple"))
+
+	info, err := client.GetPackageVersion(context.Background(), "demo", "1.0")
+	if err != nil {
+		t.Fatalf("GetPackageVersion() error: %v", err)
+	}
+
+	if !slices.Equal(info.Info.RequiresDist, []string{"idna"}) || info.Info.RequiresPython != ">=3.9" {
+		t.Errorf("Info = %+v, want requires_dist [idna] and requires_python >=3.9", info.Info)
+	}
+}
+
+func TestSimpleGetPackageVersionUnknown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `<a href="/files/demo-1.0.tar.gz">demo-1.0.tar.gz</a>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := pypi.NewSimple(pypi.WithHTTPClient(srv.Client()), pypi.WithBaseURL(srv.URL+"/simple"))
+
+	if _, err := client.GetPackageVersion(context.Background(), "demo", "2.0"); err == nil {
+		t.Error("expected error for a version missing from the page, got nil")
+	}
+}
+
+func TestWheelFromURL(t *testing.T) {
+	file, version, err := pypi.WheelFromURL("https://files.example.com/a/somedep-1.0-py3-none-any.whl#sha256=abcd")
+	if err != nil {
+		t.Fatalf("WheelFromURL() error: %v", err)
+	}
+
+	if version != "1.0" || file.Filename != "somedep-1.0-py3-none-any.whl" || file.PackageType != "bdist_wheel" {
+		t.Errorf("got version %q, file %+v", version, file)
+	}
+
+	if file.URL != "https://files.example.com/a/somedep-1.0-py3-none-any.whl" || file.Digests.SHA256 != "abcd" {
+		t.Errorf("URL = %q, sha256 = %q; want the fragment moved to the digest", file.URL, file.Digests.SHA256)
+	}
+
+	if _, _, err := pypi.WheelFromURL("https://files.example.com/somedep-1.0.tar.gz"); err == nil {
+		t.Error("expected an error for an sdist URL, got nil")
+	}
+}
+
+func TestNormalizeSimpleIndexURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://mirror.example.com/simple/", "https://mirror.example.com/simple"},
+		{"https://mirror.example.com/pypi", "https://mirror.example.com/simple"},
+		{"https://mirror.example.com", "https://mirror.example.com/simple"},
+		{"http://localhost:3141/root/pypi/+simple/", "http://localhost:3141/root/pypi/+simple"},
+	}
+
+	for _, tt := range tests {
+		got, err := pypi.NormalizeSimpleIndexURL(tt.in)
+		if err != nil {
+			t.Errorf("NormalizeSimpleIndexURL(%q) error: %v", tt.in, err)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("NormalizeSimpleIndexURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
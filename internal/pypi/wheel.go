@@ -0,0 +1,51 @@
+package pypi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WheelTag represents a PEP 425 compatibility tag.
+type WheelTag struct {
+	Python   string // e.g., "cp312", "py3"
+	ABI      string // e.g., "cp312", "none"
+	Platform string // e.g., "manylinux_2_17_x86_64", "any"
+
+	// Build is the optional PEP 427 build tag of a wheel filename, e.g., "1".
+	// It is not part of compatibility; it only breaks ties between otherwise
+	// equal wheels.
+	Build string
+}
+
+// ParseWheelFilename parses a wheel filename into its components.
+// Format: {name}-{ver}(-{build})?-{python}-{abi}-{platform}.whl
+func ParseWheelFilename(filename string) (name, version string, tag WheelTag, err error) {
+	filename = strings.TrimSuffix(filename, ".whl")
+
+	parts := strings.Split(filename, "-")
+	if len(parts) != 5 && len(parts) != 6 {
+		return "", "", WheelTag{}, fmt.Errorf("invalid wheel filename %q: expected 5 or 6 parts, got %d", filename, len(parts))
+	}
+
+	// Last 3 parts are always python-abi-platform.
+	// First part is name, second is version.
+	// Optional build tag is between version and python tag.
+	tag = WheelTag{
+		Python:   parts[len(parts)-3],
+		ABI:      parts[len(parts)-2],
+		Platform: parts[len(parts)-1],
+	}
+
+	name = parts[0]
+	version = parts[1]
+
+	if len(parts) == 6 {
+		tag.Build = parts[2]
+
+		if tag.Build == "" || tag.Build[0] < '0' || tag.Build[0] > '9' {
+			return "", "", WheelTag{}, fmt.Errorf("invalid wheel filename %q: build tag %q must start with a digit", filename, tag.Build)
+		}
+	}
+
+	return name, version, tag, nil
+}
@@ -0,0 +1,212 @@
+package pypi
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// wheelChunkSize is the smallest range read from a remote wheel. The first
+// read covers the end of the file, which holds the zip central directory and,
+// since .dist-info is written last, usually METADATA too.
+const wheelChunkSize = 64 * 1024
+
+// GetWheelMetadata reads the METADATA file in the .dist-info directory of a
+// remote wheel. When the server accepts HTTP Range requests only the zip
+// central directory and the METADATA entry are fetched; the wheel's digest
+// cannot be checked then and is left to the download. Otherwise the wheel is
+// streamed to a temporary file and verified before it is read.
+func (s *Service) GetWheelMetadata(ctx context.Context, file URL) (*Info, error) {
+	size, ok := s.rangeSize(ctx, file)
+	if !ok {
+		return s.downloadWheelMetadata(ctx, file)
+	}
+
+	s.logger.Debug("reading wheel metadata with range requests",
+		slog.String("file", file.Filename), slog.Int64("size", size))
+
+	r, err := zip.NewReader(&rangeReader{ctx: ctx, svc: s, file: file, size: size}, size)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", file.Filename, err)
+	}
+
+	return readWheelMetadata(r, file.Filename)
+}
+
+// rangeSize sends a HEAD request for file and returns its size if the server
+// advertises byte ranges. Any failure falls back to a full download, which
+// reports the error if it persists.
+func (s *Service) rangeSize(ctx context.Context, file URL) (int64, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, file.URL, nil)
+	if err != nil {
+		return 0, false
+	}
+
+	var size int64
+
+	err = s.send(s.httpClient, req, func(resp *http.Response) error {
+		if resp.Header.Get("Accept-Ranges") == "bytes" {
+			size = resp.ContentLength
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.logger.Debug("HEAD request for wheel failed",
+			slog.String("file", file.Filename), slog.String("error", err.Error()))
+
+		return 0, false
+	}
+
+	return size, size > 0
+}
+
+// downloadWheelMetadata streams a wheel to a temporary file, checks its
+// digest, and reads its METADATA. The download is bounded by ctx alone, not
+// by the client timeout meant for index pages.
+func (s *Service) downloadWheelMetadata(ctx context.Context, file URL) (*Info, error) {
+	s.logger.Debug("downloading wheel to read its metadata", slog.String("file", file.Filename))
+
+	tmp, err := os.CreateTemp("", "pipg-*.whl")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file for %s: %w", file.Filename, err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	client := *s.httpClient
+	client.Timeout = 0
+
+	var (
+		size int64
+		sum  string
+	)
+
+	err = s.retry(ctx, file.Filename, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.URL, nil)
+		if err != nil {
+			return fmt.Errorf("creating request for %s: %w", file.URL, err)
+		}
+
+		return s.send(&client, req, func(resp *http.Response) error {
+			if err := tmp.Truncate(0); err != nil {
+				return err
+			}
+
+			if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			h := sha256.New()
+
+			n, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+			if err != nil {
+				return &retryableError{err: fmt.Errorf("reading response from %s: %w", file.URL, err)}
+			}
+
+			size, sum = n, hex.EncodeToString(h.Sum(nil))
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if expected := file.Digests.SHA256; expected != "" && sum != expected {
+		return nil, fmt.Errorf("sha256 mismatch for %s: expected %s, got %s", file.Filename, expected, sum)
+	}
+
+	r, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", file.Filename, err)
+	}
+
+	return readWheelMetadata(r, file.Filename)
+}
+
+// rangeReader is an io.ReaderAt over a remote file that fetches what is read
+// with HTTP Range requests, at least wheelChunkSize bytes at a time, and keeps
+// the last range for the reads that follow.
+type rangeReader struct {
+	ctx  context.Context
+	svc  *Service
+	file URL
+	size int64
+
+	buf []byte // the last range fetched
+	off int64  // offset of buf in the file
+}
+
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	end := min(off+int64(len(p)), r.size)
+
+	if off < r.off || end > r.off+int64(len(r.buf)) {
+		start, stop := off, min(max(end, off+wheelChunkSize), r.size)
+		if stop == r.size {
+			start = min(off, max(0, r.size-wheelChunkSize))
+		}
+
+		if err := r.fetch(start, stop); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf[off-r.off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// fetch reads bytes [start, stop) of the file into buf.
+func (r *rangeReader) fetch(start, stop int64) error {
+	url := r.file.URL
+	buf := make([]byte, stop-start)
+
+	err := r.svc.retry(r.ctx, r.file.Filename, func() error {
+		req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("creating request for %s: %w", url, err)
+		}
+
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, stop-1))
+
+		return r.svc.send(r.svc.httpClient, req, func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusPartialContent {
+				return fmt.Errorf("%s ignored the range request", url)
+			}
+
+			if _, err := io.ReadFull(resp.Body, buf); err != nil {
+				return &retryableError{err: fmt.Errorf("reading response from %s: %w", url, err)}
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.buf, r.off = buf, start
+
+	return nil
+}
@@ -5,19 +5,34 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 // pythonScript is the single Python command that collects all environment info.
-const pythonScript = `import sys, site, sysconfig
+const pythonScript = `import sys, site, sysconfig, platform
 print(sys.prefix)
 print(site.getsitepackages()[0])
 print(sysconfig.get_platform())
 print(f'{sys.version_info.major}{sys.version_info.minor}')
-print(sys.executable)`
-
-// expectedOutputLines is the number of lines expected from pythonScript.
-const expectedOutputLines = 5
+print(sys.executable)
+print(bool(sysconfig.get_config_var('Py_GIL_DISABLED')))
+paths = sysconfig.get_paths()
+for key in ('purelib', 'platlib', 'scripts', 'data', 'include'):
+    print(paths[key])
+print(platform.python_version())`
+
+// expectedOutputLines is the number of lines expected from the probe script.
+// The free-threading line, the five install scheme paths after it and the
+// full version line are optional so that custom probe scripts written for
+// older versions keep working.
+const (
+	minOutputLines      = 5
+	freeThreadedLines   = 6
+	schemeOutputLines   = 11
+	expectedOutputLines = 12
+)
 
 // Detector defines the interface for detecting a Python environment.
 type Detector interface {
@@ -29,9 +44,32 @@ type Environment struct {
 	PythonPath    string // path to the python binary
 	Prefix        string // sys.prefix
 	SitePackages  string // site-packages directory
+	PlatLib       string // sysconfig "platlib" when it differs from SitePackages; empty means SitePackages
+	ScriptsDir    string // sysconfig "scripts"; empty means prefix/bin (prefix/Scripts on Windows)
+	DataDir       string // sysconfig "data"; empty means Prefix
+	IncludeDir    string // sysconfig "include"; empty means prefix/include
 	PlatformTag   string // e.g., "macosx-14.0-arm64"
 	PythonVersion string // e.g., "312"
+	FullVersion   string // e.g., "3.12.1"; empty when the probe did not report it
 	IsVirtualEnv  bool
+	FreeThreaded  bool // built with Py_GIL_DISABLED, e.g., python3.13t
+}
+
+// IsWindows reports whether the environment targets Windows, whose
+// sysconfig platform is "win32" or "win-<arch>" (e.g., "win-amd64").
+func (e *Environment) IsWindows() bool {
+	return strings.HasPrefix(e.PlatformTag, "win")
+}
+
+// LibDirs returns the directories packages are installed into: SitePackages,
+// followed by PlatLib when it is a separate directory, such as lib64 on
+// Fedora and RHEL. Lookups of installed packages must scan all of them.
+func (e *Environment) LibDirs() []string {
+	if e.PlatLib == "" {
+		return []string{e.SitePackages}
+	}
+
+	return []string{e.SitePackages, e.PlatLib}
 }
 
 // CommandRunner executes a command and returns its combined output.
@@ -44,7 +82,8 @@ type EnvLookup func(string) string
 type Option func(*Service)
 
 // WithPythonBin sets the python binary path.
-// Defaults to "python3".
+// Defaults to "python3". A PEP 514 launcher spec such as "py:-3.11" runs the
+// Windows py launcher with the given arguments, i.e., "py -3.11".
 func WithPythonBin(bin string) Option {
 	return func(s *Service) {
 		if bin != "" {
@@ -73,12 +112,29 @@ func WithEnvLookup(fn EnvLookup) Option {
 	}
 }
 
+// WithProbeScript replaces the Python code run to inspect the interpreter,
+// for interpreters whose sys/site/sysconfig layout differs from CPython's.
+// The script must print the same lines as the default, in order:
+// sys.prefix, site-packages directory, platform tag, version without a dot
+// (e.g., "312"), the interpreter path, and optionally "True" or "False" for
+// whether the build is free-threaded (omitted means False), followed by the
+// sysconfig purelib, platlib, scripts, data and include paths, and the full
+// version (e.g., "3.12.1"). Trailing groups may be omitted.
+func WithProbeScript(script string) Option {
+	return func(s *Service) {
+		if script != "" {
+			s.probeScript = script
+		}
+	}
+}
+
 // Service detects the active Python environment by inspecting
 // environment variables and running the python binary.
 type Service struct {
-	pythonBin string
-	runCmd    CommandRunner
-	getenv    EnvLookup
+	pythonBin   string
+	probeScript string
+	runCmd      CommandRunner
+	getenv      EnvLookup
 }
 
 // compile-time proof that Service implements Detector.
@@ -87,9 +143,10 @@ var _ Detector = (*Service)(nil)
 // New creates a new Python environment detector.
 func New(opts ...Option) *Service {
 	s := &Service{
-		pythonBin: "python3",
-		runCmd:    defaultRunCmd,
-		getenv:    os.Getenv,
+		pythonBin:   "python3",
+		probeScript: pythonScript,
+		runCmd:      defaultRunCmd,
+		getenv:      os.Getenv,
 	}
 
 	for _, opt := range opts {
@@ -109,13 +166,15 @@ func (s *Service) Detect(ctx context.Context) (*Environment, error) {
 		env.IsVirtualEnv = true
 	}
 
-	output, err := s.runCmd(ctx, s.pythonBin, "-c", pythonScript)
+	name, args := s.probeCommand()
+
+	output, err := s.runCmd(ctx, name, args...)
 	if err != nil {
 		return nil, fmt.Errorf("running %s: %w", s.pythonBin, err)
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) != expectedOutputLines {
+	if n := len(lines); n != minOutputLines && n != freeThreadedLines && n != schemeOutputLines && n != expectedOutputLines {
 		return nil, fmt.Errorf("unexpected output from %s: expected %d lines, got %d",
 			s.pythonBin, expectedOutputLines, len(lines))
 	}
@@ -126,9 +185,46 @@ func (s *Service) Detect(ctx context.Context) (*Environment, error) {
 	env.PythonVersion = strings.TrimSpace(lines[3])
 	env.PythonPath = strings.TrimSpace(lines[4])
 
+	if len(lines) >= freeThreadedLines {
+		freeThreaded, err := strconv.ParseBool(strings.TrimSpace(lines[5]))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected free-threading flag from %s: %q", s.pythonBin, lines[5])
+		}
+
+		env.FreeThreaded = freeThreaded
+	}
+
+	if len(lines) >= schemeOutputLines {
+		if platlib := strings.TrimSpace(lines[7]); filepath.Clean(platlib) != filepath.Clean(env.SitePackages) {
+			env.PlatLib = platlib
+		}
+
+		env.ScriptsDir = strings.TrimSpace(lines[8])
+		env.DataDir = strings.TrimSpace(lines[9])
+		env.IncludeDir = strings.TrimSpace(lines[10])
+	}
+
+	if len(lines) == expectedOutputLines {
+		env.FullVersion = strings.TrimSpace(lines[11])
+	}
+
 	return env, nil
 }
 
+// launcherPrefix marks a --python value as arguments for the Windows py launcher.
+const launcherPrefix = "py:"
+
+// probeCommand returns the command and arguments that run the probe script,
+// translating a launcher spec like "py:-3.11" into "py -3.11 -c <script>".
+func (s *Service) probeCommand() (string, []string) {
+	spec, ok := strings.CutPrefix(s.pythonBin, launcherPrefix)
+	if !ok {
+		return s.pythonBin, []string{"-c", s.probeScript}
+	}
+
+	return "py", append(strings.Fields(spec), "-c", s.probeScript)
+}
+
 // defaultRunCmd executes a command using exec.CommandContext.
 func defaultRunCmd(ctx context.Context, name string, args ...string) ([]byte, error) {
 	return exec.CommandContext(ctx, name, args...).Output()
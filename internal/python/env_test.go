@@ -3,6 +3,8 @@ package python_test
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/bilusteknoloji/pipg/internal/python"
@@ -119,6 +121,87 @@ func TestDetectCustomPythonBin(t *testing.T) {
 	}
 }
 
+func TestDetectCustomProbeScript(t *testing.T) {
+	const probe = "import sys\nprint(sys.prefix)"
+
+	var capturedArgs []string
+
+	svc := python.New(
+		python.WithProbeScript(probe),
+		python.WithCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+			capturedArgs = args
+
+			return []byte("/opt/jy\n/opt/jy/Lib/site-packages\njava-1.8\n27\n/opt/jy/bin/jython\n"), nil
+		}),
+		python.WithEnvLookup(fakeEnv(nil)),
+	)
+
+	env, err := svc.Detect(context.Background())
+	if err != nil {
+		t.Fatalf("Detect() error: %v", err)
+	}
+
+	if len(capturedArgs) != 2 || capturedArgs[0] != "-c" || capturedArgs[1] != probe {
+		t.Errorf("expected args [-c %q], got %q", probe, capturedArgs)
+	}
+	if env.PlatformTag != "java-1.8" {
+		t.Errorf("expected platform tag %q, got %q", "java-1.8", env.PlatformTag)
+	}
+}
+
+func TestWithProbeScriptIgnoresEmpty(t *testing.T) {
+	var capturedArgs []string
+
+	svc := python.New(
+		python.WithProbeScript(""),
+		python.WithCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+			capturedArgs = args
+
+			return []byte("/usr\n/usr/lib/python3.12/site-packages\nlinux-x86_64\n312\n/usr/bin/python3\n"), nil
+		}),
+		python.WithEnvLookup(fakeEnv(nil)),
+	)
+
+	if _, err := svc.Detect(context.Background()); err != nil {
+		t.Fatalf("Detect() error: %v", err)
+	}
+
+	if len(capturedArgs) != 2 || !strings.Contains(capturedArgs[1], "sysconfig.get_platform()") {
+		t.Errorf("expected default probe script, got %q", capturedArgs)
+	}
+}
+
+func TestDetectPyLauncherSpec(t *testing.T) {
+	var capturedName string
+	var capturedArgs []string
+
+	svc := python.New(
+		python.WithPythonBin("py:-3.11"),
+		python.WithCommandRunner(func(_ context.Context, name string, args ...string) ([]byte, error) {
+			capturedName, capturedArgs = name, args
+
+			return []byte("C:\\Python311\nC:\\Python311\\Lib\\site-packages\nwin-amd64\n311\nC:\\Python311\\python.exe\n"), nil
+		}),
+		python.WithEnvLookup(fakeEnv(nil)),
+	)
+
+	env, err := svc.Detect(context.Background())
+	if err != nil {
+		t.Fatalf("Detect() error: %v", err)
+	}
+
+	if capturedName != "py" {
+		t.Errorf("expected command %q, got %q", "py", capturedName)
+	}
+	if len(capturedArgs) != 3 || capturedArgs[0] != "-3.11" || capturedArgs[1] != "-c" ||
+		!strings.Contains(capturedArgs[2], "sysconfig.get_platform()") {
+		t.Errorf("expected args [-3.11 -c <script>], got %q", capturedArgs)
+	}
+	if env.PythonPath != "C:\\Python311\\python.exe" {
+		t.Errorf("expected python path from sys.executable, got %q", env.PythonPath)
+	}
+}
+
 func TestDetectPythonNotFound(t *testing.T) {
 	svc := python.New(
 		python.WithCommandRunner(fakeRunner("", fmt.Errorf("executable not found"))),
@@ -131,6 +214,102 @@ func TestDetectPythonNotFound(t *testing.T) {
 	}
 }
 
+func TestDetectFreeThreaded(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{"free-threaded", "/usr\n/usr/lib/python3.13t/site-packages\nlinux-x86_64\n313\n/usr/bin/python3.13t\nTrue\n", true},
+		{"standard", "/usr\n/usr/lib/python3.13/site-packages\nlinux-x86_64\n313\n/usr/bin/python3.13\nFalse\n", false},
+		{"flag omitted", "/usr\n/usr/lib/python3.13/site-packages\nlinux-x86_64\n313\n/usr/bin/python3.13\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := python.New(
+				python.WithCommandRunner(fakeRunner(tt.output, nil)),
+				python.WithEnvLookup(fakeEnv(nil)),
+			)
+
+			env, err := svc.Detect(context.Background())
+			if err != nil {
+				t.Fatalf("Detect() error: %v", err)
+			}
+
+			if env.FreeThreaded != tt.want {
+				t.Errorf("FreeThreaded = %v, want %v", env.FreeThreaded, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSchemePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		purelib string
+		platlib string
+		want    string
+	}{
+		{"separate platlib", "/usr/lib/python3.12/site-packages", "/usr/lib64/python3.12/site-packages", "/usr/lib64/python3.12/site-packages"},
+		{"shared platlib", "/usr/lib/python3.12/site-packages", "/usr/lib/python3.12/site-packages", ""},
+		{"platlib is site-packages", "/usr/local/lib/python3.12/site-packages", "/usr/lib/python3.12/site-packages", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := python.New(
+				python.WithCommandRunner(fakeRunner(
+					"/usr\n/usr/lib/python3.12/site-packages\nlinux-x86_64\n312\n/usr/bin/python3\nFalse\n"+
+						tt.purelib+"\n"+tt.platlib+"\n/usr/local/bin\n/usr/local\n/usr/include/python3.12\n", nil,
+				)),
+				python.WithEnvLookup(fakeEnv(nil)),
+			)
+
+			env, err := svc.Detect(context.Background())
+			if err != nil {
+				t.Fatalf("Detect() error: %v", err)
+			}
+
+			if env.PlatLib != tt.want {
+				t.Errorf("PlatLib = %q, want %q", env.PlatLib, tt.want)
+			}
+
+			wantDirs := []string{"/usr/lib/python3.12/site-packages"}
+			if tt.want != "" {
+				wantDirs = append(wantDirs, tt.want)
+			}
+
+			if got := env.LibDirs(); !slices.Equal(got, wantDirs) {
+				t.Errorf("LibDirs() = %v, want %v", got, wantDirs)
+			}
+
+			if env.ScriptsDir != "/usr/local/bin" || env.DataDir != "/usr/local" || env.IncludeDir != "/usr/include/python3.12" {
+				t.Errorf("scheme paths = %q, %q, %q", env.ScriptsDir, env.DataDir, env.IncludeDir)
+			}
+		})
+	}
+}
+
+func TestDetectFullVersion(t *testing.T) {
+	svc := python.New(
+		python.WithCommandRunner(fakeRunner(
+			"/usr\n/usr/lib/python3.12/site-packages\nlinux-x86_64\n312\n/usr/bin/python3\nFalse\n"+
+				"/usr/lib/python3.12/site-packages\n/usr/lib/python3.12/site-packages\n/usr/bin\n/usr\n/usr/include/python3.12\n3.12.1\n", nil,
+		)),
+		python.WithEnvLookup(fakeEnv(nil)),
+	)
+
+	env, err := svc.Detect(context.Background())
+	if err != nil {
+		t.Fatalf("Detect() error: %v", err)
+	}
+
+	if env.FullVersion != "3.12.1" || env.PythonVersion != "312" {
+		t.Errorf("FullVersion, PythonVersion = %q, %q; want 3.12.1, 312", env.FullVersion, env.PythonVersion)
+	}
+}
+
 func TestDetectUnexpectedOutput(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -138,7 +317,9 @@ func TestDetectUnexpectedOutput(t *testing.T) {
 	}{
 		{"empty output", ""},
 		{"too few lines", "/usr\n/usr/lib/site-packages\nlinux\n312\n"},
-		{"too many lines", "/usr\n/usr/lib/site-packages\nlinux\n312\n/usr/bin/python3\nextra\n"},
+		{"too many lines", "/usr\n/usr/lib/site-packages\nlinux\n312\n/usr/bin/python3\nFalse\nextra\n"},
+		{"invalid free-threading flag", "/usr\n/usr/lib/site-packages\nlinux\n312\n/usr/bin/python3\nmaybe\n"},
+		{"partial scheme paths", "/usr\n/usr/lib/site-packages\nlinux\n312\n/usr/bin/python3\nFalse\n/usr/lib/site-packages\n/usr/lib64/site-packages\n"},
 	}
 
 	for _, tt := range tests {
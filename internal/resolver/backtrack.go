@@ -0,0 +1,188 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/bilusteknoloji/pipg/internal/pypi"
+)
+
+// maxBacktracks bounds the re-walks of one resolution, so a hopeless
+// conflict among packages with many releases fails in reasonable time.
+const maxBacktracks = 1000
+
+// decision is what backtracking has learned about the choice of one package.
+type decision struct {
+	skip      int             // acceptable candidates to pass over, best first
+	learned   []Dependent     // specifiers that conflicted with an earlier choice
+	conflicts map[string]bool // packages implicated when this choice failed
+}
+
+// backtrackPlan carries decisions from one walk of the dependency graph into
+// the next. A walk is deterministic, so the next one makes the same choices
+// up to the decision that backtracking changed.
+type backtrackPlan struct {
+	order     []string // packages in the order the last walk decided them
+	decisions map[string]*decision
+}
+
+func newBacktrackPlan() *backtrackPlan {
+	return &backtrackPlan{decisions: make(map[string]*decision)}
+}
+
+// decision returns the state of name's choice; the zero decision picks the
+// best candidate.
+func (p *backtrackPlan) decision(name string) *decision {
+	d, ok := p.decisions[name]
+	if !ok {
+		d = &decision{conflicts: make(map[string]bool)}
+		p.decisions[name] = d
+	}
+
+	return d
+}
+
+// conflictError reports the conflict that ended a walk, with the specifiers
+// involved. Their requiring packages, and the conflicting choice itself, are
+// the decisions backtracking may revisit.
+type conflictError struct {
+	name    string
+	version string      // the conflicting choice; empty when no version matched
+	sources []Dependent // specifiers involved; Name is empty for root requirements
+	err     error
+}
+
+func (e *conflictError) Error() string { return e.err.Error() }
+func (e *conflictError) Unwrap() error { return e.err }
+
+// backtrack revises the plan after conflict c, returning the package whose
+// choice changes, or false when no decision can be revisited. It jumps to the
+// latest decision implicated in the conflict, so unrelated choices made in
+// between are not searched: a conflicting choice learns the specifiers it
+// failed, any other decision moves to its next candidate. Decisions made after
+// it are forgotten, as they may not recur.
+func (p *backtrackPlan) backtrack(c *conflictError) (string, bool) {
+	culprits := make(map[string]bool)
+	if c.version != "" {
+		culprits[c.name] = true
+	}
+
+	for _, d := range c.sources {
+		if d.Name != "" {
+			culprits[d.Name] = true
+		}
+	}
+
+	if d, ok := p.decisions[c.name]; ok {
+		for name := range d.conflicts {
+			culprits[name] = true
+		}
+	}
+
+	deepest := -1
+
+	for i, name := range p.order {
+		if culprits[name] {
+			deepest = i
+		}
+	}
+
+	if deepest < 0 {
+		return "", false
+	}
+
+	target := p.order[deepest]
+	d := p.decision(target)
+
+	if target == c.name && c.version != "" {
+		d.learned = append(d.learned, c.sources...)
+	} else {
+		d.skip++
+	}
+
+	for name := range culprits {
+		if name != target {
+			d.conflicts[name] = true
+		}
+	}
+
+	for _, name := range p.order[deepest+1:] {
+		delete(p.decisions, name)
+	}
+
+	p.order = p.order[:deepest+1]
+
+	return target, true
+}
+
+// memoClient caches index responses for the duration of one resolution, so
+// the walks after a backtrack do not fetch metadata again. Its maps are not
+// guarded: it relies on the resolver walking the graph sequentially.
+type memoClient struct {
+	client   pypi.Client
+	packages map[string]*pypi.PackageInfo
+	versions map[string]*pypi.PackageInfo
+	wheels   map[string]*pypi.Info // direct URL wheel metadata by URL
+}
+
+// compile-time proof that memoClient implements pypi.Client.
+var _ pypi.Client = (*memoClient)(nil)
+
+func newMemoClient(client pypi.Client) *memoClient {
+	return &memoClient{
+		client:   client,
+		packages: make(map[string]*pypi.PackageInfo),
+		versions: make(map[string]*pypi.PackageInfo),
+		wheels:   make(map[string]*pypi.Info),
+	}
+}
+
+func (m *memoClient) GetPackage(ctx context.Context, name string) (*pypi.PackageInfo, error) {
+	if info, ok := m.packages[name]; ok {
+		return info, nil
+	}
+
+	info, err := m.client.GetPackage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.packages[name] = info
+
+	return info, nil
+}
+
+func (m *memoClient) GetPackageVersion(ctx context.Context, name, version string) (*pypi.PackageInfo, error) {
+	key := name + "==" + version
+	if info, ok := m.versions[key]; ok {
+		return info, nil
+	}
+
+	info, err := m.client.GetPackageVersion(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	m.versions[key] = info
+
+	return info, nil
+}
+
+// wheelMetadata wraps fn so the metadata of each direct URL wheel, which
+// costs a download, is read once per resolution: walks after a backtrack and
+// the --validate audit reuse it.
+func (m *memoClient) wheelMetadata(fn WheelMetadata) WheelMetadata {
+	return func(ctx context.Context, file pypi.URL) (*pypi.Info, error) {
+		if info, ok := m.wheels[file.URL]; ok {
+			return info, nil
+		}
+
+		info, err := fn(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m.wheels[file.URL] = info
+
+		return info, nil
+	}
+}
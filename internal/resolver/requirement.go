@@ -2,6 +2,7 @@ package resolver
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 
 	pep440 "github.com/aquasecurity/go-pep440-version"
@@ -9,16 +10,33 @@ import (
 
 // Requirement represents a parsed PEP 508 dependency specifier.
 type Requirement struct {
-	Name      string // normalized package name
-	Specifier string // version specifier, e.g., ">=3.0,<4.0"
-	Marker    string // environment marker, e.g., `python_version < "3.10"`
+	Name      string   // normalized package name
+	Extras    []string // normalized requested extras, e.g., ["socks"]
+	Specifier string   // version specifier, e.g., ">=3.0,<4.0"
+	Marker    string   // environment marker, e.g., `python_version < "3.10"`
+	URL       string   // direct URL of a "name @ url" requirement, installed instead of an index release
 }
 
 // MarkerEnv holds environment variables used for evaluating PEP 508 markers.
 type MarkerEnv struct {
-	PythonVersion string // e.g., "3.12"
-	SysPlatform   string // e.g., "darwin", "linux"
-	OsName        string // e.g., "posix"
+	PythonVersion     string // e.g., "3.12"
+	PythonFullVersion string // e.g., "3.12.1"; empty means PythonVersion
+	SysPlatform       string // e.g., "darwin", "linux"
+	OsName            string // e.g., "posix"
+
+	// Extras requested for the package whose dependencies are being evaluated.
+	// `extra == "name"` terms are true only for names in this list.
+	Extras []string
+}
+
+// fullVersion returns the interpreter version with its micro part, falling
+// back to major.minor when the full version is unknown.
+func (e MarkerEnv) fullVersion() string {
+	if e.PythonFullVersion != "" {
+		return e.PythonFullVersion
+	}
+
+	return e.PythonVersion
 }
 
 // ParseRequirement parses a PEP 508 requirement string.
@@ -29,8 +47,14 @@ type MarkerEnv struct {
 //	"flask>=3.0"
 //	"flask>=3.0,<4.0"
 //	"flask (>=3.0)"
+//	"requests[socks,security]>=2.0"
 //	"importlib-metadata>=3.6.0; python_version < \"3.10\""
+//	"pip @ https://example.com/pip-24.0-py3-none-any.whl ; python_version >= \"3.8\""
 func ParseRequirement(s string) Requirement {
+	if req, ok := parseURLRequirement(s); ok {
+		return req
+	}
+
 	marker := ""
 
 	parts := strings.SplitN(s, ";", 2)
@@ -40,9 +64,17 @@ func ParseRequirement(s string) Requirement {
 		marker = strings.TrimSpace(parts[1])
 	}
 
-	// Strip extras: package[extra1,extra2]
+	// Extract extras: package[extra1,extra2]
+	var extras []string
+
 	if idx := strings.Index(nameSpec, "["); idx >= 0 {
 		if endIdx := strings.Index(nameSpec, "]"); endIdx > idx {
+			for _, e := range strings.Split(nameSpec[idx+1:endIdx], ",") {
+				if e = strings.TrimSpace(e); e != "" {
+					extras = append(extras, NormalizeName(e))
+				}
+			}
+
 			nameSpec = nameSpec[:idx] + nameSpec[endIdx+1:]
 		}
 	}
@@ -63,11 +95,36 @@ func ParseRequirement(s string) Requirement {
 
 	return Requirement{
 		Name:      NormalizeName(name),
+		Extras:    extras,
 		Specifier: specifier,
 		Marker:    marker,
 	}
 }
 
+// parseURLRequirement parses the PEP 508 direct reference form
+// "name[extras] @ url ; marker". The URL ends at whitespace, since it may
+// itself contain ';'.
+func parseURLRequirement(s string) (Requirement, bool) {
+	head, rest, ok := strings.Cut(s, "@")
+	if !ok || strings.ContainsAny(head, "<>=!~(;") {
+		return Requirement{}, false
+	}
+
+	rest = strings.TrimSpace(rest)
+	rawURL, marker := rest, ""
+
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		rawURL = rest[:i]
+		marker = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest[i:]), ";"))
+	}
+
+	req := ParseRequirement(head)
+	req.URL = rawURL
+	req.Marker = marker
+
+	return req, true
+}
+
 // NormalizeName normalizes a Python package name per PEP 503.
 // Converts to lowercase and replaces runs of [-_.] with a single hyphen.
 func NormalizeName(name string) string {
@@ -95,25 +152,21 @@ func NormalizeName(name string) string {
 
 // EvalMarker evaluates a PEP 508 environment marker against the given environment.
 // Returns true if the marker matches (dependency should be included).
-// Returns true for empty markers.
+// Returns true for empty markers. `extra` terms are evaluated against
+// env.Extras and combine with other terms through and/or and parentheses.
 func EvalMarker(marker string, env MarkerEnv) bool {
 	marker = strings.TrimSpace(marker)
 	if marker == "" {
 		return true
 	}
 
-	// Skip extra-conditional dependencies in v1
-	if strings.Contains(marker, "extra") {
-		return false
-	}
-
 	// Evaluate OR groups: any group true → true
 	for _, orGroup := range splitOutside(marker, " or ") {
 		// Evaluate AND terms: all terms true → group true
 		allTrue := true
 
 		for _, term := range splitOutside(strings.TrimSpace(orGroup), " and ") {
-			if !evalTerm(strings.TrimSpace(term), env) {
+			if !evalAtom(strings.TrimSpace(term), env) {
 				allTrue = false
 
 				break
@@ -128,6 +181,46 @@ func EvalMarker(marker string, env MarkerEnv) bool {
 	return false
 }
 
+// evalAtom evaluates a single term or a parenthesized sub-expression.
+func evalAtom(term string, env MarkerEnv) bool {
+	if inner, ok := stripParens(term); ok {
+		return EvalMarker(inner, env)
+	}
+
+	return evalTerm(term, env)
+}
+
+// stripParens removes one pair of parentheses enclosing the whole expression,
+// e.g., `(a or b)` → `a or b`, but leaves `(a) and (b)` untouched.
+func stripParens(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return s, false
+	}
+
+	depth := 0
+	inQuote := byte(0)
+
+	for i := 0; i < len(s)-1; i++ {
+		switch {
+		case inQuote != 0:
+			if s[i] == inQuote {
+				inQuote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			inQuote = s[i]
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+			if depth == 0 {
+				return s, false
+			}
+		}
+	}
+
+	return s[1 : len(s)-1], true
+}
+
 var markerTermRe = regexp.MustCompile(
 	`^\s*([\w.]+|"[^"]*"|'[^']*')\s*(>=|<=|!=|==|~=|>|<|not\s+in|in)\s*([\w.]+|"[^"]*"|'[^']*')\s*$`,
 )
@@ -144,6 +237,10 @@ func evalTerm(term string, env MarkerEnv) bool {
 	right := resolveMarkerValue(m[3], env)
 
 	lVar := unquote(m[1])
+	if lVar == "extra" || unquote(m[3]) == "extra" {
+		return evalExtra(m[1], op, m[3], env.Extras)
+	}
+
 	if isVersionVariable(lVar) || isVersionVariable(unquote(m[3])) {
 		return compareVersionMarker(left, op, right)
 	}
@@ -159,7 +256,7 @@ func resolveMarkerValue(token string, env MarkerEnv) string {
 	case "python_version":
 		return env.PythonVersion
 	case "python_full_version":
-		return env.PythonVersion
+		return env.fullVersion()
 	case "sys_platform":
 		return env.SysPlatform
 	case "os_name":
@@ -169,6 +266,26 @@ func resolveMarkerValue(token string, env MarkerEnv) string {
 	}
 }
 
+// evalExtra evaluates an `extra == "name"` term against the requested extras.
+// Names are compared after PEP 685 normalization.
+func evalExtra(left, op, right string, extras []string) bool {
+	name := unquote(right)
+	if unquote(left) != "extra" {
+		name = unquote(left)
+	}
+
+	requested := slices.Contains(extras, NormalizeName(name))
+
+	switch op {
+	case "==":
+		return requested
+	case "!=":
+		return !requested
+	default:
+		return false
+	}
+}
+
 func unquote(s string) string {
 	if len(s) >= 2 {
 		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
@@ -131,3 +131,90 @@ func TestEvalMarkerVersionComparison(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRequirementExtras(t *testing.T) {
+	req := resolver.ParseRequirement(`requests[socks, Security_Extra]>=2.0; python_version >= "3.8"`)
+
+	if req.Name != "requests" || req.Specifier != ">=2.0" {
+		t.Errorf("unexpected name/specifier: %q %q", req.Name, req.Specifier)
+	}
+
+	want := []string{"socks", "security-extra"}
+	if len(req.Extras) != len(want) || req.Extras[0] != want[0] || req.Extras[1] != want[1] {
+		t.Errorf("Extras = %v, want %v", req.Extras, want)
+	}
+}
+
+func TestParseRequirementURL(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantName   string
+		wantExtras int
+		wantURL    string
+		wantMark   string
+	}{
+		{"pip @ https://example.com/pip-24.0-py3-none-any.whl", "pip", 0, "https://example.com/pip-24.0-py3-none-any.whl", ""},
+		{
+			`Some_Dep[cli] @ https://example.com/a;b/some_dep-1.0-py3-none-any.whl ; python_version >= "3.8"`,
+			"some-dep", 1, "https://example.com/a;b/some_dep-1.0-py3-none-any.whl", `python_version >= "3.8"`,
+		},
+		{`flask>=3.0; platform_release == "5.15@custom"`, "flask", 0, "", `platform_release == "5.15@custom"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			req := resolver.ParseRequirement(tt.input)
+
+			if req.Name != tt.wantName || len(req.Extras) != tt.wantExtras {
+				t.Errorf("Name = %q, Extras = %v, want %q with %d extras", req.Name, req.Extras, tt.wantName, tt.wantExtras)
+			}
+			if req.URL != tt.wantURL {
+				t.Errorf("URL = %q, want %q", req.URL, tt.wantURL)
+			}
+			if req.Marker != tt.wantMark {
+				t.Errorf("Marker = %q, want %q", req.Marker, tt.wantMark)
+			}
+		})
+	}
+}
+
+func TestEvalMarkerExtras(t *testing.T) {
+	tests := []struct {
+		name   string
+		marker string
+		python string
+		extras []string
+		want   bool
+	}{
+		{"extra requested", `extra == "a"`, "3.12", []string{"a"}, true},
+		{"extra not requested", `extra == "a"`, "3.12", []string{"b"}, false},
+		{"extra name normalized", `extra == "Dev_Tools"`, "3.12", []string{"dev-tools"}, true},
+		{"extra not equal", `extra != "a"`, "3.12", nil, true},
+		{"extra and version both hold", `extra == "a" and python_version < "3.9"`, "3.8", []string{"a"}, true},
+		{"extra and version, version fails", `extra == "a" and python_version < "3.9"`, "3.12", []string{"a"}, false},
+		{"extra and version, extra missing", `extra == "a" and python_version < "3.9"`, "3.8", nil, false},
+		{"version first", `python_version < "3.9" and extra == "a"`, "3.8", []string{"a"}, true},
+		{"or across extras", `extra == "a" or extra == "b"`, "3.12", []string{"b"}, true},
+		{
+			"nested extra or with version",
+			`(extra == "a" or extra == "b") and python_version >= "3.10"`, "3.12", []string{"b"}, true,
+		},
+		{
+			"nested extra or, version fails",
+			`(extra == "a" or extra == "b") and python_version >= "3.10"`, "3.9", []string{"b"}, false,
+		},
+		{
+			"nested version or with extra",
+			`extra == "a" and (python_version < "3.9" or sys_platform == "linux")`, "3.12", []string{"a"}, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := resolver.MarkerEnv{PythonVersion: tt.python, SysPlatform: "linux", OsName: "posix", Extras: tt.extras}
+			if got := resolver.EvalMarker(tt.marker, env); got != tt.want {
+				t.Errorf("EvalMarker(%q) with extras %v = %v, want %v", tt.marker, tt.extras, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package resolver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/bilusteknoloji/pipg/internal/pypi"
 )
@@ -18,6 +22,83 @@ type ResolvedPackage struct {
 	Name         string
 	Version      string
 	Dependencies []string
+	RequiredBy   []Dependent // packages that depend on this one (roots excluded)
+	Root         bool        // requested directly rather than pulled in as a dependency
+	Extras       []string    // extras requested for this package
+	Skipped      []Skipped   // dependencies dropped by markers, set with WithCollectSkipped
+
+	// Yanked is set when the selected release is yanked (PEP 592), which
+	// happens only when no other release matches or it is the installed one.
+	Yanked       bool
+	YankedReason string
+	// YankedSkipped is the newest yanked release passed over for Version.
+	YankedSkipped string
+
+	// URL is set for a "name @ url" requirement: the wheel is installed from
+	// it rather than from the index.
+	URL string
+}
+
+// Skipped describes a declared dependency that was not installed because its
+// environment marker did not match.
+type Skipped struct {
+	Name   string // dependency name, e.g., "importlib-metadata"
+	Marker string // marker as declared, e.g., `python_version < "3.10"`
+	Reason string // e.g., "marker not satisfied (python 3.12, linux)"
+}
+
+// Dependent describes an edge from a requiring package to a resolved package.
+type Dependent struct {
+	Name      string // requiring package, e.g., "pandas"
+	Specifier string // specifier it placed on the dependency, e.g., ">=1.23"
+}
+
+// Conflict describes a package whose accumulated constraints cannot all be satisfied.
+type Conflict struct {
+	Name        string
+	Version     string      // version selected before the conflict surfaced, empty if none matched
+	Constraints []Dependent // competing specifiers; Name is empty for root requirements
+}
+
+// UnresolvableError aggregates every version conflict found in a single
+// resolution pass. It is returned when WithCollectConflicts is enabled.
+type UnresolvableError struct {
+	Conflicts []Conflict
+}
+
+func (e *UnresolvableError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+
+	for i, c := range e.Conflicts {
+		sources := make([]string, len(c.Constraints))
+		for j, d := range c.Constraints {
+			name := d.Name
+			if name == "" {
+				name = "requested"
+			}
+
+			sources[j] = name + "(" + d.Specifier + ")"
+		}
+
+		lines[i] = c.Name + ": " + strings.Join(sources, ", ")
+		if c.Version != "" {
+			lines[i] += "; selected " + c.Version
+		}
+	}
+
+	return fmt.Sprintf("cannot resolve %d packages:\n  %s", len(e.Conflicts), strings.Join(lines, "\n  "))
+}
+
+// errNoCompatibleVersion reports that no candidate satisfies the accumulated specifiers.
+var errNoCompatibleVersion = errors.New("no compatible version found")
+
+// queueItem is a requirement waiting to be resolved, along with the package
+// that declared it (empty for root requirements). Extras travel in req, so an
+// extra requested on a transitive dependency (e.g., "asgiref[tz]" in flask's
+// metadata) enables that dependency's own extra-gated requirements.
+type queueItem struct {
+	req    Requirement
+	parent string
 }
 
 // Option configures a Service.
@@ -37,6 +118,158 @@ func WithMarkerEnv(env MarkerEnv) Option {
 	}
 }
 
+// WheelCheck reports whether a release's files include a wheel that can be
+// installed in the target environment.
+type WheelCheck func(files []pypi.URL) bool
+
+// WithWheelCheck makes the resolver skip versions that have no compatible
+// wheel (e.g., sdist-only releases), falling back to the newest version that does.
+func WithWheelCheck(fn WheelCheck) Option {
+	return func(s *Service) {
+		s.wheelCheck = fn
+	}
+}
+
+// WheelMetadata reads the core metadata of a wheel by its URL. The
+// dependencies of direct URL requirements, which are not on the index, are
+// read with it.
+type WheelMetadata func(ctx context.Context, file pypi.URL) (*pypi.Info, error)
+
+// WithWheelMetadata sets the reader for the metadata of direct URL
+// requirements. Without it, a direct URL requirement can only be resolved
+// with WithNoDeps.
+func WithWheelMetadata(fn WheelMetadata) Option {
+	return func(s *Service) {
+		s.wheelMetadata = fn
+	}
+}
+
+// WithAllowlist restricts resolution to the given package names. Resolution
+// fails if any package outside the list would be installed. A nil or empty
+// list disables the check.
+func WithAllowlist(names []string) Option {
+	return func(s *Service) {
+		if len(names) == 0 {
+			s.allowlist = nil
+
+			return
+		}
+
+		s.allowlist = make(map[string]bool, len(names))
+		for _, n := range names {
+			s.allowlist[NormalizeName(n)] = true
+		}
+	}
+}
+
+// WithCollectConflicts makes the resolver keep going after a version conflict
+// and report every conflict at once as an *UnresolvableError, instead of
+// failing on the first one.
+func WithCollectConflicts(collect bool) Option {
+	return func(s *Service) {
+		s.collectConflicts = collect
+	}
+}
+
+// WithCollectSkipped records the dependencies dropped because their markers
+// did not match in ResolvedPackage.Skipped, so the decision can be audited.
+func WithCollectSkipped(collect bool) Option {
+	return func(s *Service) {
+		s.collectSkipped = collect
+	}
+}
+
+// WithAllowPrerelease makes pre-release versions candidates during resolution.
+// Without it, a pre-release is only selected when pinned exactly.
+func WithAllowPrerelease(allow bool) Option {
+	return func(s *Service) {
+		s.allowPrerelease = allow
+	}
+}
+
+// WithMaintenanceWarnings logs a warning for each resolved package whose
+// project is classified "Development Status :: 7 - Inactive" or whose latest
+// release has been yanked.
+func WithMaintenanceWarnings(warn bool) Option {
+	return func(s *Service) {
+		s.maintenanceWarnings = warn
+	}
+}
+
+// WithMaxVersions limits each package to its n highest releases that match
+// its specifiers, bounding the work for projects with thousands of versions:
+// once n matching releases are rejected (e.g., for lacking a compatible
+// wheel), older ones are not tried. Non-positive values are ignored.
+func WithMaxVersions(n int) Option {
+	return func(s *Service) {
+		if n > 0 {
+			s.maxVersions = n
+		}
+	}
+}
+
+// WithConstraints caps package versions without requesting them, like pip's
+// constraints files. Each name's specifiers are added to the package's own
+// when it is reached through a requirement; a constrained package that
+// nothing requires is never resolved.
+func WithConstraints(constraints map[string][]string) Option {
+	return func(s *Service) {
+		if len(constraints) == 0 {
+			s.constraints = nil
+
+			return
+		}
+
+		s.constraints = make(map[string][]string, len(constraints))
+		for name, specs := range constraints {
+			n := NormalizeName(name)
+			s.constraints[n] = append(s.constraints[n], specs...)
+		}
+	}
+}
+
+// WithValidation makes Resolve finish with Validate, failing when the result
+// is not self-consistent.
+func WithValidation(validate bool) Option {
+	return func(s *Service) {
+		s.validate = validate
+	}
+}
+
+// WithPreferred seeds the resolver with installed versions keyed by package
+// name. A package whose installed version still satisfies every specifier
+// placed on it keeps that version rather than moving to the newest one, like
+// pip's "only-if-needed" upgrade strategy. Nil disables the preference.
+func WithPreferred(versions map[string]string) Option {
+	return func(s *Service) {
+		if len(versions) == 0 {
+			s.preferred = nil
+
+			return
+		}
+
+		s.preferred = make(map[string]string, len(versions))
+		for name, v := range versions {
+			s.preferred[NormalizeName(name)] = v
+		}
+	}
+}
+
+// WithAutoExtras activates the named extras on every resolved package that
+// declares them, e.g., "recommended" for a batteries-included install. An
+// extra counts as declared when some dependency is gated on it. Nil disables
+// it.
+func WithAutoExtras(names []string) Option {
+	return func(s *Service) {
+		s.autoExtras = nil
+		for _, name := range names {
+			if n := NormalizeName(name); n != "" && !slices.Contains(s.autoExtras, n) {
+				s.autoExtras = append(s.autoExtras, n)
+			}
+		}
+	}
+}
+
 // WithLogger sets the structured logger.
 func WithLogger(l *slog.Logger) Option {
 	return func(s *Service) {
@@ -46,12 +279,29 @@ func WithLogger(l *slog.Logger) Option {
 	}
 }
 
-// Service resolves package dependencies using a simple BFS iterative approach.
+// Service resolves package dependencies with BFS walks of the dependency
+// graph, backtracking over versions when a walk ends in conflict.
 type Service struct {
-	client    pypi.Client
-	noDeps    bool
-	markerEnv MarkerEnv
-	logger    *slog.Logger
+	client     pypi.Client
+	noDeps     bool
+	markerEnv  MarkerEnv
+	wheelCheck WheelCheck
+	allowlist  map[string]bool
+	logger     *slog.Logger
+
+	wheelMetadata WheelMetadata
+
+	constraints map[string][]string
+	preferred   map[string]string
+	autoExtras  []string
+
+	collectConflicts bool
+	collectSkipped   bool
+	allowPrerelease  bool
+	maxVersions      int
+
+	maintenanceWarnings bool
+	validate            bool
 }
 
 // compile-time proof that Service implements Resolver.
@@ -73,28 +323,151 @@ func New(client pypi.Client, opts ...Option) *Service {
 
 // Resolve resolves all dependencies for the given package requirements.
 // It walks the dependency tree using BFS, finds compatible versions,
-// and returns the full list of packages to install.
+// and returns the full list of packages to install. When a walk hits a
+// version conflict, Resolve backtracks: it revisits the latest choice
+// implicated in the conflict, such as selecting an older release of a
+// package, and walks again. The first conflict is returned if no
+// combination of versions works.
 func (s *Service) Resolve(ctx context.Context, requirements []string) ([]ResolvedPackage, error) {
-	var queue []Requirement
+	memo := newMemoClient(s.client)
+
+	svc := *s
+	svc.client = memo
+
+	if s.wheelMetadata != nil {
+		svc.wheelMetadata = memo.wheelMetadata(s.wheelMetadata)
+	}
+
+	plan := newBacktrackPlan()
+
+	result, err := svc.walk(ctx, requirements, plan, false)
+
+	var conflict *conflictError
+	if errors.As(err, &conflict) {
+		result, err = svc.backtrack(ctx, requirements, plan, conflict)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.validate {
+		if err := svc.Validate(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// backtrack searches for a resolution after the first walk ended in
+// conflict. The search walks log nothing; the walk that succeeds is repeated
+// with logging so its notes appear once. With WithCollectConflicts, a failed
+// search is reported by a walk that collects every conflict.
+func (s *Service) backtrack(ctx context.Context, requirements []string, plan *backtrackPlan, conflict *conflictError) ([]ResolvedPackage, error) {
+	quiet := *s
+	quiet.logger = slog.New(slog.DiscardHandler)
+
+	first := conflict.err
+
+	for range maxBacktracks {
+		target, ok := plan.backtrack(conflict)
+		if !ok {
+			break
+		}
+
+		s.logger.Debug("backtracking", slog.String("conflict", conflict.name), slog.String("revisiting", target))
+
+		_, err := quiet.walk(ctx, requirements, plan, false)
+		if err == nil {
+			return s.walk(ctx, requirements, plan, false)
+		}
+
+		if !errors.As(err, &conflict) {
+			return nil, err
+		}
+	}
+
+	if s.collectConflicts {
+		return s.walk(ctx, requirements, newBacktrackPlan(), true)
+	}
+
+	return nil, first
+}
+
+// walk makes one BFS pass over the dependency graph, choosing versions as
+// directed by plan and recording the order of its decisions there. It stops
+// at the first conflict with a *conflictError, unless collect is set.
+func (s *Service) walk(ctx context.Context, requirements []string, plan *backtrackPlan, collect bool) ([]ResolvedPackage, error) {
+	var queue []queueItem
+
+	roots := make(map[string]bool, len(requirements))
+
 	for _, r := range requirements {
-		queue = append(queue, ParseRequirement(r))
+		req := ParseRequirement(r)
+		queue = append(queue, queueItem{req: req})
+		roots[req.Name] = true
 	}
 
 	resolved := make(map[string]*ResolvedPackage)
 	constraints := make(map[string][]string)
 	processing := make(map[string]bool)
+	requiredBy := make(map[string][]Dependent)
+	sources := make(map[string][]Dependent)
+	conflicts := make(map[string]bool)
+	extras := make(map[string][]string)
+	rawDeps := make(map[string][]string)
+
+	var disallowed []string
+
+	plan.order = plan.order[:0]
 
 	for len(queue) > 0 {
-		req := queue[0]
+		item := queue[0]
 		queue = queue[1:]
+		req := item.req
 
 		if req.Specifier != "" {
 			constraints[req.Name] = append(constraints[req.Name], req.Specifier)
+			sources[req.Name] = append(sources[req.Name], Dependent{Name: item.parent, Specifier: req.Specifier})
+		}
+
+		if item.parent != "" {
+			requiredBy[req.Name] = append(requiredBy[req.Name], Dependent{Name: item.parent, Specifier: req.Specifier})
+		}
+
+		if req.URL != "" && item.parent != "" {
+			// Like pip, an index package may not send the install to an
+			// arbitrary URL; only a direct URL package may depend on one.
+			if parent := resolved[item.parent]; parent == nil || parent.URL == "" {
+				return nil, fmt.Errorf("%s is from the index but depends on %s @ %s; only packages installed from a direct URL may depend on one",
+					item.parent, req.Name, req.URL)
+			}
 		}
 
 		if pkg, ok := resolved[req.Name]; ok {
+			if req.URL != "" && req.URL != pkg.URL {
+				return nil, fmt.Errorf("%s is required from %s, but was already resolved from %s",
+					req.Name, req.URL, packageSource(pkg))
+			}
+
 			if err := s.verifyConstraints(pkg, constraints[req.Name]); err != nil {
-				return nil, err
+				if !collect {
+					return nil, &conflictError{name: req.Name, version: pkg.Version, sources: unmet(pkg.Version, sources[req.Name]), err: err}
+				}
+
+				conflicts[req.Name] = true
+			}
+
+			if added := newExtras(extras[req.Name], req.Extras); len(added) > 0 {
+				all := slices.Concat(extras[req.Name], added)
+				pkg.Dependencies = filterDepNames(rawDeps[req.Name], s.envWithExtras(all))
+
+				for _, dep := range s.extraDeps(rawDeps[req.Name], extras[req.Name], all) {
+					queue = append(queue, queueItem{req: dep, parent: req.Name})
+				}
+
+				extras[req.Name] = all
 			}
 
 			continue
@@ -106,23 +479,229 @@ func (s *Service) Resolve(ctx context.Context, requirements []string) ([]Resolve
 
 		processing[req.Name] = true
 
-		pkg, deps, err := s.resolvePackage(ctx, req.Name, constraints[req.Name])
+		if s.allowlist != nil && !s.allowlist[req.Name] {
+			disallowed = append(disallowed, disallowedNote(req.Name, item.parent))
+
+			continue
+		}
+
+		plan.order = append(plan.order, req.Name)
+		choice := plan.decisions[req.Name]
+
+		specs := constraints[req.Name]
+		var skip int
+
+		if choice != nil {
+			skip = choice.skip
+			for _, d := range choice.learned {
+				specs = append(slices.Clip(specs), d.Specifier)
+			}
+		}
+
+		var pkg *ResolvedPackage
+		var deps []string
+		var err error
+
+		if req.URL != "" {
+			pkg, deps, err = s.resolveURL(ctx, req, specs, skip)
+		} else {
+			pkg, deps, err = s.resolvePackage(ctx, req.Name, specs, req.Extras, skip)
+		}
+
 		if err != nil {
-			return nil, err
+			if !errors.Is(err, errNoCompatibleVersion) {
+				return nil, err
+			}
+
+			if !collect {
+				involved := slices.Clone(sources[req.Name])
+				if choice != nil {
+					involved = append(involved, choice.learned...)
+				}
+
+				return nil, &conflictError{name: req.Name, sources: involved, err: err}
+			}
+
+			conflicts[req.Name] = true
+
+			continue
+		}
+
+		active := req.Extras
+		if auto := s.declaredAutoExtras(req.Name, deps, active); len(auto) > 0 {
+			active = slices.Concat(active, auto)
+			pkg.Dependencies = filterDepNames(deps, s.envWithExtras(active))
 		}
 
 		resolved[req.Name] = pkg
-		queue = append(queue, s.filterDeps(deps)...)
+		extras[req.Name] = active
+		rawDeps[req.Name] = deps
+
+		for _, dep := range s.filterDeps(deps, active) {
+			queue = append(queue, queueItem{req: dep, parent: req.Name})
+		}
+	}
+
+	if len(disallowed) > 0 {
+		return nil, fmt.Errorf("packages not in the allowlist:\n  %s", strings.Join(disallowed, "\n  "))
+	}
+
+	if len(conflicts) > 0 {
+		return nil, unresolvable(conflicts, resolved, sources)
 	}
 
 	result := make([]ResolvedPackage, 0, len(resolved))
-	for _, pkg := range resolved {
+	for name, pkg := range resolved {
+		pkg.RequiredBy = requiredBy[name]
+		pkg.Root = roots[name]
+		pkg.Extras = extras[name]
+		if s.collectSkipped && !s.noDeps {
+			pkg.Skipped = skippedDeps(rawDeps[name], s.envWithExtras(extras[name]))
+		}
+
 		result = append(result, *pkg)
+
+		if len(pkg.RequiredBy) > 1 {
+			s.logger.Debug("shared dependency",
+				slog.String("package", name),
+				slog.String("version", pkg.Version),
+				slog.String("required_by", requirers(pkg.RequiredBy)))
+		}
 	}
 
 	return result, nil
 }
 
+// unmet returns the specifiers in sources that version does not satisfy.
+func unmet(version string, sources []Dependent) []Dependent {
+	var failing []Dependent
+
+	for _, d := range sources {
+		if ok, err := MatchesAll(version, []string{d.Specifier}); err != nil || !ok {
+			failing = append(failing, d)
+		}
+	}
+
+	return failing
+}
+
+// Validate audits a resolution for self-consistency: the Requires-Dist of
+// every package, with markers evaluated for its extras, must be met by the
+// selected versions. It re-reads metadata at each chosen version, so it
+// catches resolver bugs and metadata quirks alike, and reports every
+// unsatisfied edge at once. With WithNoDeps, missing dependencies are
+// expected and only the versions present are checked.
+func (s *Service) Validate(ctx context.Context, resolved []ResolvedPackage) error {
+	versions := make(map[string]string, len(resolved))
+	for _, pkg := range resolved {
+		versions[pkg.Name] = pkg.Version
+	}
+
+	var problems []string
+
+	for _, pkg := range resolved {
+		requiresDist, err := s.validationDeps(ctx, pkg)
+		if err != nil {
+			return fmt.Errorf("validating %s %s: %w", pkg.Name, pkg.Version, err)
+		}
+
+		env := s.envWithExtras(pkg.Extras)
+
+		for _, dep := range requiresDist {
+			req := ParseRequirement(dep)
+			if req.Marker != "" && !EvalMarker(req.Marker, env) {
+				continue
+			}
+
+			got, ok := versions[req.Name]
+			if !ok {
+				if !s.noDeps {
+					problems = append(problems, fmt.Sprintf("%s %s requires %s, which is not in the resolution", pkg.Name, pkg.Version, req.Name))
+				}
+
+				continue
+			}
+
+			if req.Specifier == "" {
+				continue
+			}
+
+			if match, err := MatchesAll(got, []string{req.Specifier}); err != nil || !match {
+				problems = append(problems, fmt.Sprintf("%s %s requires %s%s, resolved %s", pkg.Name, pkg.Version, req.Name, req.Specifier, got))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+
+		return fmt.Errorf("inconsistent resolution:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// validationDeps re-reads the requires_dist of a resolved package, from its
+// wheel for a direct URL requirement.
+func (s *Service) validationDeps(ctx context.Context, pkg ResolvedPackage) ([]string, error) {
+	if pkg.URL != "" {
+		_, deps, err := s.urlMetadata(ctx, pkg.Name, pkg.URL)
+
+		return deps, err
+	}
+
+	info, err := s.client.GetPackageVersion(ctx, pkg.Name, pkg.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return info.Info.RequiresDist, nil
+}
+
+// unresolvable builds an *UnresolvableError from the conflicting package names,
+// sorted by name for stable output.
+func unresolvable(names map[string]bool, resolved map[string]*ResolvedPackage, sources map[string][]Dependent) error {
+	e := &UnresolvableError{Conflicts: make([]Conflict, 0, len(names))}
+
+	for name := range names {
+		c := Conflict{Name: name, Constraints: sources[name]}
+		if pkg, ok := resolved[name]; ok {
+			c.Version = pkg.Version
+		}
+
+		e.Conflicts = append(e.Conflicts, c)
+	}
+
+	sort.Slice(e.Conflicts, func(i, j int) bool { return e.Conflicts[i].Name < e.Conflicts[j].Name })
+
+	return e
+}
+
+// disallowedNote describes a package rejected by the allowlist and who required it.
+func disallowedNote(name, parent string) string {
+	if parent == "" {
+		return name + " (requested directly)"
+	}
+
+	return name + " (required by " + parent + ")"
+}
+
+// requirers lists the packages in deps with their specifiers, sorted, e.g.,
+// "pandas(>=1.23), scipy(>=1.21)".
+func requirers(deps []Dependent) string {
+	names := make([]string, len(deps))
+	for i, d := range deps {
+		names[i] = d.Name
+		if d.Specifier != "" {
+			names[i] += "(" + d.Specifier + ")"
+		}
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
 // verifyConstraints checks that a resolved package still satisfies all accumulated constraints.
 func (s *Service) verifyConstraints(pkg *ResolvedPackage, specs []string) error {
 	ok, err := MatchesAll(pkg.Version, specs)
@@ -139,26 +718,59 @@ func (s *Service) verifyConstraints(pkg *ResolvedPackage, specs []string) error
 }
 
 // resolvePackage fetches a package from PyPI, selects the best version, and returns
-// the resolved package along with its raw dependency list.
-func (s *Service) resolvePackage(ctx context.Context, name string, specs []string) (*ResolvedPackage, []string, error) {
+// the resolved package along with its raw dependency list. The first skip
+// acceptable versions, best first, are passed over when backtracking.
+func (s *Service) resolvePackage(ctx context.Context, name string, specs, extras []string, skip int) (*ResolvedPackage, []string, error) {
 	s.logger.Debug("resolving package", slog.String("name", name))
 
+	if extra := s.constraints[name]; len(extra) > 0 {
+		specs = slices.Concat(specs, extra)
+	}
+
 	info, err := s.client.GetPackage(ctx, name)
 	if err != nil {
 		return nil, nil, fmt.Errorf("fetching %s from PyPI: %w", name, err)
 	}
 
-	best, err := FindBestVersion(availableVersions(info), specs)
-	if err != nil {
-		return nil, nil, fmt.Errorf("finding best version for %s: %w", name, err)
+	filter := &versionFilter{s: s, info: info}
+
+	best := s.preferredVersion(info, name, specs)
+	if best != "" && skip > 0 {
+		filter.passed, best = map[string]bool{best: true}, ""
+		skip--
 	}
 
 	if best == "" {
-		return nil, nil, fmt.Errorf("no compatible version found for %s matching %v", name, specs)
+		best, err = s.findVersion(info, specs, filter, skip)
+		if err != nil {
+			return nil, nil, fmt.Errorf("finding best version for %s: %w", name, err)
+		}
+	}
+
+	if best == "" {
+		if filter.pythonSkipped != "" {
+			return nil, nil, s.pythonMismatch(info, name, specs, filter)
+		}
+
+		return nil, nil, fmt.Errorf("%w for %s matching %v", errNoCompatibleVersion, name, specs)
+	}
+
+	if filter.pythonSkipped != "" {
+		s.logger.Info("newer release requires a newer Python, selecting an older one",
+			slog.String("package", name),
+			slog.String("skipped", filter.pythonSkipped),
+			slog.String("requires-python", filter.pythonSkippedSpec),
+			slog.String("selected", best),
+			slog.String("python", s.markerEnv.PythonVersion),
+		)
 	}
 
 	s.logger.Debug("resolved version", slog.String("name", name), slog.String("version", best))
 
+	if s.maintenanceWarnings {
+		s.warnMaintenance(name, best, info.Info)
+	}
+
 	deps, err := s.fetchDeps(ctx, info, name, best)
 	if err != nil {
 		return nil, nil, err
@@ -167,12 +779,264 @@ func (s *Service) resolvePackage(ctx context.Context, name string, specs []strin
 	pkg := &ResolvedPackage{
 		Name:         name,
 		Version:      best,
-		Dependencies: filterDepNames(deps, s.markerEnv),
+		Dependencies: filterDepNames(deps, s.envWithExtras(extras)),
+	}
+
+	pkg.Yanked, pkg.YankedReason = releaseYanked(info, best)
+	if !pkg.Yanked {
+		pkg.YankedSkipped = filter.yankedSkipped
 	}
 
 	return pkg, deps, nil
 }
 
+// resolveURL resolves a "name @ url" requirement to the wheel at the URL,
+// without consulting the index. The version comes from the wheel filename and
+// must satisfy every specifier placed on the package; there is no other
+// version to backtrack to.
+func (s *Service) resolveURL(ctx context.Context, req Requirement, specs []string, skip int) (*ResolvedPackage, []string, error) {
+	s.logger.Debug("resolving package from URL", slog.String("name", req.Name), slog.String("url", req.URL))
+
+	if extra := s.constraints[req.Name]; len(extra) > 0 {
+		specs = slices.Concat(specs, extra)
+	}
+
+	version, deps, err := s.urlMetadata(ctx, req.Name, req.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ok, err := MatchesAll(version, specs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checking constraints for %s: %w", req.Name, err)
+	}
+
+	if !ok || skip > 0 {
+		return nil, nil, fmt.Errorf("%w for %s matching %v: %s provides %s", errNoCompatibleVersion, req.Name, specs, req.URL, version)
+	}
+
+	pkg := &ResolvedPackage{
+		Name:         req.Name,
+		Version:      version,
+		Dependencies: filterDepNames(deps, s.envWithExtras(req.Extras)),
+		URL:          req.URL,
+	}
+
+	return pkg, deps, nil
+}
+
+// urlMetadata returns the version of the wheel at a direct URL and, unless
+// WithNoDeps is set, its requires_dist. Only https URLs are accepted.
+func (s *Service) urlMetadata(ctx context.Context, name, rawURL string) (string, []string, error) {
+	file, version, err := pypi.WheelFromURL(rawURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving %s: %w", name, err)
+	}
+
+	if !strings.HasPrefix(file.URL, "https://") {
+		return "", nil, fmt.Errorf("resolving %s: direct URL %s must use https", name, rawURL)
+	}
+
+	if s.noDeps {
+		return version, nil, nil
+	}
+
+	if s.wheelMetadata == nil {
+		return "", nil, fmt.Errorf("resolving %s: cannot read the dependencies of %s", name, file.Filename)
+	}
+
+	info, err := s.wheelMetadata(ctx, file)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading metadata of %s: %w", file.Filename, err)
+	}
+
+	return version, info.RequiresDist, nil
+}
+
+// packageSource names where a resolved package comes from, for errors.
+func packageSource(pkg *ResolvedPackage) string {
+	if pkg.URL != "" {
+		return pkg.URL
+	}
+
+	return "the index (" + pkg.Version + ")"
+}
+
+// findVersion returns the best version filter accepts after passing over the
+// first skip. Like pip, it considers yanked releases only when no other
+// release matches.
+func (s *Service) findVersion(info *pypi.PackageInfo, specs []string, filter *versionFilter, skip int) (string, error) {
+	sel := selection{allowPre: s.allowPrerelease, limit: s.maxVersions}
+
+	best, err := findBestVersion(availableVersions(info), specs, sel, filter.skipping(skip))
+	if err != nil || best != "" || filter.yankedSkipped == "" {
+		return best, err
+	}
+
+	filter.allowYanked = true
+
+	best, err = findBestVersion(availableVersions(info), specs, sel, filter.skipping(skip))
+	if best != "" {
+		s.logger.Warn("selecting yanked release, no other release matches",
+			slog.String("package", info.Info.Name),
+			slog.String("version", best),
+			slog.String("specifiers", strings.Join(specs, ",")),
+		)
+	}
+
+	return best, err
+}
+
+// preferredVersion returns the preferred (installed) version of name when the
+// index still offers it and it satisfies specs and the version filters, or
+// "" otherwise.
+func (s *Service) preferredVersion(info *pypi.PackageInfo, name string, specs []string) string {
+	v, ok := s.preferred[name]
+	if !ok || !slices.Contains(availableVersions(info), v) {
+		return ""
+	}
+
+	// A fresh filter keeps a rejected preference out of the Requires-Python
+	// note. An installed release stays preferred after it is yanked.
+	filter := &versionFilter{s: s, info: info, allowYanked: true}
+
+	kept, err := findBestVersion([]string{v}, specs, selection{allowPre: true}, filter.accept)
+	if err != nil || kept == "" {
+		return ""
+	}
+
+	s.logger.Debug("keeping installed version", slog.String("name", name), slog.String("version", kept))
+
+	return kept
+}
+
+// pythonMismatch explains a resolution failure caused by Requires-Python:
+// the newest matching version that was excluded, and the newest release that
+// does support the target interpreter, if any.
+func (s *Service) pythonMismatch(info *pypi.PackageInfo, name string, specs []string, filter *versionFilter) error {
+	err := fmt.Errorf("%w for %s matching %v: %s %s requires Python %s, target is %s",
+		errNoCompatibleVersion, name, specs, name, filter.pythonSkipped, filter.pythonSkippedSpec, s.markerEnv.PythonVersion)
+
+	nearest, _ := FindBestVersionFunc(availableVersions(info), nil, (&versionFilter{s: s, info: info, allowYanked: true}).accept)
+	if nearest == "" {
+		return fmt.Errorf("%w; no release supports Python %s", err, s.markerEnv.PythonVersion)
+	}
+
+	return fmt.Errorf("%w; the newest release supporting Python %s is %s", err, s.markerEnv.PythonVersion, nearest)
+}
+
+// warnMaintenance logs maintenance risks recorded in the project metadata:
+// an inactive project, and a yanked latest release when it is the selected
+// version.
+func (s *Service) warnMaintenance(name, version string, info pypi.Info) {
+	if info.Inactive() {
+		s.logger.Warn("project is classified as inactive and may no longer be maintained", slog.String("package", name))
+	}
+
+	if info.Yanked && info.Version == version {
+		s.logger.Warn("latest release is yanked",
+			slog.String("package", name),
+			slog.String("version", info.Version),
+			slog.String("reason", info.YankedReason),
+		)
+	}
+}
+
+// versionFilter rejects candidate versions that cannot be installed in the
+// target environment: versions whose Requires-Python excludes the interpreter,
+// and (when a WheelCheck is configured) versions without a compatible wheel.
+type versionFilter struct {
+	s    *Service
+	info *pypi.PackageInfo
+
+	// First version skipped because of Requires-Python, for the selection note.
+	pythonSkipped     string
+	pythonSkippedSpec string
+
+	// Versions already tried by backtracking, rejected without further checks.
+	passed map[string]bool
+
+	// Yanked releases are rejected unless allowYanked; the first one rejected
+	// is kept for the selection note.
+	allowYanked   bool
+	yankedSkipped string
+}
+
+// skipping returns an accept func that also passes over the first n versions
+// accept would take, for backtracking to the next candidate.
+func (f *versionFilter) skipping(n int) func(string) bool {
+	return func(version string) bool {
+		if !f.accept(version) {
+			return false
+		}
+
+		if n > 0 {
+			n--
+
+			return false
+		}
+
+		return true
+	}
+}
+
+func (f *versionFilter) accept(version string) bool {
+	if f.passed[version] {
+		return false
+	}
+
+	if spec := requiresPython(f.info, version); !f.s.pythonSatisfies(spec) {
+		if f.pythonSkipped == "" {
+			f.pythonSkipped, f.pythonSkippedSpec = version, spec
+		}
+
+		f.s.logger.Debug("skipping version incompatible with Python",
+			slog.String("name", f.info.Info.Name),
+			slog.String("version", version),
+			slog.String("requires_python", spec),
+		)
+
+		return false
+	}
+
+	if f.s.wheelCheck != nil && !f.s.wheelCheck(releaseFiles(f.info, version)) {
+		f.s.logger.Debug("skipping version without a compatible wheel",
+			slog.String("name", f.info.Info.Name),
+			slog.String("version", version),
+		)
+
+		return false
+	}
+
+	if yanked, _ := releaseYanked(f.info, version); yanked && !f.allowYanked {
+		if f.yankedSkipped == "" {
+			f.yankedSkipped = version
+		}
+
+		f.s.logger.Debug("skipping yanked version",
+			slog.String("name", f.info.Info.Name),
+			slog.String("version", version),
+		)
+
+		return false
+	}
+
+	return true
+}
+
+// pythonSatisfies reports whether the target interpreter satisfies a
+// Requires-Python specifier. Unknown interpreters and unparsable specifiers
+// are treated as satisfied.
+func (s *Service) pythonSatisfies(spec string) bool {
+	if spec == "" || s.markerEnv.PythonVersion == "" {
+		return true
+	}
+
+	ok, err := MatchesAll(s.markerEnv.fullVersion(), []string{spec})
+
+	return err != nil || ok
+}
+
 // fetchDeps returns requires_dist for a specific version.
 func (s *Service) fetchDeps(ctx context.Context, info *pypi.PackageInfo, name, version string) ([]string, error) {
 	if version == info.Info.Version {
@@ -187,17 +1051,38 @@ func (s *Service) fetchDeps(ctx context.Context, info *pypi.PackageInfo, name, v
 	return versionInfo.Info.RequiresDist, nil
 }
 
-// filterDeps filters dependency strings by marker environment and returns parsed requirements.
-func (s *Service) filterDeps(deps []string) []Requirement {
+// filterDeps filters dependency strings by marker environment and the
+// requested extras, and returns parsed requirements.
+func (s *Service) filterDeps(deps, extras []string) []Requirement {
 	if s.noDeps {
 		return nil
 	}
 
+	env := s.envWithExtras(extras)
+
 	var reqs []Requirement
 
 	for _, dep := range deps {
 		req := ParseRequirement(dep)
-		if req.Marker != "" && !EvalMarker(req.Marker, s.markerEnv) {
+		if req.Marker != "" && !EvalMarker(req.Marker, env) {
+			continue
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	return reqs
+}
+
+// extraDeps returns the dependencies enabled by the extras in want that were
+// not already enabled by the extras in had.
+func (s *Service) extraDeps(deps, had, want []string) []Requirement {
+	hadEnv := s.envWithExtras(had)
+
+	var reqs []Requirement
+
+	for _, req := range s.filterDeps(deps, want) {
+		if req.Marker == "" || EvalMarker(req.Marker, hadEnv) {
 			continue
 		}
 
@@ -207,6 +1092,42 @@ func (s *Service) filterDeps(deps []string) []Requirement {
 	return reqs
 }
 
+// envWithExtras returns the marker environment with the given extras requested.
+func (s *Service) envWithExtras(extras []string) MarkerEnv {
+	env := s.markerEnv
+	env.Extras = extras
+
+	return env
+}
+
+// declaredAutoExtras returns the WithAutoExtras names, not already in
+// extras, that gate at least one of the deps of package name.
+func (s *Service) declaredAutoExtras(name string, deps, extras []string) []string {
+	var auto []string
+
+	for _, extra := range newExtras(extras, s.autoExtras) {
+		if len(s.extraDeps(deps, extras, slices.Concat(extras, []string{extra}))) > 0 {
+			s.logger.Debug("activating extra", slog.String("package", name), slog.String("extra", extra))
+			auto = append(auto, extra)
+		}
+	}
+
+	return auto
+}
+
+// newExtras returns the extras in requested that are not in have.
+func newExtras(have, requested []string) []string {
+	var added []string
+
+	for _, e := range requested {
+		if !slices.Contains(have, e) && !slices.Contains(added, e) {
+			added = append(added, e)
+		}
+	}
+
+	return added
+}
+
 // availableVersions extracts version strings from a PackageInfo's releases.
 // Falls back to info.Version if no releases are present.
 func availableVersions(info *pypi.PackageInfo) []string {
@@ -230,6 +1151,59 @@ func availableVersions(info *pypi.PackageInfo) []string {
 	return nil
 }
 
+// releaseFiles returns the distribution files published for a version.
+// Falls back to info.URLs for the latest version when no releases are present.
+func releaseFiles(info *pypi.PackageInfo, version string) []pypi.URL {
+	if files, ok := info.Releases[version]; ok {
+		return files
+	}
+
+	if version == info.Info.Version {
+		return info.URLs
+	}
+
+	return nil
+}
+
+// releaseYanked reports whether every file of a release is yanked (PEP 592),
+// with the first reason given.
+func releaseYanked(info *pypi.PackageInfo, version string) (bool, string) {
+	files := releaseFiles(info, version)
+	if len(files) == 0 {
+		return false, ""
+	}
+
+	var reason string
+
+	for _, f := range files {
+		if !f.Yanked {
+			return false, ""
+		}
+
+		if reason == "" {
+			reason = f.YankedReason
+		}
+	}
+
+	return true, reason
+}
+
+// requiresPython returns the Requires-Python specifier declared for a version,
+// taken from its release files or, for the latest version, from the project info.
+func requiresPython(info *pypi.PackageInfo, version string) string {
+	for _, f := range releaseFiles(info, version) {
+		if f.RequiresPython != "" {
+			return f.RequiresPython
+		}
+	}
+
+	if version == info.Info.Version {
+		return info.Info.RequiresPython
+	}
+
+	return ""
+}
+
 // filterDepNames extracts normalized dependency names from requires_dist,
 // filtering by marker environment.
 func filterDepNames(requiresDist []string, env MarkerEnv) []string {
@@ -246,3 +1220,29 @@ func filterDepNames(requiresDist []string, env MarkerEnv) []string {
 
 	return names
 }
+
+// skippedDeps returns the dependencies in requiresDist whose markers do not
+// match env, the complement of filterDepNames.
+func skippedDeps(requiresDist []string, env MarkerEnv) []Skipped {
+	var skipped []Skipped
+
+	for _, dep := range requiresDist {
+		req := ParseRequirement(dep)
+		if req.Marker == "" || EvalMarker(req.Marker, env) {
+			continue
+		}
+
+		skipped = append(skipped, Skipped{Name: req.Name, Marker: req.Marker, Reason: skipReason(req.Marker, env)})
+	}
+
+	return skipped
+}
+
+// skipReason explains why a marker evaluated false in env.
+func skipReason(marker string, env MarkerEnv) string {
+	if strings.Contains(marker, "extra") {
+		return "extra not requested"
+	}
+
+	return fmt.Sprintf("marker not satisfied (python %s, %s)", env.PythonVersion, env.SysPlatform)
+}
@@ -1,8 +1,14 @@
 package resolver_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/bilusteknoloji/pipg/internal/pypi"
@@ -147,6 +153,12 @@ func TestResolveWithDependencies(t *testing.T) {
 	if resolved["jinja2"] != "3.1.3" {
 		t.Errorf("jinja2: expected %q, got %q", "3.1.3", resolved["jinja2"])
 	}
+
+	for _, pkg := range result {
+		if pkg.Root != (pkg.Name == "flask") {
+			t.Errorf("%s: Root = %v", pkg.Name, pkg.Root)
+		}
+	}
 }
 
 func TestResolveNoDeps(t *testing.T) {
@@ -223,6 +235,134 @@ func TestResolveSkipsMarkerMismatch(t *testing.T) {
 	}
 }
 
+func TestResolveCollectSkipped(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"flask": {
+				Info: pypi.Info{
+					Name:    "flask",
+					Version: "3.0.0",
+					RequiresDist: []string{
+						"werkzeug>=3.0.0",
+						`importlib-metadata>=3.6.0; python_version < "3.10"`,
+						`asgiref>=3.2; extra == "async"`,
+					},
+				},
+				Releases: releases("3.0.0"),
+			},
+			"werkzeug": {
+				Info:     pypi.Info{Name: "werkzeug", Version: "3.0.1"},
+				Releases: releases("3.0.1"),
+			},
+		},
+	}
+
+	env := resolver.MarkerEnv{PythonVersion: "3.12", SysPlatform: "linux", OsName: "posix"}
+	svc := resolver.New(client, resolver.WithMarkerEnv(env), resolver.WithCollectSkipped(true))
+
+	result, err := svc.Resolve(context.Background(), []string{"flask"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	var flask resolver.ResolvedPackage
+	for _, pkg := range result {
+		if pkg.Name == "flask" {
+			flask = pkg
+		}
+	}
+
+	want := []resolver.Skipped{
+		{Name: "importlib-metadata", Marker: `python_version < "3.10"`, Reason: "marker not satisfied (python 3.12, linux)"},
+		{Name: "asgiref", Marker: `extra == "async"`, Reason: "extra not requested"},
+	}
+
+	if !slices.Equal(flask.Skipped, want) {
+		t.Errorf("flask.Skipped = %+v, want %+v", flask.Skipped, want)
+	}
+}
+
+func TestResolvePrerelease(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"mypkg": {
+				Info:     pypi.Info{Name: "mypkg", Version: "1.9.0"},
+				Releases: releases("1.9.0", "2.0.0rc1"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		req  string
+		pre  bool
+		want string
+	}{
+		{"pinned rc without --pre", "mypkg==2.0.0rc1", false, "2.0.0rc1"},
+		{"pinned rc with --pre", "mypkg==2.0.0rc1", true, "2.0.0rc1"},
+		{"loose without --pre", "mypkg>=1.0", false, "1.9.0"},
+		{"loose with --pre", "mypkg>=1.0", true, "2.0.0rc1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := resolver.New(client, resolver.WithAllowPrerelease(tt.pre))
+
+			result, err := svc.Resolve(context.Background(), []string{tt.req})
+			if err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+
+			if len(result) != 1 || result[0].Version != tt.want {
+				t.Errorf("Resolve(%q) = %+v, want version %s", tt.req, result, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMaxVersions(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info:     pypi.Info{Name: "pkg", Version: "3.0"},
+				Releases: releases("1.0", "2.0", "3.0"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithMaxVersions(2))
+
+	result, err := svc.Resolve(context.Background(), []string{"pkg<3.0"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if result[0].Version != "2.0" {
+		t.Errorf("expected 2.0, got %s", result[0].Version)
+	}
+
+	// The window counts only releases matching the specifiers.
+	result, err = svc.Resolve(context.Background(), []string{"pkg<2.0"})
+	if err != nil {
+		t.Fatalf("Resolve(pkg<2.0) error: %v", err)
+	}
+
+	if result[0].Version != "1.0" {
+		t.Errorf("expected 1.0, got %s", result[0].Version)
+	}
+
+	client.packages["pkg"].Releases = map[string][]pypi.URL{
+		"1.0": {{Filename: "pkg-1.0-py3-none-any.whl", RequiresPython: ">=3.8"}},
+		"2.0": {{Filename: "pkg-2.0-py3-none-any.whl", RequiresPython: ">=3.12"}},
+		"3.0": {{Filename: "pkg-3.0-py3-none-any.whl", RequiresPython: ">=3.12"}},
+	}
+
+	svc = resolver.New(client, resolver.WithMaxVersions(2), resolver.WithMarkerEnv(resolver.MarkerEnv{PythonVersion: "3.11"}))
+	if _, err := svc.Resolve(context.Background(), []string{"pkg"}); err == nil {
+		t.Error("expected no compatible version once the 2 newest matching releases are rejected, got nil")
+	}
+}
+
 func TestResolveVersionConflict(t *testing.T) {
 	client := &mockClient{
 		packages: map[string]*pypi.PackageInfo{
@@ -256,6 +396,78 @@ func TestResolveVersionConflict(t *testing.T) {
 	}
 }
 
+func TestResolveBacktracksToOlderVersion(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"x": {
+				Info:     pypi.Info{Name: "x", Version: "1.0", RequiresDist: []string{"a>=1.0"}},
+				Releases: releases("1.0"),
+			},
+			"y": {
+				Info:     pypi.Info{Name: "y", Version: "1.0", RequiresDist: []string{"a<2.0"}},
+				Releases: releases("1.0"),
+			},
+			"a": {
+				Info:     pypi.Info{Name: "a", Version: "2.1"},
+				Releases: releases("0.9", "1.0", "1.9", "2.0", "2.1"),
+			},
+		},
+	}
+
+	result, err := resolver.New(client).Resolve(context.Background(), []string{"x", "y"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	got := make(map[string]string, len(result))
+	for _, pkg := range result {
+		got[pkg.Name] = pkg.Version
+	}
+
+	if got["a"] != "1.9" {
+		t.Errorf("a resolved to %q, want 1.9 (the newest satisfying >=1.0 and <2.0)", got["a"])
+	}
+}
+
+func TestResolveBacktracksToOlderParent(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"app": {
+				Info:     pypi.Info{Name: "app", Version: "2.0", RequiresDist: []string{"shared>=2.0"}},
+				Releases: releases("1.0", "2.0"),
+			},
+			"app@1.0": {
+				Info: pypi.Info{Name: "app", Version: "1.0", RequiresDist: []string{"shared>=1.0"}},
+			},
+			"lib": {
+				Info:     pypi.Info{Name: "lib", Version: "1.0", RequiresDist: []string{"shared<2.0"}},
+				Releases: releases("1.0"),
+			},
+			"shared": {
+				Info:     pypi.Info{Name: "shared", Version: "2.1"},
+				Releases: releases("1.5", "2.1"),
+			},
+		},
+	}
+
+	result, err := resolver.New(client).Resolve(context.Background(), []string{"app", "lib"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	got := make(map[string]string, len(result))
+	for _, pkg := range result {
+		got[pkg.Name] = pkg.Version
+	}
+
+	want := map[string]string{"app": "1.0", "lib": "1.0", "shared": "1.5"}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s resolved to %q, want %q", name, got[name], v)
+		}
+	}
+}
+
 func TestResolvePackageNotFound(t *testing.T) {
 	client := &mockClient{packages: map[string]*pypi.PackageInfo{}}
 
@@ -340,3 +552,835 @@ func TestResolveMultipleRoots(t *testing.T) {
 		t.Fatalf("expected 2 packages, got %d", len(result))
 	}
 }
+
+func TestResolveSharedDependencyLog(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pandas": {
+				Info:     pypi.Info{Name: "pandas", Version: "2.1.0", RequiresDist: []string{"numpy>=1.23"}},
+				Releases: releases("2.1.0"),
+			},
+			"scipy": {
+				Info:     pypi.Info{Name: "scipy", Version: "1.11.0", RequiresDist: []string{"numpy>=1.21"}},
+				Releases: releases("1.11.0"),
+			},
+			"numpy": {
+				Info:     pypi.Info{Name: "numpy", Version: "1.26.0"},
+				Releases: releases("1.25.0", "1.26.0"),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	svc := resolver.New(client, resolver.WithLogger(logger))
+
+	result, err := svc.Resolve(context.Background(), []string{"pandas", "scipy"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	var numpy resolver.ResolvedPackage
+	for _, pkg := range result {
+		if pkg.Name == "numpy" {
+			numpy = pkg
+		}
+	}
+
+	if len(numpy.RequiredBy) != 2 {
+		t.Fatalf("numpy.RequiredBy = %v, want 2 parents", numpy.RequiredBy)
+	}
+
+	want := `msg="shared dependency" package=numpy version=1.26.0 required_by="pandas(>=1.23), scipy(>=1.21)"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected debug log to contain %q, got:\n%s", want, buf.String())
+	}
+}
+
+func TestResolveSkipsVersionsWithoutWheel(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info: pypi.Info{Name: "pkg", Version: "2.0.0"},
+				Releases: map[string][]pypi.URL{
+					"1.9.0": {{Filename: "pkg-1.9.0-py3-none-any.whl", PackageType: "bdist_wheel"}},
+					"2.0.0": {{Filename: "pkg-2.0.0.tar.gz", PackageType: "sdist"}},
+				},
+			},
+		},
+	}
+
+	hasWheel := func(files []pypi.URL) bool {
+		for _, f := range files {
+			if f.PackageType == "bdist_wheel" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	svc := resolver.New(client, resolver.WithWheelCheck(hasWheel))
+
+	result, err := svc.Resolve(context.Background(), []string{"pkg"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if len(result) != 1 || result[0].Version != "1.9.0" {
+		t.Fatalf("expected fallback to pkg 1.9.0, got %+v", result)
+	}
+
+	// Without the check the sdist-only 2.0.0 is selected.
+	result, err = resolver.New(client).Resolve(context.Background(), []string{"pkg"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if result[0].Version != "2.0.0" {
+		t.Errorf("expected 2.0.0 without wheel check, got %s", result[0].Version)
+	}
+}
+
+func TestResolveSkipsYankedVersions(t *testing.T) {
+	yanked := func(v, reason string) []pypi.URL {
+		return []pypi.URL{{Filename: "pkg-" + v + "-py3-none-any.whl", Yanked: true, YankedReason: reason}}
+	}
+
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info: pypi.Info{Name: "pkg", Version: "2.0.0"},
+				Releases: map[string][]pypi.URL{
+					"1.0.0": {{Filename: "pkg-1.0.0-py3-none-any.whl"}},
+					"1.5.0": yanked("1.5.0", "broken build"),
+					"2.0.0": yanked("2.0.0", "security"),
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		req         string
+		wantVersion string
+		wantReason  string
+		wantSkipped string
+	}{
+		{req: "pkg", wantVersion: "1.0.0", wantSkipped: "2.0.0"},
+		{req: "pkg>1.0", wantVersion: "2.0.0", wantReason: "security"},
+		{req: "pkg==1.5.0", wantVersion: "1.5.0", wantReason: "broken build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.req, func(t *testing.T) {
+			result, err := resolver.New(client).Resolve(context.Background(), []string{tt.req})
+			if err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+
+			pkg := result[0]
+			if pkg.Version != tt.wantVersion || pkg.Yanked != (tt.wantReason != "") ||
+				pkg.YankedReason != tt.wantReason || pkg.YankedSkipped != tt.wantSkipped {
+				t.Errorf("got %s yanked=%v reason=%q skipped=%q, want %s reason=%q skipped=%q",
+					pkg.Version, pkg.Yanked, pkg.YankedReason, pkg.YankedSkipped, tt.wantVersion, tt.wantReason, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestResolveNoVersionWithWheel(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info:     pypi.Info{Name: "pkg", Version: "1.0.0"},
+				Releases: releases("1.0.0"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithWheelCheck(func([]pypi.URL) bool { return false }))
+
+	if _, err := svc.Resolve(context.Background(), []string{"pkg"}); err == nil {
+		t.Fatal("expected error when no version has a compatible wheel, got nil")
+	}
+}
+
+func TestResolveRequiresPythonSkipNote(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info: pypi.Info{Name: "pkg", Version: "2.0", RequiresPython: ">=3.12"},
+				Releases: map[string][]pypi.URL{
+					"1.9": {{Filename: "pkg-1.9-py3-none-any.whl", RequiresPython: ">=3.8"}},
+					"2.0": {{Filename: "pkg-2.0-py3-none-any.whl", RequiresPython: ">=3.12"}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	env := resolver.MarkerEnv{PythonVersion: "3.11", SysPlatform: "linux", OsName: "posix"}
+	svc := resolver.New(client, resolver.WithMarkerEnv(env), resolver.WithLogger(logger))
+
+	result, err := svc.Resolve(context.Background(), []string{"pkg"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if result[0].Version != "1.9" {
+		t.Fatalf("expected fallback to 1.9, got %s", result[0].Version)
+	}
+
+	for _, want := range []string{"package=pkg", "skipped=2.0", `requires-python=">=3.12"`, "selected=1.9", "python=3.11"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected log to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestResolveMaintenanceWarnings(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"oldpkg": {
+				Info: pypi.Info{
+					Name:        "oldpkg",
+					Version:     "1.0",
+					Classifiers: []string{"Development Status :: 7 - Inactive"},
+				},
+				Releases: releases("1.0"),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	svc := resolver.New(client, resolver.WithMaintenanceWarnings(true), resolver.WithLogger(logger))
+	if _, err := svc.Resolve(context.Background(), []string{"oldpkg"}); err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	want := "project is classified as inactive and may no longer be maintained\" package=oldpkg"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected log to contain %q, got:\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+
+	svc = resolver.New(client, resolver.WithLogger(logger))
+	if _, err := svc.Resolve(context.Background(), []string{"oldpkg"}); err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no warning without WithMaintenanceWarnings, got:\n%s", buf.String())
+	}
+}
+
+func TestResolveYankedLatestWarningOnlyWhenSelected(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info: pypi.Info{Name: "pkg", Version: "2.0", Yanked: true, YankedReason: "broken build"},
+				Releases: map[string][]pypi.URL{
+					"1.0": {{Filename: "pkg-1.0-py3-none-any.whl"}},
+					"2.0": {{Filename: "pkg-2.0-py3-none-any.whl", Yanked: true, YankedReason: "broken build"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		requirement string
+		wantWarning bool
+	}{
+		{requirement: "pkg<2", wantWarning: false},
+		{requirement: "pkg==2.0", wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.requirement, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+			svc := resolver.New(client, resolver.WithMaintenanceWarnings(true), resolver.WithLogger(logger))
+			if _, err := svc.Resolve(context.Background(), []string{tt.requirement}); err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+
+			if got := strings.Contains(buf.String(), "latest release is yanked"); got != tt.wantWarning {
+				t.Errorf("yanked warning logged = %v, want %v; log:\n%s", got, tt.wantWarning, buf.String())
+			}
+		})
+	}
+}
+
+func TestResolveRequiresPythonExcludesAll(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info: pypi.Info{Name: "pkg", Version: "2.1", RequiresPython: ">=3.11"},
+				Releases: map[string][]pypi.URL{
+					"1.9": {{Filename: "pkg-1.9-py3-none-any.whl", RequiresPython: ">=3.8"}},
+					"2.0": {{Filename: "pkg-2.0-py3-none-any.whl", RequiresPython: ">=3.11"}},
+					"2.1": {{Filename: "pkg-2.1-py3-none-any.whl", RequiresPython: ">=3.11"}},
+				},
+			},
+		},
+	}
+
+	env := resolver.MarkerEnv{PythonVersion: "3.8"}
+	svc := resolver.New(client, resolver.WithMarkerEnv(env))
+
+	_, err := svc.Resolve(context.Background(), []string{"pkg>=2.0"})
+	if err == nil {
+		t.Fatal("expected error when every matching version needs a newer Python, got nil")
+	}
+
+	for _, want := range []string{"pkg 2.1 requires Python >=3.11, target is 3.8", "newest release supporting Python 3.8 is 1.9"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestResolveRequiresPythonNoNoteWhenNewestFits(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info:     pypi.Info{Name: "pkg", Version: "2.0", RequiresPython: ">=3.8"},
+				Releases: releases("1.9", "2.0"),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	env := resolver.MarkerEnv{PythonVersion: "3.11"}
+	svc := resolver.New(client, resolver.WithMarkerEnv(env), resolver.WithLogger(logger))
+
+	result, err := svc.Resolve(context.Background(), []string{"pkg"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if result[0].Version != "2.0" {
+		t.Errorf("expected 2.0, got %s", result[0].Version)
+	}
+
+	if strings.Contains(buf.String(), "requires Python") {
+		t.Errorf("unexpected selection note: %s", buf.String())
+	}
+}
+
+func TestResolveAllowlist(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"flask": {
+				Info:     pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{"jinja2>=3.1.2"}},
+				Releases: releases("3.0.0"),
+			},
+			"jinja2": {
+				Info:     pypi.Info{Name: "jinja2", Version: "3.1.3", RequiresDist: []string{"markupsafe>=2.0"}},
+				Releases: releases("3.1.3"),
+			},
+			"markupsafe": {
+				Info:     pypi.Info{Name: "markupsafe", Version: "2.1.5"},
+				Releases: releases("2.1.5"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithAllowlist([]string{"Flask", "Jinja2", "MarkupSafe"}))
+	if _, err := svc.Resolve(context.Background(), []string{"flask"}); err != nil {
+		t.Fatalf("Resolve() error with complete allowlist: %v", err)
+	}
+
+	svc = resolver.New(client, resolver.WithAllowlist([]string{"flask", "jinja2"}))
+
+	_, err := svc.Resolve(context.Background(), []string{"flask"})
+	if err == nil {
+		t.Fatal("expected error for package outside the allowlist, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "markupsafe (required by jinja2)") {
+		t.Errorf("error should name the offending package and its parent, got: %v", err)
+	}
+}
+
+func TestResolveAllowlistRoot(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"requests": {
+				Info:     pypi.Info{Name: "requests", Version: "2.31.0"},
+				Releases: releases("2.31.0"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithAllowlist([]string{"flask"}))
+
+	_, err := svc.Resolve(context.Background(), []string{"requests"})
+	if err == nil || !strings.Contains(err.Error(), "requests (requested directly)") {
+		t.Fatalf("expected allowlist error for root package, got %v", err)
+	}
+}
+
+func TestResolveCollectConflicts(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"app": {
+				Info:     pypi.Info{Name: "app", Version: "1.0", RequiresDist: []string{"numpy>=2.0", "urllib3==1.26.0"}},
+				Releases: releases("1.0"),
+			},
+			"legacy": {
+				Info:     pypi.Info{Name: "legacy", Version: "1.0", RequiresDist: []string{"numpy<2.0"}},
+				Releases: releases("1.0"),
+			},
+			"client": {
+				Info:     pypi.Info{Name: "client", Version: "1.0", RequiresDist: []string{"urllib3>=2.0"}},
+				Releases: releases("1.0"),
+			},
+			"numpy": {
+				Info:     pypi.Info{Name: "numpy", Version: "2.1.0"},
+				Releases: releases("1.26.0", "2.1.0"),
+			},
+			"urllib3": {
+				Info:     pypi.Info{Name: "urllib3", Version: "2.2.0"},
+				Releases: releases("1.26.0", "2.2.0"),
+			},
+		},
+	}
+
+	roots := []string{"app", "legacy", "client"}
+
+	if _, err := resolver.New(client).Resolve(context.Background(), roots); err == nil {
+		t.Fatal("expected conflict error, got nil")
+	}
+
+	svc := resolver.New(client, resolver.WithCollectConflicts(true))
+
+	_, err := svc.Resolve(context.Background(), roots)
+
+	var unresolvable *resolver.UnresolvableError
+	if !errors.As(err, &unresolvable) {
+		t.Fatalf("expected *UnresolvableError, got %v", err)
+	}
+
+	if len(unresolvable.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %d: %v", len(unresolvable.Conflicts), err)
+	}
+
+	for _, want := range []string{
+		"numpy: app(>=2.0), legacy(<2.0); selected 2.1.0",
+		"urllib3: app(==1.26.0), client(>=2.0); selected 1.26.0",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestResolveCollectConflictsNoMatch(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"pkg": {
+				Info:     pypi.Info{Name: "pkg", Version: "1.0"},
+				Releases: releases("1.0"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithCollectConflicts(true))
+
+	_, err := svc.Resolve(context.Background(), []string{"pkg>=2.0"})
+	if err == nil || !strings.Contains(err.Error(), "pkg: requested(>=2.0)") {
+		t.Fatalf("expected aggregated error for unmatched root, got %v", err)
+	}
+}
+
+func TestResolveExtrasWithMarkers(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"requests": {
+				Info: pypi.Info{Name: "requests", Version: "2.31.0", RequiresDist: []string{
+					"urllib3>=1.21",
+					`pysocks>=1.5.6; extra == "socks"`,
+					`chardet>=3.0; extra == "use-chardet" and python_version < "3.9"`,
+					`win-inet-pton; extra == "socks" and sys_platform == "win32"`,
+				}},
+				Releases: releases("2.31.0"),
+			},
+			"urllib3": {Info: pypi.Info{Name: "urllib3", Version: "2.2.0"}, Releases: releases("2.2.0")},
+			"pysocks": {Info: pypi.Info{Name: "pysocks", Version: "1.7.1"}, Releases: releases("1.7.1")},
+			"chardet": {Info: pypi.Info{Name: "chardet", Version: "5.2.0"}, Releases: releases("5.2.0")},
+		},
+	}
+
+	env := resolver.MarkerEnv{PythonVersion: "3.12", SysPlatform: "linux", OsName: "posix"}
+
+	tests := []struct {
+		name  string
+		roots []string
+		want  []string
+	}{
+		{"no extras", []string{"requests"}, []string{"requests", "urllib3"}},
+		{"socks extra", []string{"requests[socks]"}, []string{"pysocks", "requests", "urllib3"}},
+		{"extra gated by python version", []string{"requests[socks,use_chardet]"}, []string{"pysocks", "requests", "urllib3"}},
+		{"extra requested after resolution", []string{"requests", "requests[socks]"}, []string{"pysocks", "requests", "urllib3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := resolver.New(client, resolver.WithMarkerEnv(env))
+
+			result, err := svc.Resolve(context.Background(), tt.roots)
+			if err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+
+			var got []string
+			for _, pkg := range result {
+				got = append(got, pkg.Name)
+			}
+
+			sort.Strings(got)
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("resolved %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	env.PythonVersion = "3.8"
+	svc := resolver.New(client, resolver.WithMarkerEnv(env))
+
+	result, err := svc.Resolve(context.Background(), []string{"requests[use-chardet]"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	if len(result) != 3 {
+		t.Errorf("expected chardet on Python 3.8 with use-chardet extra, got %+v", result)
+	}
+}
+
+func TestResolveTransitiveExtras(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"flask": {
+				Info: pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{
+					`asgiref[tz]>=3.2; extra == "async"`,
+				}},
+				Releases: releases("3.0.0"),
+			},
+			"asgiref": {
+				Info: pypi.Info{Name: "asgiref", Version: "3.8.1", RequiresDist: []string{
+					`tzdata[zoneinfo]; extra == "tz"`,
+					`mypy; extra == "tests"`,
+				}},
+				Releases: releases("3.8.1"),
+			},
+			"tzdata": {
+				Info: pypi.Info{Name: "tzdata", Version: "2024.1", RequiresDist: []string{
+					`backports-zoneinfo; extra == "zoneinfo"`,
+				}},
+				Releases: releases("2024.1"),
+			},
+			"backports-zoneinfo": {Info: pypi.Info{Name: "backports-zoneinfo", Version: "0.2.1"}, Releases: releases("0.2.1")},
+			"mypy":               {Info: pypi.Info{Name: "mypy", Version: "1.9.0"}, Releases: releases("1.9.0")},
+		},
+	}
+
+	env := resolver.MarkerEnv{PythonVersion: "3.12", SysPlatform: "linux", OsName: "posix"}
+
+	tests := []struct {
+		name  string
+		roots []string
+		want  []string
+	}{
+		{"extra chain", []string{"flask[async]"}, []string{"asgiref", "backports-zoneinfo", "flask", "tzdata"}},
+		{"no root extra", []string{"flask"}, []string{"flask"}},
+		{"dependency resolved before its extra is requested", []string{"asgiref", "tzdata", "flask[async]"}, []string{"asgiref", "backports-zoneinfo", "flask", "tzdata"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := resolver.New(client, resolver.WithMarkerEnv(env))
+
+			result, err := svc.Resolve(context.Background(), tt.roots)
+			if err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+
+			var got []string
+			for _, pkg := range result {
+				got = append(got, pkg.Name)
+
+				if pkg.Name == "asgiref" && slices.Contains(tt.want, "tzdata") && !slices.Equal(pkg.Extras, []string{"tz"}) {
+					t.Errorf("asgiref extras = %v, want [tz]", pkg.Extras)
+				}
+			}
+
+			sort.Strings(got)
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("resolved %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConstraints(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"flask": {
+				Info:     pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{"werkzeug>=2.0"}},
+				Releases: releases("3.0.0"),
+			},
+			"werkzeug": {
+				Info:     pypi.Info{Name: "werkzeug", Version: "3.0.1"},
+				Releases: releases("2.3.8", "3.0.1"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithConstraints(map[string][]string{
+		"Werkzeug": {"<3"},
+		"urllib3":  {"<2"},
+	}))
+
+	result, err := svc.Resolve(context.Background(), []string{"flask"})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	versions := make(map[string]string, len(result))
+	for _, pkg := range result {
+		versions[pkg.Name] = pkg.Version
+	}
+
+	if versions["werkzeug"] != "2.3.8" {
+		t.Errorf("expected constraint to cap werkzeug at 2.3.8, got %q", versions["werkzeug"])
+	}
+
+	if _, ok := versions["urllib3"]; ok {
+		t.Error("constraint alone must not add urllib3 to the resolution")
+	}
+
+	if len(result) != 2 {
+		t.Errorf("expected 2 packages, got %d", len(result))
+	}
+}
+
+func TestValidateCatchesUnsatisfiedEdges(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"flask": {
+				Info: pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{
+					"werkzeug>=3.0",
+					"click",
+					`importlib-metadata; python_version < "3.10"`,
+				}},
+				Releases: releases("3.0.0"),
+			},
+			"werkzeug": {
+				Info:     pypi.Info{Name: "werkzeug", Version: "3.0.1"},
+				Releases: releases("2.3.8", "3.0.1"),
+			},
+		},
+	}
+
+	env := resolver.MarkerEnv{PythonVersion: "3.12", SysPlatform: "linux", OsName: "posix"}
+	svc := resolver.New(client, resolver.WithMarkerEnv(env))
+
+	// A deliberately inconsistent result: werkzeug too old, click missing.
+	inconsistent := []resolver.ResolvedPackage{
+		{Name: "flask", Version: "3.0.0", Root: true},
+		{Name: "werkzeug", Version: "2.3.8"},
+	}
+
+	err := svc.Validate(context.Background(), inconsistent)
+	if err == nil {
+		t.Fatal("expected validation error, got nil")
+	}
+
+	for _, want := range []string{
+		"flask 3.0.0 requires werkzeug>=3.0, resolved 2.3.8",
+		"flask 3.0.0 requires click, which is not in the resolution",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	if strings.Contains(err.Error(), "importlib-metadata") {
+		t.Errorf("marker-excluded dependency reported: %v", err)
+	}
+}
+
+func TestResolveWithValidation(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"flask": {
+				Info:     pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{"werkzeug>=3.0"}},
+				Releases: releases("3.0.0"),
+			},
+			"werkzeug": {
+				Info:     pypi.Info{Name: "werkzeug", Version: "3.0.1"},
+				Releases: releases("2.3.8", "3.0.1"),
+			},
+		},
+	}
+
+	svc := resolver.New(client, resolver.WithValidation(true))
+
+	if _, err := svc.Resolve(context.Background(), []string{"flask"}); err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+}
+
+func TestResolvePreferInstalled(t *testing.T) {
+	tests := []struct {
+		name    string
+		require string
+		want    string
+	}{
+		{name: "installed version satisfies", require: "werkzeug>=2.0", want: "2.3.8"},
+		{name: "installed version too old", require: "werkzeug>=3.0", want: "3.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{
+				packages: map[string]*pypi.PackageInfo{
+					"flask": {
+						Info:     pypi.Info{Name: "flask", Version: "3.0.0", RequiresDist: []string{tt.require}},
+						Releases: releases("3.0.0"),
+					},
+					"werkzeug": {
+						Info:     pypi.Info{Name: "werkzeug", Version: "3.0.1"},
+						Releases: releases("2.3.8", "3.0.1"),
+					},
+				},
+			}
+
+			svc := resolver.New(client, resolver.WithPreferred(map[string]string{"Werkzeug": "2.3.8"}))
+
+			result, err := svc.Resolve(context.Background(), []string{"flask"})
+			if err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+
+			for _, pkg := range result {
+				if pkg.Name == "werkzeug" && pkg.Version != tt.want {
+					t.Errorf("werkzeug = %s, want %s", pkg.Version, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveDirectURLDependency(t *testing.T) {
+	const (
+		appURL = "https://files.example.com/app-2.0-py3-none-any.whl"
+		depURL = "https://files.example.com/somedep-1.0-py3-none-any.whl#sha256=abcd"
+	)
+
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			// The index has a newer somedep, which the URL must override.
+			"somedep": {
+				Info:     pypi.Info{Name: "somedep", Version: "9.0"},
+				Releases: releases("9.0"),
+			},
+			"six": {
+				Info:     pypi.Info{Name: "six", Version: "1.17.0"},
+				Releases: releases("1.17.0"),
+			},
+		},
+	}
+
+	var read []string
+
+	metadata := func(_ context.Context, file pypi.URL) (*pypi.Info, error) {
+		read = append(read, file.URL)
+
+		if file.Filename == "app-2.0-py3-none-any.whl" {
+			return &pypi.Info{Name: "app", Version: "2.0", RequiresDist: []string{"somedep @ " + depURL}}, nil
+		}
+
+		return &pypi.Info{Name: "somedep", Version: "1.0", RequiresDist: []string{"six"}}, nil
+	}
+
+	svc := resolver.New(client, resolver.WithWheelMetadata(metadata), resolver.WithValidation(true))
+
+	result, err := svc.Resolve(context.Background(), []string{"app @ " + appURL})
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	got := make(map[string]resolver.ResolvedPackage, len(result))
+	for _, pkg := range result {
+		got[pkg.Name] = pkg
+	}
+
+	dep := got["somedep"]
+	if dep.Version != "1.0" || dep.URL != depURL {
+		t.Errorf("somedep = %s from %q, want 1.0 from %q", dep.Version, dep.URL, depURL)
+	}
+
+	if six, ok := got["six"]; !ok || six.URL != "" {
+		t.Errorf("six = %+v, want the index release required by the URL wheel", six)
+	}
+
+	// Each wheel is read once, although validation needs its metadata again.
+	want := []string{appURL, "https://files.example.com/somedep-1.0-py3-none-any.whl"}
+	if !slices.Equal(read, want) {
+		t.Errorf("metadata read from %v, want %v", read, want)
+	}
+}
+
+func TestResolveDirectURLRejected(t *testing.T) {
+	client := &mockClient{
+		packages: map[string]*pypi.PackageInfo{
+			"app": {
+				Info: pypi.Info{Name: "app", Version: "2.0", RequiresDist: []string{
+					"somedep @ https://files.example.com/somedep-1.0-py3-none-any.whl",
+				}},
+				Releases: releases("2.0"),
+			},
+		},
+	}
+
+	metadata := func(context.Context, pypi.URL) (*pypi.Info, error) {
+		return &pypi.Info{Name: "somedep", Version: "1.0"}, nil
+	}
+
+	tests := []struct {
+		name string
+		req  string
+		want string
+	}{
+		{"index package depends on URL", "app", "only packages installed from a direct URL"},
+		{"plain http", "somedep @ http://files.example.com/somedep-1.0-py3-none-any.whl", "must use https"},
+		{"local file", "somedep @ file:///tmp/somedep-1.0-py3-none-any.whl", "must use https"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := resolver.New(client, resolver.WithWheelMetadata(metadata))
+
+			_, err := svc.Resolve(context.Background(), []string{tt.req})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Resolve(%q) error = %v, want %q", tt.req, err, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package resolver
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	pep440 "github.com/aquasecurity/go-pep440-version"
 )
@@ -28,43 +29,137 @@ func MatchesAll(versionStr string, specifiers []string) (bool, error) {
 	return true, nil
 }
 
+// VersionsEqual reports whether a and b are the same PEP 440 version, e.g.,
+// "1.0" and "1.0.0", or "2.0rc1" and "2.0.0RC1". Unparsable versions are
+// compared as strings.
+func VersionsEqual(a, b string) bool {
+	va, errA := pep440.Parse(a)
+	vb, errB := pep440.Parse(b)
+
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return va.Equal(vb)
+}
+
 // FindBestVersion finds the highest version from candidates that satisfies all specifiers.
-// Candidates are version strings. Pre-release versions are excluded unless no stable version matches.
+// Candidates are version strings. Pre-release versions are excluded unless a
+// specifier pins one exactly, e.g., "==2.0.0rc1".
 // Returns empty string if no version matches.
 func FindBestVersion(candidates []string, specifiers []string) (string, error) {
-	sorted, err := SortVersionsDesc(candidates)
-	if err != nil {
-		return "", err
+	return FindBestVersionFunc(candidates, specifiers, nil)
+}
+
+// FindBestVersionFunc is like FindBestVersion but additionally requires accept
+// to return true for the selected version. Candidates are tried highest first,
+// so accept is only called for versions that already satisfy the specifiers.
+// A nil accept accepts every version.
+func FindBestVersionFunc(candidates []string, specifiers []string, accept func(version string) bool) (string, error) {
+	return findBestVersion(candidates, specifiers, selection{}, accept)
+}
+
+// selection tunes which candidates findBestVersion considers.
+type selection struct {
+	allowPre bool // treat pre-releases as regular candidates
+	limit    int  // try only the limit highest versions matching the specifiers; 0 means all
+}
+
+// findBestVersion implements FindBestVersionFunc. Specifiers are parsed once
+// and candidates once, and the search stops at the first match.
+func findBestVersion(candidates, specifiers []string, sel selection, accept func(version string) bool) (string, error) {
+	specs := make([]pep440.Specifiers, 0, len(specifiers))
+
+	for _, spec := range specifiers {
+		ss, err := pep440.NewSpecifiers(spec)
+		if err != nil {
+			return "", fmt.Errorf("parsing specifier %q: %w", spec, err)
+		}
+
+		specs = append(specs, ss)
 	}
 
+	sorted := parseVersions(candidates)
+	sortDesc(sorted)
+
+	allowPre := sel.allowPre || pinsPreRelease(specifiers)
+	tried := 0
+
 	for _, v := range sorted {
-		parsed, _ := pep440.Parse(v)
-		if parsed.IsPreRelease() {
+		if v.ver.IsPreRelease() && !allowPre || !checkAll(specs, v.ver) {
 			continue
 		}
 
-		matches, err := MatchesAll(v, specifiers)
-		if err != nil {
-			return "", err
+		// The limit applies after the specifiers, so an old pin like
+		// "django==1.11" is still found.
+		if sel.limit > 0 && tried == sel.limit {
+			break
 		}
 
-		if matches {
-			return v, nil
+		tried++
+
+		if accept == nil || accept(v.raw) {
+			return v.raw, nil
 		}
 	}
 
 	return "", nil
 }
 
+// checkAll reports whether v satisfies every parsed specifier.
+func checkAll(specs []pep440.Specifiers, v pep440.Version) bool {
+	for _, ss := range specs {
+		if !ss.Check(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pinsPreRelease reports whether any specifier clause is an exact pin to a
+// pre-release, e.g., "==2.0.0rc1". Like pip, such a pin opts into pre-releases.
+func pinsPreRelease(specifiers []string) bool {
+	for _, spec := range specifiers {
+		for _, clause := range strings.Split(spec, ",") {
+			clause = strings.TrimSpace(clause)
+			if !strings.HasPrefix(clause, "==") {
+				continue
+			}
+
+			v, err := pep440.Parse(strings.TrimSpace(strings.TrimLeft(clause, "=")))
+			if err == nil && v.IsPreRelease() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // SortVersionsDesc sorts version strings in descending order (highest first).
 // Invalid version strings are filtered out.
 func SortVersionsDesc(versions []string) ([]string, error) {
-	type parsed struct {
-		raw string
-		ver pep440.Version
+	parsed := parseVersions(versions)
+	sortDesc(parsed)
+
+	result := make([]string, len(parsed))
+	for i, v := range parsed {
+		result[i] = v.raw
 	}
 
-	var valid []parsed
+	return result, nil
+}
+
+// parsedVersion pairs a version string with its parsed form.
+type parsedVersion struct {
+	raw string
+	ver pep440.Version
+}
+
+// parseVersions parses version strings, dropping invalid ones.
+func parseVersions(versions []string) []parsedVersion {
+	valid := make([]parsedVersion, 0, len(versions))
 
 	for _, raw := range versions {
 		v, err := pep440.Parse(raw)
@@ -72,19 +167,17 @@ func SortVersionsDesc(versions []string) ([]string, error) {
 			continue
 		}
 
-		valid = append(valid, parsed{raw: raw, ver: v})
+		valid = append(valid, parsedVersion{raw: raw, ver: v})
 	}
 
-	sort.Slice(valid, func(i, j int) bool {
-		return valid[i].ver.GreaterThan(valid[j].ver)
-	})
-
-	result := make([]string, len(valid))
-	for i, v := range valid {
-		result[i] = v.raw
-	}
+	return valid
+}
 
-	return result, nil
+// sortDesc sorts parsed versions highest first.
+func sortDesc(versions []parsedVersion) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ver.GreaterThan(versions[j].ver)
+	})
 }
 
 // FormatPythonVersion converts a compact version like "312" to dotted "3.12".
@@ -1,6 +1,7 @@
 package resolver_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/bilusteknoloji/pipg/internal/resolver"
@@ -53,6 +54,8 @@ func TestFindBestVersion(t *testing.T) {
 		{"exact", []string{"==1.5.0"}, "1.5.0"},
 		{"no match", []string{">=4.0"}, ""},
 		{"skips prerelease", []string{">=2.0"}, "2.1.0"},
+		{"pinned prerelease", []string{"==3.0.0a1"}, "3.0.0a1"},
+		{"pinned prerelease in clause", []string{">=2.0,==3.0.0a1"}, "3.0.0a1"},
 	}
 
 	for _, tt := range tests {
@@ -69,6 +72,26 @@ func TestFindBestVersion(t *testing.T) {
 	}
 }
 
+func TestVersionsEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.0", "1.0.0", true},
+		{"2.0rc1", "2.0.0RC1", true},
+		{"1.0.post1", "1.0-1", true},
+		{"1.0", "1.0.1", false},
+		{"not-a-version", "not-a-version", true},
+		{"not-a-version", "1.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := resolver.VersionsEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("VersionsEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
 func TestSortVersionsDesc(t *testing.T) {
 	input := []string{"1.0", "3.0", "2.0", "1.5", "invalid", "2.0.1"}
 
@@ -109,3 +132,26 @@ func TestFormatPythonVersion(t *testing.T) {
 		})
 	}
 }
+
+// syntheticVersions returns n distinct release versions, e.g., boto3's history.
+func syntheticVersions(n int) []string {
+	versions := make([]string, 0, n)
+	for i := range n {
+		versions = append(versions, fmt.Sprintf("1.%d.%d", i/100, i%100))
+	}
+
+	return versions
+}
+
+func BenchmarkFindBestVersion(b *testing.B) {
+	candidates := syntheticVersions(5000)
+	specifiers := []string{">=1.20.0,<1.30.0"}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := resolver.FindBestVersion(candidates, specifiers); err != nil {
+			b.Fatal(err)
+		}
+	}
+}